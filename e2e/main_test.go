@@ -25,6 +25,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bank-vaults/vault-secrets-reloader/pkg/reloader"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -46,6 +47,12 @@ const defaultTimeout = 3 * time.Minute
 
 var testenv env.Environment
 
+// The annotations the tests assert on, kept in sync with the ones the reloader is configured with
+var (
+	reloadCountAnnotation = reloader.ReloadCountAnnotationName
+	reloadAnnotation      = reloader.SecretReloadAnnotationName
+)
+
 func TestMain(m *testing.M) {
 	// See https://github.com/kubernetes-sigs/e2e-framework/issues/269
 	// testenv = env.New()
@@ -58,6 +65,13 @@ func TestMain(m *testing.M) {
 	}
 	log.SetLogger(klog.NewKlogr())
 
+	if v := os.Getenv("RELOAD_COUNT_ANNOTATION"); v != "" {
+		reloadCountAnnotation = v
+	}
+	if v := os.Getenv("RELOAD_ANNOTATION"); v != "" {
+		reloadAnnotation = v
+	}
+
 	bootstrap := strings.ToLower(os.Getenv("BOOTSTRAP")) != "false"
 	useRealCluster := !bootstrap || strings.ToLower(os.Getenv("USE_REAL_CLUSTER")) == "true"
 
@@ -211,11 +225,19 @@ func installVaultSecretsReloader(ctx context.Context, cfg *envconf.Config) (cont
 		chart = v
 	}
 
+	args := []string{"--set", "image.tag=" + version, "--set", "logLevel=debug", "--set", "collectorSyncPeriod=15s", "--set", "reloaderRunPeriod=15s", "--set", "env.VAULT_ROLE=reloader", "--set", "env.VAULT_ADDR=https://vault.default.svc.cluster.local:8200", "--set", "env.VAULT_TLS_SECRET=vault-tls", "--set", "env.VAULT_TLS_SECRET_NS=bank-vaults-infra"}
+	if v := os.Getenv("RELOAD_COUNT_ANNOTATION"); v != "" {
+		args = append(args, "--set", "reloadCountAnnotation="+v)
+	}
+	if v := os.Getenv("RELOAD_ANNOTATION"); v != "" {
+		args = append(args, "--set", "reloadAnnotation="+v)
+	}
+
 	err := manager.RunInstall(
 		helm.WithName("vault-secrets-reloader"),
 		helm.WithChart(chart),
 		helm.WithNamespace("bank-vaults-infra"),
-		helm.WithArgs("--set", "image.tag="+version, "--set", "logLevel=debug", "--set", "collectorSyncPeriod=15s", "--set", "reloaderRunPeriod=15s", "--set", "env.VAULT_ROLE=reloader", "--set", "env.VAULT_ADDR=https://vault.default.svc.cluster.local:8200", "--set", "env.VAULT_TLS_SECRET=vault-tls", "--set", "env.VAULT_TLS_SECRET_NS=bank-vaults-infra"),
+		helm.WithArgs(args...),
 		helm.WithWait(),
 		helm.WithTimeout(defaultTimeout.String()),
 	)
@@ -30,6 +30,7 @@ import (
 	"github.com/bank-vaults/vault-secrets-reloader/pkg/reloader"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	extv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -73,7 +74,7 @@ func TestWorkloadReload(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{Name: "reloader-test-daemonset", Namespace: cfg.Namespace()},
 			}
 			err := wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(daemonSet, func(obj k8s.Object) bool {
-				return obj.(*appsv1.DaemonSet).Spec.Template.Annotations[reloader.ReloadCountAnnotationName] == "1"
+				return obj.(*appsv1.DaemonSet).Spec.Template.Annotations[reloadCountAnnotation] == "1"
 			}), wait.WithTimeout(3*time.Minute))
 			require.NoError(t, err)
 
@@ -84,7 +85,18 @@ func TestWorkloadReload(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{Name: "reloader-test-statefulset", Namespace: cfg.Namespace()},
 			}
 			err := wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(statefulSet, func(obj k8s.Object) bool {
-				return obj.(*appsv1.StatefulSet).Spec.Template.Annotations[reloader.ReloadCountAnnotationName] == "1"
+				return obj.(*appsv1.StatefulSet).Spec.Template.Annotations[reloadCountAnnotation] == "1"
+			}), wait.WithTimeout(3*time.Minute))
+			require.NoError(t, err)
+
+			return ctx
+		}).
+		Assess("cronjob reloaded", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			cronJob := &batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "reloader-test-cronjob", Namespace: cfg.Namespace()},
+			}
+			err := wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(cronJob, func(obj k8s.Object) bool {
+				return obj.(*batchv1.CronJob).Spec.JobTemplate.Spec.Template.Annotations[reloadCountAnnotation] == "1"
 			}), wait.WithTimeout(3*time.Minute))
 			require.NoError(t, err)
 
@@ -95,7 +107,7 @@ func TestWorkloadReload(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{Name: "reloader-test-deployment-to-be-reloaded", Namespace: cfg.Namespace()},
 			}
 			err := wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(deployment, func(obj k8s.Object) bool {
-				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloader.ReloadCountAnnotationName] == "1"
+				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloadCountAnnotation] == "1"
 			}), wait.WithTimeout(3*time.Minute))
 			require.NoError(t, err)
 
@@ -106,7 +118,7 @@ func TestWorkloadReload(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{Name: "reloader-test-deployment-no-reload", Namespace: cfg.Namespace()},
 			}
 			err := wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(deployment, func(obj k8s.Object) bool {
-				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloader.ReloadCountAnnotationName] == ""
+				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloadCountAnnotation] == ""
 			}), wait.WithTimeout(3*time.Minute))
 			require.NoError(t, err)
 
@@ -117,7 +129,7 @@ func TestWorkloadReload(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{Name: "reloader-test-deployment-fixed-versions-no-reload", Namespace: cfg.Namespace()},
 			}
 			err := wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(deployment, func(obj k8s.Object) bool {
-				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloader.ReloadCountAnnotationName] == ""
+				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloadCountAnnotation] == ""
 			}), wait.WithTimeout(3*time.Minute))
 			require.NoError(t, err)
 
@@ -128,7 +140,7 @@ func TestWorkloadReload(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{Name: "reloader-test-deployment-annotated-reload", Namespace: cfg.Namespace()},
 			}
 			err := wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(deployment, func(obj k8s.Object) bool {
-				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloader.ReloadCountAnnotationName] == "1"
+				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloadCountAnnotation] == "1"
 			}), wait.WithTimeout(3*time.Minute))
 			require.NoError(t, err)
 
@@ -139,7 +151,18 @@ func TestWorkloadReload(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{Name: "reloader-test-deployment-annotated-no-reload", Namespace: cfg.Namespace()},
 			}
 			err := wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(deployment, func(obj k8s.Object) bool {
-				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloader.ReloadCountAnnotationName] == ""
+				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloadCountAnnotation] == ""
+			}), wait.WithTimeout(3*time.Minute))
+			require.NoError(t, err)
+
+			return ctx
+		}).
+		Assess("excluded deployment not reloaded", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "reloader-test-deployment-excluded-no-reload", Namespace: cfg.Namespace()},
+			}
+			err := wait.For(conditions.New(cfg.Client().Resources()).ResourceMatch(deployment, func(obj k8s.Object) bool {
+				return obj.(*appsv1.Deployment).Spec.Template.Annotations[reloadCountAnnotation] == ""
 			}), wait.WithTimeout(3*time.Minute))
 			require.NoError(t, err)
 
@@ -167,6 +190,7 @@ func applyWorkloads() *features.FeatureBuilder {
 				ctx, os.DirFS("deploy/workloads"), "*",
 				decoder.CreateHandler(cfg.Client().Resources()),
 				decoder.MutateNamespace(cfg.Namespace()),
+				mutateReloadAnnotation(),
 			)
 			require.NoError(t, err)
 
@@ -179,6 +203,35 @@ func applyWorkloads() *features.FeatureBuilder {
 		})
 }
 
+// mutateReloadAnnotation renames the reload annotation of the workloads to the one the reloader is configured with.
+func mutateReloadAnnotation() decoder.DecodeOption {
+	return decoder.MutateOption(func(obj k8s.Object) error {
+		if reloadAnnotation == reloader.SecretReloadAnnotationName {
+			return nil
+		}
+
+		var template *v1.PodTemplateSpec
+		switch workload := obj.(type) {
+		case *appsv1.Deployment:
+			template = &workload.Spec.Template
+		case *appsv1.DaemonSet:
+			template = &workload.Spec.Template
+		case *appsv1.StatefulSet:
+			template = &workload.Spec.Template
+		case *batchv1.CronJob:
+			template = &workload.Spec.JobTemplate.Spec.Template
+		default:
+			return nil
+		}
+		if value, ok := template.Annotations[reloader.SecretReloadAnnotationName]; ok {
+			delete(template.Annotations, reloader.SecretReloadAnnotationName)
+			template.Annotations[reloadAnnotation] = value
+		}
+
+		return nil
+	})
+}
+
 func workloadsAvailable(cfg *envconf.Config) error {
 	var errors []error
 
@@ -0,0 +1,102 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	slogmulti "github.com/samber/slog-multi"
+)
+
+const (
+	// Send warnings and errors to stderr, info and debug logs to stdout
+	logDestinationSplit  = "split"
+	logDestinationStdout = "stdout"
+	logDestinationStderr = "stderr"
+)
+
+// eventOutputStdout is the event output that writes reload events to stdout
+const eventOutputStdout = "-"
+
+// logDestinationWithEventOutput returns the log destination to use along with the event output, as logs
+// written to stdout would be mixed into the events written there: split logs are sent to stderr only,
+// and logging to stdout is rejected.
+func logDestinationWithEventOutput(destination, eventOutput string) (string, error) {
+	if eventOutput != eventOutputStdout {
+		return destination, nil
+	}
+
+	switch destination {
+	case logDestinationSplit:
+		return logDestinationStderr, nil
+	case logDestinationStdout:
+		return "", fmt.Errorf("log destination %q can't be used with events written to stdout", destination)
+	default:
+		return destination, nil
+	}
+}
+
+func newLogger(logLevel string, enableJSONLog bool, destination string, stdout, stderr io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+
+	err := level.UnmarshalText([]byte(logLevel))
+	if err != nil { // Silently fall back to info level
+		level = slog.LevelInfo
+	}
+
+	newHandler := func(w io.Writer, level slog.Leveler) slog.Handler {
+		if enableJSONLog {
+			return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+		}
+		return slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})
+	}
+
+	var handler slog.Handler
+	switch destination {
+	case logDestinationSplit:
+		levelFilter := func(levels ...slog.Level) func(ctx context.Context, r slog.Record) bool {
+			return func(_ context.Context, r slog.Record) bool {
+				return slices.Contains(levels, r.Level)
+			}
+		}
+
+		handler = slogmulti.Router().
+			// Send logs with level higher than warning to stderr
+			Add(newHandler(stderr, slog.LevelWarn), levelFilter(slog.LevelWarn, slog.LevelError)).
+			// Send info and debug logs to stdout
+			Add(newHandler(stdout, level), levelFilter(slog.LevelDebug, slog.LevelInfo)).
+			Handler()
+
+	case logDestinationStdout:
+		handler = newHandler(stdout, level)
+
+	case logDestinationStderr:
+		handler = newHandler(stderr, level)
+
+	default:
+		return nil, fmt.Errorf("invalid log destination %q, must be one of %s, %s, %s",
+			destination, logDestinationSplit, logDestinationStdout, logDestinationStderr)
+	}
+
+	// TODO: add level filter handler
+	logger := slog.New(handler)
+	logger = logger.With(slog.String("app", "vault-secrets-reloader"))
+
+	return logger, nil
+}
@@ -0,0 +1,96 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		destination    string
+		expectedStdout []string
+		expectedStderr []string
+	}{
+		{
+			destination:    logDestinationSplit,
+			expectedStdout: []string{"debug message", "info message"},
+			expectedStderr: []string{"warn message", "error message"},
+		},
+		{
+			destination:    logDestinationStdout,
+			expectedStdout: []string{"debug message", "info message", "warn message", "error message"},
+		},
+		{
+			destination:    logDestinationStderr,
+			expectedStderr: []string{"debug message", "info message", "warn message", "error message"},
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.destination, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			logger, err := newLogger("debug", true, ttp.destination, &stdout, &stderr)
+			require.NoError(t, err)
+
+			logger.Debug("debug message")
+			logger.Info("info message")
+			logger.Warn("warn message")
+			logger.Error("error message")
+
+			for _, output := range []struct {
+				buffer   *bytes.Buffer
+				expected []string
+			}{{&stdout, ttp.expectedStdout}, {&stderr, ttp.expectedStderr}} {
+				if len(output.expected) == 0 {
+					assert.Empty(t, output.buffer.String())
+					continue
+				}
+				lines := bytes.Split(bytes.TrimSpace(output.buffer.Bytes()), []byte("\n"))
+				require.Len(t, lines, len(output.expected))
+				for i, message := range output.expected {
+					assert.Contains(t, string(lines[i]), message)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid destination", func(t *testing.T) {
+		_, err := newLogger("info", false, "syslog", &bytes.Buffer{}, &bytes.Buffer{})
+		assert.Error(t, err)
+	})
+}
+
+func TestLogDestinationWithEventOutput(t *testing.T) {
+	// Events written to stdout send all logs to stderr
+	destination, err := logDestinationWithEventOutput(logDestinationSplit, eventOutputStdout)
+	require.NoError(t, err)
+	assert.Equal(t, logDestinationStderr, destination)
+	destination, err = logDestinationWithEventOutput(logDestinationStderr, eventOutputStdout)
+	require.NoError(t, err)
+	assert.Equal(t, logDestinationStderr, destination)
+	_, err = logDestinationWithEventOutput(logDestinationStdout, eventOutputStdout)
+	assert.Error(t, err)
+
+	// Events written to a file don't change the log destination
+	destination, err = logDestinationWithEventOutput(logDestinationSplit, "/var/log/events.json")
+	require.NoError(t, err)
+	assert.Equal(t, logDestinationSplit, destination)
+}
@@ -19,14 +19,21 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
-	"net/http"
 	"os"
+	"runtime/debug"
 	"slices"
+	"strings"
 	"time"
 
-	slogmulti "github.com/samber/slog-multi"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
@@ -36,100 +43,495 @@ import (
 const (
 	defaultSyncPeriod        = 30 * time.Second
 	defaultReloaderRunPeriod = 60 * time.Second
+	defaultVerifyReloadDelay = 5 * time.Minute
+	defaultSettleDelay       = 5 * time.Second
 )
 
 func main() {
 	// Register CLI flags
 	collectorSyncPeriod := flag.Duration("collector-sync-period", defaultSyncPeriod,
 		"Determines the minimum frequency at which watched resources are reconciled")
+	resyncCollectionInterval := flag.Duration("resync-collection-interval", 0,
+		"Re-collect one workload per interval on periodic resyncs to smooth out the load, 0 re-collects them all at once")
 	reloaderRunPeriod := flag.Duration("reloader-run-period", defaultReloaderRunPeriod,
 		"Determines the minimum frequency at which watched resources are reloaded")
+	settleDelay := flag.Duration("settle-delay", defaultSettleDelay,
+		"Time to wait after the informer caches synced before the first reloader cycle, for the initial workloads to be collected")
+	cacheSyncTimeout := flag.Duration("cache-sync-timeout", 0,
+		"Exit with an error if the informer caches don't sync on startup within this duration, 0 waits indefinitely")
+	oneShot := flag.Bool("one-shot", false,
+		"Sync the informer caches, run a single reloader cycle and exit, with a non-zero code if it failed")
 	logLevel := flag.String("log-level", "info", "Log level (debug, info, warn, error).")
 	enableJSONLog := flag.Bool("enable-json-log", false, "Enable JSON logging")
+	logDestination := flag.String("log-destination", logDestinationSplit,
+		"Log destination (split: warnings and errors to stderr, the rest to stdout; stdout; stderr)")
+	enableVaultEvents := flag.Bool("enable-vault-events", false,
+		"Reload workloads on secret change events received from Vault (requires Vault 1.16+), in addition to periodic reloading")
+	disableDeprecatedAnnotation := flag.Bool("disable-deprecated-annotation", false,
+		"Don't collect secrets from the deprecated vault-env-from-path annotation of workloads without the vault-from-path annotation")
+	collectWorkloadAnnotations := flag.Bool("collect-workload-annotations", false,
+		"Collect secrets from the vault-from-path annotation of the workload itself, in addition to its pod template")
+	eventOutputPath := flag.String("event-output", "",
+		"Write reload decisions as JSON events to a file, or to stdout if set to \"-\", which sends all logs to stderr")
+	eventSinkURL := flag.String("event-sink-url", "",
+		"Publish reload decisions as JSON events to an HTTP endpoint with POST requests, e.g. a webhook or a message queue bridge")
+	kubernetesEvents := flag.Bool("kubernetes-events", false,
+		"Record reloads as Kubernetes Events on the reloaded workloads (requires the POD_NAME and POD_NAMESPACE env vars)")
+	kubernetesEventsAggregationThreshold := flag.Int("kubernetes-events-aggregation-threshold", 10,
+		"Number of workloads reloaded for the same secret in a cycle above which a single summary event is recorded on the reloader Pod instead")
+	changeDetection := flag.String("change-detection", reloader.ChangeDetectionVersion,
+		"How changes of secrets are detected (version: by their version; workload-hash: by the checksum of the data of the secrets of each workload)")
+	reloadThreshold := flag.String("reload-threshold", "1",
+		"Number (e.g. 2) or percentage (e.g. 50%) of a workload's secrets that need to change within a cycle to reload it")
+	minVersionDelta := flag.Int("min-version-delta", 1,
+		"Increase of the version of a secret since the last reload of a workload needed to reload it again, e.g. 3 to only reload on every third new version")
+	maxReloadsPerCycle := flag.Int("max-reloads-per-cycle", 0,
+		"Maximum number of workloads reloaded in a cycle, deferring the rest to the next cycles, the ones with the oldest pending changes first, 0 disables the limit")
+	reloadCoalesceWindow := flag.Duration("reload-coalesce-window", 0,
+		"Suppress reloads of a workload for this long after it was reloaded, reloading it once at the end with the latest changes, 0 disables coalescing")
+	pendingReloadsOnShutdown := flag.String("pending-reloads-on-shutdown", reloader.PendingReloadsDrop,
+		"How reloads triggered but not run yet (coalesced reloads and changed pinned versions) are handled on shutdown, \"drop\" (logging the workloads) or \"run\" (reloading them once before exiting)")
+	reloadLoopThreshold := flag.Int("reload-loop-threshold", 0,
+		"Stop reloading a workload once it was reloaded more than this many times within the reload loop window for the same secret versions, 0 disables loop detection")
+	reloadLoopWindow := flag.Duration("reload-loop-window", reloader.DefaultReloadLoopWindow,
+		"Window the repeated reloads of a workload are counted in for reload loop detection")
+	secretVersionsConfigMap := flag.String("secret-versions-configmap", "",
+		"Name of the ConfigMap in the pod namespace the checked secret versions are persisted to across restarts (requires the POD_NAMESPACE env var), empty keeps them in memory only")
+	secretVersionsSaveThreshold := flag.Int("secret-versions-save-threshold", 1,
+		"Number of changed secret versions saved to the secret versions ConfigMap right away, fewer changes are saved after the save max cycles or on shutdown")
+	secretVersionsSaveMaxCycles := flag.Int("secret-versions-save-max-cycles", 0,
+		"Number of cycles changed secret versions below the save threshold are kept unsaved for, 0 only saves them on shutdown")
+	reloaderConcurrency := flag.Int("reloader-concurrency", reloader.DefaultReloaderConcurrency,
+		"Number of secrets checked, and of workloads reloaded, at the same time in a cycle")
+	deploymentReloadConcurrency := flag.Int("deployment-reload-concurrency", 0,
+		"Number of Deployments reloaded at the same time in a cycle, 0 only limits them by the reloader concurrency")
+	daemonSetReloadConcurrency := flag.Int("daemonset-reload-concurrency", 0,
+		"Number of DaemonSets reloaded at the same time in a cycle, 0 only limits them by the reloader concurrency")
+	statefulSetReloadConcurrency := flag.Int("statefulset-reload-concurrency", 0,
+		"Number of StatefulSets reloaded at the same time in a cycle (e.g. 1 to reload them one at a time), 0 only limits them by the reloader concurrency")
+	cronJobReloadConcurrency := flag.Int("cronjob-reload-concurrency", 0,
+		"Number of CronJobs reloaded at the same time in a cycle, 0 only limits them by the reloader concurrency")
+	jobReloadConcurrency := flag.Int("job-reload-concurrency", 0,
+		"Number of Jobs reloaded at the same time in a cycle, 0 only limits them by the reloader concurrency")
+	reloadWindow := flag.String("reload-window", "",
+		"Time ranges of the day to confine reloads to, in HH:MM-HH:MM format separated by commas (e.g. 22:00-06:00)")
+	skipOwners := flag.String("skip-owners", "",
+		"Comma-separated list of controllers, in apiVersion/kind format, whose workloads are never reloaded")
+	reloadAnnotation := flag.String("reload-annotation", reloader.SecretReloadAnnotationName,
+		"Pod template annotation that enables reloading a workload if set to \"true\"")
+	reloadCountAnnotation := flag.String("reload-count-annotation", reloader.ReloadCountAnnotationName,
+		"Pod template annotation to keep the reload count in, e.g. one that GitOps tools are configured to ignore")
+	excludeAnnotation := flag.String("exclude-annotation", reloader.ExcludeAnnotationName,
+		"Annotation of workloads or their pod templates that excludes them from all reloader behavior if set to \"true\"")
+	var allowedSecretPaths stringsFlag
+	flag.Var(&allowedSecretPaths, "allowed-secret-paths",
+		"Regular expression that collected secret paths must fully match to be read, can be repeated, by default every path is allowed")
+	namespacePathTemplate := flag.String("namespace-path-template", "",
+		"Template relative secret paths (without a \"/\") are resolved with, e.g. secret/data/{namespace}/{path} for per-namespace secrets")
+	vaultNamespaceMismatch := flag.String("vault-namespace-mismatch", reloader.VaultNamespaceMismatchFollow,
+		"How workloads requesting another Vault namespace than VAULT_NAMESPACE are handled (follow: read their secrets from it; skip: don't track them)")
+	var secretAliases stringsFlag
+	flag.Var(&secretAliases, "secret-aliases",
+		"Comma-separated paths the same secret can be read under (e.g. secret/data/app,legacy/data/app), whose version is resolved from all of them, can be repeated")
+	kindSuffixedReloadCount := flag.Bool("kind-suffixed-reload-count", false,
+		"Suffix the reload count annotation with the lowercase kind of the workload, e.g. to tell the reloads of different kinds apart on dashboards")
+	stripAnnotations := flag.String("strip-annotations", "",
+		"Comma-separated list of pod template annotations to remove from workloads when they are reloaded")
+	reloadGenerationLabel := flag.Bool("reload-generation-label", false,
+		"Set the "+reloader.ReloadGenerationLabelName+" label on the pod template of reloaded workloads to their reload count")
+	reloadDependents := flag.Bool("reload-dependents", false,
+		"Also reload the workloads depending on reloaded workloads, declared with the "+reloader.DependsOnAnnotationName+" pod template annotation")
+	reloadViaPodDelete := flag.Bool("reload-via-pod-delete", false,
+		"Evict the pods of reloaded workloads, for workloads whose pods are not replaced when their pod template changes")
+	checkPodVersions := flag.Bool("check-pod-versions", false,
+		"Skip reloading workloads whose running pods all run the new versions of the changed secrets already")
+	requireRecreateOptIn := flag.Bool("require-recreate-opt-in", false,
+		"Only reload Deployments with the Recreate strategy if their pod template is annotated with "+reloader.AllowRecreateReloadAnnotationName+": \"true\"")
+	verifyReload := flag.Bool("verify-reload", false,
+		"Verify that reloaded workloads rolled out after a delay, warning about reloads that were reverted or not picked up")
+	verifyReloadDelay := flag.Duration("verify-reload-delay", defaultVerifyReloadDelay,
+		"Time to wait after a reload before verifying that the workload rolled out")
+	scalingSignalConfigMap := flag.String("scaling-signal-configmap", "",
+		"ConfigMap, in namespace/name format, whose annotation signals that the cluster is scaling, deferring reloads until it's finished")
+	scalingSignalAnnotation := flag.String("scaling-signal-annotation", reloader.ScalingInProgressAnnotationName,
+		"Annotation of the scaling signal ConfigMap that is set to \"true\" while the cluster is scaling")
+	featureFlagsConfigMap := flag.String("feature-flags-configmap", "",
+		"ConfigMap, in namespace/name format, whose data sets feature flags (dry-run, pause, readonly-namespaces) that are applied without a restart")
+	requireLabel := flag.String("require-label", "",
+		"Label selector (e.g. secrets-reloader.security.bank-vaults.io/enabled=true) that workloads must match to be cached and reloaded, to reduce memory use in large clusters")
+	watchNamespaces := flag.String("watch-namespaces", "",
+		"Comma-separated list of namespaces whose workloads are collected, by default every namespace is watched")
+	excludeNamespaces := flag.String("exclude-namespaces", "",
+		"Comma-separated list of namespaces whose workloads are never collected")
+	watchVaultAgentConfigMaps := flag.Bool("watch-vault-agent-configmaps", false,
+		"Collect secrets from the vault-agent ConfigMaps referenced by workloads, and watch them for changes")
+	fieldManager := flag.String("field-manager", reloader.DefaultFieldManager,
+		"Field manager the changes made to workloads are attributed to")
+	applyMode := flag.String("apply-mode", reloader.ApplyModeUpdate,
+		"How reloaded workloads are written back, \"update\" (updating them as a whole) or \"server-side\" (applying only the annotations set by the reloader with server-side apply)")
+	subkeyAwareReload := flag.Bool("subkey-aware-reload", false,
+		"Only reload workloads referencing specific keys of a secret when the value of one of those keys changed")
+	validateSecretPaths := flag.Bool("validate-secret-paths", false,
+		"Report the collected secret paths of KV v2 mounts missing the data segment (e.g. secret/foo instead of secret/data/foo) on startup")
+	missingKeyDetection := flag.Bool("missing-key-detection", false,
+		"Warn about keys workloads reference that are missing from their secrets, and reload the workloads when a referenced key disappears")
+	cycleHistorySize := flag.Int("cycle-history-size", reloader.DefaultCycleHistorySize,
+		"Number of recent reloader cycle summaries served on /debug/state, 0 disables keeping them")
+	bindAddress := flag.String("bind-address", getEnv("LISTEN_ADDRESS", ":8080"),
+		"Address to serve health checks on, and metrics unless a separate metrics bind address is set")
+	metricsBindAddress := flag.String("metrics-bind-address", "",
+		"Address to serve metrics on, separately from health checks")
+	metricsPushGateway := flag.String("metrics-push-gateway", "",
+		"URL of a Prometheus push gateway to push the final metric values to on shutdown")
+	metricsPrefix := flag.String("metrics-prefix", reloader.DefaultMetricsPrefix,
+		"Prefix of the names of the metrics, e.g. myorg_vsr for myorg_vsr_vault_sealed")
+	workloadInfoMetrics := flag.Bool("workload-info-metrics", false,
+		"Expose the secrets used by every tracked workload as the reloader_workload_info metric, mind its cardinality in large clusters")
+	showVersion := flag.Bool("version", false, "Print the version of the build and exit")
 	flag.Parse()
 
+	if *showVersion {
+		info, _ := debug.ReadBuildInfo()
+		fmt.Println(formatVersion(info))
+		os.Exit(0)
+	}
+
 	// Set up signals so we handle the shutdown signal gracefully
 	ctx := signals.SetupSignalHandler()
 
 	// Setup logger
-	var logger *slog.Logger
-	{
-		var level slog.Level
+	destination, err := logDestinationWithEventOutput(*logDestination, *eventOutputPath)
+	if err != nil {
+		slog.Error(fmt.Errorf("error setting up logger: %s", err).Error())
+		os.Exit(1)
+	}
+	logger, err := newLogger(*logLevel, *enableJSONLog, destination, os.Stdout, os.Stderr)
+	if err != nil {
+		slog.Error(fmt.Errorf("error setting up logger: %s", err).Error())
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
 
-		err := level.UnmarshalText([]byte(*logLevel))
-		if err != nil { // Silently fall back to info level
-			level = slog.LevelInfo
-		}
+	// Create kubernetes client
+	kubeConfig, err := config.GetConfig()
+	if err != nil {
+		logger.Error(fmt.Errorf("error building kubeconfig: %s", err).Error())
+		os.Exit(1)
+	}
 
-		levelFilter := func(levels ...slog.Level) func(ctx context.Context, r slog.Record) bool {
-			return func(_ context.Context, r slog.Record) bool {
-				return slices.Contains(levels, r.Level)
-			}
-		}
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		logger.Error(fmt.Errorf("error building kubernetes clientset: %s", err).Error())
+		os.Exit(1)
+	}
 
-		router := slogmulti.Router()
-
-		if *enableJSONLog {
-			// Send logs with level higher than warning to stderr
-			router = router.Add(
-				slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}),
-				levelFilter(slog.LevelWarn, slog.LevelError),
-			)
-
-			// Send info and debug logs to stdout
-			router = router.Add(
-				slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}),
-				levelFilter(slog.LevelDebug, slog.LevelInfo),
-			)
-		} else {
-			// Send logs with level higher than warning to stderr
-			router = router.Add(
-				slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}),
-				levelFilter(slog.LevelWarn, slog.LevelError),
-			)
-
-			// Send info and debug logs to stdout
-			router = router.Add(
-				slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}),
-				levelFilter(slog.LevelDebug, slog.LevelInfo),
-			)
+	var workloadInformerOptions []kubeinformers.SharedInformerOption
+	if *requireLabel != "" {
+		requiredLabelOption, err := reloader.RequiredLabelInformerOption(*requireLabel)
+		if err != nil {
+			logger.Error(fmt.Errorf("error parsing required label: %s", err).Error())
+			os.Exit(1)
 		}
+		workloadInformerOptions = append(workloadInformerOptions, requiredLabelOption)
+	}
+
+	var watchedNamespaces, excludedNamespaces []string
+	if *watchNamespaces != "" {
+		watchedNamespaces = strings.Split(*watchNamespaces, ",")
+	}
+	if *excludeNamespaces != "" {
+		excludedNamespaces = strings.Split(*excludeNamespaces, ",")
+	}
+	namespacesOption, err := reloader.WithNamespaces(watchedNamespaces, excludedNamespaces)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing namespaces: %s", err).Error())
+		os.Exit(1)
+	}
+	var configMapInformerOptions []kubeinformers.SharedInformerOption
+	if len(watchedNamespaces) == 1 {
+		// A single namespace is listed and watched by namespaced informers, instead of cluster-wide ones
+		workloadInformerOptions = append(workloadInformerOptions, kubeinformers.WithNamespace(watchedNamespaces[0]))
+		configMapInformerOptions = append(configMapInformerOptions, kubeinformers.WithNamespace(watchedNamespaces[0]))
+	}
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, *collectorSyncPeriod, workloadInformerOptions...)
+	// Jobs are watched with their own informer, so succeeded Jobs, which are never reloaded, are not cached
+	jobInformerOption, err := reloader.JobInformerOption(*requireLabel)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing required label: %s", err).Error())
+		os.Exit(1)
+	}
+	// Added last, as it replaces the required label option of the workload informers
+	jobInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, *collectorSyncPeriod,
+		append(slices.Clone(workloadInformerOptions), jobInformerOption)...)
+	// vault-agent ConfigMaps are not labeled like the workloads, so they are watched without the required label
+	configMapInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, *collectorSyncPeriod, configMapInformerOptions...)
+
+	reloadThresholdOption, err := reloader.WithReloadThreshold(*reloadThreshold)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing reload threshold: %s", err).Error())
+		os.Exit(1)
+	}
 
-		// TODO: add level filter handler
-		logger = slog.New(router.Handler())
-		logger = logger.With(slog.String("app", "vault-secrets-reloader"))
+	maxReloadsPerCycleOption, err := reloader.WithMaxReloadsPerCycle(*maxReloadsPerCycle)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing max reloads per cycle: %s", err).Error())
+		os.Exit(1)
+	}
+	minVersionDeltaOption, err := reloader.WithMinVersionDelta(*minVersionDelta)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing minimum version delta: %s", err).Error())
+		os.Exit(1)
+	}
 
-		slog.SetDefault(logger)
+	pendingReloadsOnShutdownOption, err := reloader.WithPendingReloadsOnShutdown(*pendingReloadsOnShutdown)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing pending reloads on shutdown: %s", err).Error())
+		os.Exit(1)
 	}
 
-	// Handler for health checks
-	port := os.Getenv("LISTEN_ADDRESS")
-	if port == "" {
-		port = ":8080"
+	applyModeOption, err := reloader.WithApplyMode(*applyMode)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing apply mode: %s", err).Error())
+		os.Exit(1)
 	}
 
-	go func() {
-		_ = http.ListenAndServe(port, http.HandlerFunc(
-			func(w http.ResponseWriter, _ *http.Request) {
-				_, _ = w.Write([]byte("ok"))
-			},
-		))
-	}()
+	changeDetectionOption, err := reloader.WithChangeDetection(*changeDetection)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing change detection: %s", err).Error())
+		os.Exit(1)
+	}
 
-	// Create kubernetes client
-	kubeConfig, err := config.GetConfig()
+	metricsPrefixOption, err := reloader.WithMetricsPrefix(*metricsPrefix)
 	if err != nil {
-		logger.Error(fmt.Errorf("error building kubeconfig: %s", err).Error())
+		logger.Error(fmt.Errorf("error parsing metrics prefix: %s", err).Error())
 		os.Exit(1)
 	}
 
-	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	reloadLoopDetectionOption, err := reloader.WithReloadLoopDetection(*reloadLoopThreshold, *reloadLoopWindow)
 	if err != nil {
-		logger.Error(fmt.Errorf("error building kubernetes clientset: %s", err).Error())
+		logger.Error(fmt.Errorf("error parsing reload loop detection: %s", err).Error())
+		os.Exit(1)
+	}
+
+	reloaderConcurrencyOption, err := reloader.WithReloaderConcurrency(*reloaderConcurrency)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing reloader concurrency: %s", err).Error())
+		os.Exit(1)
+	}
+	kindReloadConcurrencyOption, err := reloader.WithKindReloadConcurrency(map[string]int{
+		reloader.DeploymentKind:  *deploymentReloadConcurrency,
+		reloader.DaemonSetKind:   *daemonSetReloadConcurrency,
+		reloader.StatefulSetKind: *statefulSetReloadConcurrency,
+		reloader.CronJobKind:     *cronJobReloadConcurrency,
+		reloader.JobKind:         *jobReloadConcurrency,
+	})
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing reload concurrency: %s", err).Error())
+		os.Exit(1)
+	}
+
+	var skippedOwners []string
+	if *skipOwners != "" {
+		skippedOwners = strings.Split(*skipOwners, ",")
+	}
+	skippedOwnersOption, err := reloader.WithSkippedOwners(skippedOwners)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing skipped owners: %s", err).Error())
+		os.Exit(1)
+	}
+
+	allowedSecretPathsOption, err := reloader.WithAllowedSecretPaths(allowedSecretPaths)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing allowed secret paths: %s", err).Error())
+		os.Exit(1)
+	}
+
+	namespacePathTemplateOption, err := reloader.WithNamespacePathTemplate(*namespacePathTemplate)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing namespace path template: %s", err).Error())
+		os.Exit(1)
+	}
+	vaultNamespaceMismatchOption, err := reloader.WithVaultNamespaceMismatch(*vaultNamespaceMismatch)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing Vault namespace mismatch handling: %s", err).Error())
+		os.Exit(1)
+	}
+	secretAliasesOption, err := reloader.WithSecretAliases(secretAliases)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing secret aliases: %s", err).Error())
+		os.Exit(1)
+	}
+
+	secretReloadAnnotationOption, err := reloader.WithSecretReloadAnnotation(*reloadAnnotation)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing reload annotation: %s", err).Error())
+		os.Exit(1)
+	}
+	reloadCountAnnotationOption, err := reloader.WithReloadCountAnnotation(*reloadCountAnnotation, *kindSuffixedReloadCount)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing reload count annotation: %s", err).Error())
 		os.Exit(1)
 	}
+	excludeAnnotationOption, err := reloader.WithExcludeAnnotation(*excludeAnnotation)
+	if err != nil {
+		logger.Error(fmt.Errorf("error parsing exclude annotation: %s", err).Error())
+		os.Exit(1)
+	}
+
+	var strippedAnnotations []string
+	if *stripAnnotations != "" {
+		strippedAnnotations = strings.Split(*stripAnnotations, ",")
+	}
+
+	controllerOptions := []reloader.Option{
+		reloader.WithVaultEvents(*enableVaultEvents),
+		reloader.WithWorkloadAnnotations(*collectWorkloadAnnotations),
+		reloader.WithDeprecatedAnnotationFallback(!*disableDeprecatedAnnotation),
+		reloadThresholdOption,
+		minVersionDeltaOption,
+		maxReloadsPerCycleOption,
+		changeDetectionOption,
+		namespacesOption,
+		skippedOwnersOption,
+		reloaderConcurrencyOption,
+		kindReloadConcurrencyOption,
+		allowedSecretPathsOption,
+		namespacePathTemplateOption,
+		vaultNamespaceMismatchOption,
+		secretAliasesOption,
+		secretReloadAnnotationOption,
+		reloadCountAnnotationOption,
+		excludeAnnotationOption,
+		reloader.WithStrippedAnnotations(strippedAnnotations),
+		reloader.WithReloadViaPodDelete(*reloadViaPodDelete),
+		reloader.WithPodVersionCheck(*checkPodVersions),
+		reloader.WithRecreateReloadOptIn(*requireRecreateOptIn),
+		reloader.WithReloadGenerationLabel(*reloadGenerationLabel),
+		reloader.WithDependentReloads(*reloadDependents),
+		reloader.WithReloadCoalescing(*reloadCoalesceWindow),
+		pendingReloadsOnShutdownOption,
+		reloadLoopDetectionOption,
+		reloader.WithResyncCollectionInterval(*resyncCollectionInterval),
+		metricsPrefixOption,
+		reloader.WithWorkloadInfoMetrics(*workloadInfoMetrics),
+		reloader.WithFieldManager(*fieldManager),
+		applyModeOption,
+		reloader.WithCycleHistorySize(*cycleHistorySize),
+		reloader.WithSubkeyAwareReload(*subkeyAwareReload),
+		reloader.WithMissingKeyDetection(*missingKeyDetection),
+		reloader.WithSecretPathValidation(*validateSecretPaths),
+		reloader.WithCacheSyncTimeout(*cacheSyncTimeout),
+		reloader.WithSettleDelay(*settleDelay),
+	}
+
+	if *verifyReload {
+		controllerOptions = append(controllerOptions, reloader.WithReloadVerification(*verifyReloadDelay))
+	}
+
+	if *reloadWindow != "" {
+		reloadWindowOption, err := reloader.WithReloadWindow(*reloadWindow)
+		if err != nil {
+			logger.Error(fmt.Errorf("error parsing reload window: %s", err).Error())
+			os.Exit(1)
+		}
+		controllerOptions = append(controllerOptions, reloadWindowOption)
+	}
+
+	if *scalingSignalConfigMap != "" {
+		scalingSignalOption, err := reloader.WithScalingSignal(*scalingSignalConfigMap, *scalingSignalAnnotation)
+		if err != nil {
+			logger.Error(fmt.Errorf("error parsing scaling signal: %s", err).Error())
+			os.Exit(1)
+		}
+		controllerOptions = append(controllerOptions, scalingSignalOption)
+	}
+
+	if *secretVersionsConfigMap != "" {
+		secretVersionsConfigMapOption, err := reloader.WithSecretVersionsConfigMap(os.Getenv("POD_NAMESPACE"), *secretVersionsConfigMap)
+		if err != nil {
+			logger.Error(fmt.Errorf("error parsing secret versions ConfigMap: %s", err).Error())
+			os.Exit(1)
+		}
+		secretVersionsSaveBatchingOption, err := reloader.WithSecretVersionsSaveBatching(*secretVersionsSaveThreshold, *secretVersionsSaveMaxCycles)
+		if err != nil {
+			logger.Error(fmt.Errorf("error parsing secret versions save batching: %s", err).Error())
+			os.Exit(1)
+		}
+		controllerOptions = append(controllerOptions, secretVersionsConfigMapOption, secretVersionsSaveBatchingOption)
+	}
+
+	if *watchVaultAgentConfigMaps {
+		controllerOptions = append(controllerOptions, reloader.WithVaultAgentConfigMaps(configMapInformerFactory.Core().V1().ConfigMaps()))
+	}
+
+	// The feature flags ConfigMap is watched with its own informer, so only that ConfigMap is cached
+	var featureFlagsInformerFactory kubeinformers.SharedInformerFactory
+	if *featureFlagsConfigMap != "" {
+		namespace, name, _ := strings.Cut(*featureFlagsConfigMap, "/")
+		featureFlagsInformerFactory = kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, *collectorSyncPeriod,
+			kubeinformers.WithNamespace(namespace),
+			kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+			}),
+		)
+		featureFlagsOption, err := reloader.WithFeatureFlags(featureFlagsInformerFactory.Core().V1().ConfigMaps(), *featureFlagsConfigMap)
+		if err != nil {
+			logger.Error(fmt.Errorf("error parsing feature flags ConfigMap: %s", err).Error())
+			os.Exit(1)
+		}
+		controllerOptions = append(controllerOptions, featureFlagsOption)
+	}
+
+	if *metricsPushGateway != "" {
+		controllerOptions = append(controllerOptions, reloader.WithShutdownFlush(newMetricsPusher(*metricsPushGateway, prometheus.DefaultGatherer)))
+	}
+
+	switch *eventOutputPath {
+	case "":
+	case eventOutputStdout:
+		controllerOptions = append(controllerOptions, reloader.WithEventOutput(os.Stdout))
+	default:
+		eventOutputFile, err := os.OpenFile(*eventOutputPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			logger.Error(fmt.Errorf("error opening event output file: %s", err).Error())
+			os.Exit(1)
+		}
+		// main exits without running deferred calls, so the file is closed when the controller shuts down
+		closeEventOutput := func(context.Context) error {
+			if err := eventOutputFile.Sync(); err != nil {
+				return err
+			}
+			return eventOutputFile.Close()
+		}
+
+		controllerOptions = append(controllerOptions, reloader.WithEventOutput(eventOutputFile), reloader.WithShutdownFlush(closeEventOutput))
+	}
+
+	if *eventSinkURL != "" {
+		eventSink, err := reloader.NewHTTPEventSink(*eventSinkURL, reloader.DefaultEventSinkTimeout)
+		if err != nil {
+			logger.Error(fmt.Errorf("error parsing event sink URL: %s", err).Error())
+			os.Exit(1)
+		}
+		controllerOptions = append(controllerOptions, reloader.WithEventSink(eventSink))
+	}
 
-	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, *collectorSyncPeriod)
+	var eventBroadcaster record.EventBroadcaster
+	if *kubernetesEvents {
+		eventBroadcaster = record.NewBroadcaster()
+		eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+
+		kubernetesEventsOption, err := reloader.WithKubernetesEvents(
+			eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "vault-secrets-reloader"}),
+			&corev1.ObjectReference{Kind: "Pod", APIVersion: "v1", Namespace: os.Getenv("POD_NAMESPACE"), Name: os.Getenv("POD_NAME")},
+			*kubernetesEventsAggregationThreshold,
+		)
+		if err != nil {
+			logger.Error(fmt.Errorf("error setting up kubernetes events: %s", err).Error())
+			os.Exit(1)
+		}
+		controllerOptions = append(controllerOptions, kubernetesEventsOption)
+	}
 
 	controller := reloader.NewController(
 		logger,
@@ -137,12 +539,55 @@ func main() {
 		kubeInformerFactory.Apps().V1().Deployments(),
 		kubeInformerFactory.Apps().V1().DaemonSets(),
 		kubeInformerFactory.Apps().V1().StatefulSets(),
+		kubeInformerFactory.Batch().V1().CronJobs(),
+		jobInformerFactory.Batch().V1().Jobs(),
+		controllerOptions...,
 	)
 
+	// Servers for health checks, debugging and metrics
+	httpServers := newHTTPServers(*bindAddress, *metricsBindAddress, prometheus.DefaultGatherer, controller.DebugStateHandler())
+	startHTTPServers(logger, httpServers)
+
 	kubeInformerFactory.Start(ctx.Done())
+	jobInformerFactory.Start(ctx.Done())
+	configMapInformerFactory.Start(ctx.Done())
+	if featureFlagsInformerFactory != nil {
+		featureFlagsInformerFactory.Start(ctx.Done())
+	}
 
-	if err = controller.Run(ctx, *reloaderRunPeriod); err != nil {
+	if *oneShot {
+		err = controller.RunOnce(ctx)
+	} else {
+		err = controller.Run(ctx, *reloaderRunPeriod)
+	}
+	shutdownHTTPServers(logger, httpServers)
+	// Shut down explicitly, as main exits without running deferred calls on errors
+	if eventBroadcaster != nil {
+		eventBroadcaster.Shutdown()
+	}
+	if err != nil {
 		logger.Error(fmt.Errorf("error running controller: %s", err).Error())
 		os.Exit(1)
 	}
 }
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// stringsFlag is a flag that can be repeated, collecting each of its values.
+// Used for values that may contain commas themselves, e.g. regular expressions.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
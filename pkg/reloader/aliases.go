@@ -0,0 +1,86 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// WithSecretAliases sets groups of paths the same secret can be read under, e.g. through mount aliasing,
+// each as a comma separated list of paths (e.g. "secret/data/app,legacy/data/app"). The version of a secret
+// in a group is resolved from all members of the group, so rotating it under any of its paths reloads the
+// workloads referencing the others.
+func WithSecretAliases(groups []string) (Option, error) {
+	secretAliases := make(map[string][]string)
+	for _, group := range groups {
+		var members []string
+		for _, member := range strings.Split(group, ",") {
+			if member = normalizeSecretPath(member); member != "" && !slices.Contains(members, member) {
+				members = append(members, member)
+			}
+		}
+		if len(members) < 2 {
+			return nil, fmt.Errorf("invalid secret alias group %q, must list at least two paths", group)
+		}
+
+		for _, member := range members {
+			if _, ok := secretAliases[member]; ok {
+				return nil, fmt.Errorf("invalid secret alias group %q, path %s is already in another group", group, member)
+			}
+			secretAliases[member] = slices.DeleteFunc(slices.Clone(members), func(alias string) bool { return alias == member })
+		}
+	}
+
+	return func(c *Controller) {
+		c.secretAliases = secretAliases
+	}, nil
+}
+
+// readAliasedSecretVersion gets the current version of a secret like readSecretVersion, resolving the version
+// of secrets in an alias group as the sum of the versions of all members, so it changes with any of them.
+// The hashes of the keys are only returned for the secret itself.
+func (c *Controller) readAliasedSecretVersion(ctx context.Context, vaultClient vaultSecretReader, secretPath string, logger *slog.Logger) (int, map[string]string, error) {
+	version, keyHashes, err := c.readSecretVersion(ctx, vaultClient, secretPath, logger)
+	if err != nil {
+		return version, nil, err
+	}
+
+	for _, alias := range c.secretAliases[secretPath] {
+		aliasVersion, _, err := c.readSecretVersion(ctx, vaultClient, alias, logger)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read alias %s: %w", alias, err)
+		}
+		version += aliasVersion
+	}
+
+	return version, keyHashes, nil
+}
+
+// aliasedSecretWorkloads returns the workloads using the secret, or any of its aliases, by the path they use.
+func (c *Controller) aliasedSecretWorkloads(secretPath string) map[string][]workload {
+	secretWorkloads := make(map[string][]workload)
+	allSecretWorkloads := c.workloadSecrets.GetSecretWorkloadsMap()
+	for _, path := range append([]string{secretPath}, c.secretAliases[secretPath]...) {
+		if workloads := allSecretWorkloads[path]; len(workloads) > 0 {
+			secretWorkloads[path] = workloads
+		}
+	}
+
+	return secretWorkloads
+}
@@ -0,0 +1,102 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWithSecretAliases(t *testing.T) {
+	option, err := WithSecretAliases([]string{"secret/data/app,legacy/data/app/,kv//data/app", "secret/data/db,legacy/data/db"})
+	require.NoError(t, err)
+	controller := newTestController()
+	option(controller)
+	assert.Equal(t, map[string][]string{
+		"secret/data/app": {"legacy/data/app", "kv/data/app"},
+		"legacy/data/app": {"secret/data/app", "kv/data/app"},
+		"kv/data/app":     {"secret/data/app", "legacy/data/app"},
+		"secret/data/db":  {"legacy/data/db"},
+		"legacy/data/db":  {"secret/data/db"},
+	}, controller.secretAliases)
+
+	for _, groups := range [][]string{
+		{"secret/data/app"},
+		{"secret/data/app,secret/data/app/"},
+		{"secret/data/app,legacy/data/app", "legacy/data/app,kv/data/app"},
+	} {
+		_, err := WithSecretAliases(groups)
+		assert.Error(t, err, groups)
+	}
+}
+
+func TestReadAliasedSecretVersion(t *testing.T) {
+	ctx := context.Background()
+	option, err := WithSecretAliases([]string{"secret/data/app,legacy/data/app"})
+	require.NoError(t, err)
+	controller := newTestController()
+	option(controller)
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/app": 3, "legacy/data/app": 5, "secret/data/other": 2}}
+
+	// The version of an aliased secret is resolved from all members of its group
+	version, _, err := controller.readAliasedSecretVersion(ctx, vaultClient, "secret/data/app", controller.logger)
+	require.NoError(t, err)
+	assert.Equal(t, 8, version)
+
+	version, _, err = controller.readAliasedSecretVersion(ctx, vaultClient, "secret/data/other", controller.logger)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	// An unreadable alias fails the read, instead of changing the resolved version
+	delete(vaultClient.versions, "legacy/data/app")
+	_, _, err = controller.readAliasedSecretVersion(ctx, vaultClient, "secret/data/app", controller.logger)
+	assert.ErrorContains(t, err, "legacy/data/app")
+}
+
+func TestReloadChangedWorkloadsSecretAliases(t *testing.T) {
+	ctx := context.Background()
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	option, err := WithSecretAliases([]string{"secret/data/app,legacy/data/app"})
+	require.NoError(t, err)
+	option(controller)
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/app"})
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/app": 1, "legacy/data/app": 1}}
+	reloadCount := func() string {
+		deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		return deployment.Spec.Template.Annotations[ReloadCountAnnotationName]
+	}
+
+	// The first check records the resolved version
+	controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+	assert.Equal(t, 2, controller.secretVersions["secret/data/app"])
+	assert.Equal(t, "", reloadCount())
+
+	// Rotating the secret under its alias reloads the workload referencing the other path
+	vaultClient.setVersion("legacy/data/app", 2)
+	summary := controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+	assert.Equal(t, 1, summary.WorkloadsReloaded)
+	assert.Equal(t, "1", reloadCount())
+
+	// Events of the alias are resolved to the workloads using the other path
+	assert.Equal(t, map[string][]workload{"secret/data/app": {testWorkload}}, controller.aliasedSecretWorkloads("legacy/data/app"))
+	assert.Empty(t, controller.aliasedSecretWorkloads("secret/data/other"))
+}
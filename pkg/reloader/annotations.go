@@ -0,0 +1,91 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// WithSecretReloadAnnotation sets the pod template annotation that enables reloading a workload,
+// e.g. to migrate to an annotation of one's own.
+func WithSecretReloadAnnotation(name string) (Option, error) {
+	if err := validateAnnotationName(name); err != nil {
+		return nil, err
+	}
+
+	return func(c *Controller) {
+		c.secretReloadAnnotation = name
+	}, nil
+}
+
+// WithReloadCountAnnotation sets the pod template annotation the reload count is kept in, e.g. to use one
+// that GitOps tools are configured to ignore. If kindSuffixed is set, the annotation is suffixed with the
+// lowercase kind of the workload, so the reloads of different kinds of workloads can be told apart, e.g. on dashboards.
+func WithReloadCountAnnotation(name string, kindSuffixed bool) (Option, error) {
+	names := []string{name}
+	if kindSuffixed {
+		for _, kind := range []string{DeploymentKind, DaemonSetKind, StatefulSetKind, CronJobKind, JobKind} {
+			names = append(names, name+"-"+strings.ToLower(kind))
+		}
+	}
+	for _, name := range names {
+		if err := validateAnnotationName(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(c *Controller) {
+		c.reloadCountAnnotation = name
+		c.kindSuffixedReloadCount = kindSuffixed
+	}, nil
+}
+
+// WithExcludeAnnotation sets the annotation that excludes a workload from all reloader behavior.
+func WithExcludeAnnotation(name string) (Option, error) {
+	if err := validateAnnotationName(name); err != nil {
+		return nil, err
+	}
+
+	return func(c *Controller) {
+		c.excludeAnnotation = name
+	}, nil
+}
+
+// reloadCountAnnotationForKind returns the name of the pod template annotation the reload count of workloads
+// of the kind is kept in, suffixed with the lowercase kind if enabled (e.g. "secret-reload-count-statefulset").
+func (c *Controller) reloadCountAnnotationForKind(kind string) string {
+	if !c.kindSuffixedReloadCount {
+		return c.reloadCountAnnotation
+	}
+
+	return c.reloadCountAnnotation + "-" + strings.ToLower(kind)
+}
+
+// excluded reports whether the workload, or its pod template, has the exclude annotation set to "true".
+func (c *Controller) excluded(accessor WorkloadAccessor) bool {
+	return accessor.GetAnnotations()[c.excludeAnnotation] == "true" ||
+		accessor.GetPodTemplate().GetAnnotations()[c.excludeAnnotation] == "true"
+}
+
+func validateAnnotationName(name string) error {
+	if errs := validation.IsQualifiedName(name); len(errs) > 0 {
+		return fmt.Errorf("invalid annotation name %q: %s", name, strings.Join(errs, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotationOptions(t *testing.T) {
+	controller := newTestController()
+	assert.Equal(t, ReloadCountAnnotationName, controller.reloadCountAnnotationForKind(DeploymentKind))
+	assert.Equal(t, SecretReloadAnnotationName, controller.secretReloadAnnotation)
+	assert.Equal(t, ExcludeAnnotationName, controller.excludeAnnotation)
+
+	option, err := WithReloadCountAnnotation("example.com/reload-count", false)
+	require.NoError(t, err)
+	option(controller)
+	assert.Equal(t, "example.com/reload-count", controller.reloadCountAnnotationForKind(DeploymentKind))
+	option, err = WithReloadCountAnnotation("example.com/reload-count", true)
+	require.NoError(t, err)
+	option(controller)
+	assert.Equal(t, "example.com/reload-count-statefulset", controller.reloadCountAnnotationForKind(StatefulSetKind))
+
+	_, err = WithReloadCountAnnotation("invalid annotation", false)
+	assert.Error(t, err)
+	_, err = WithReloadCountAnnotation("", false)
+	assert.Error(t, err)
+
+	option, err = WithSecretReloadAnnotation("example.com/reload")
+	require.NoError(t, err)
+	option(controller)
+	assert.Equal(t, "example.com/reload", controller.secretReloadAnnotation)
+	_, err = WithSecretReloadAnnotation("invalid annotation")
+	assert.Error(t, err)
+
+	option, err = WithExcludeAnnotation("example.com/exclude")
+	require.NoError(t, err)
+	option(controller)
+	assert.True(t, controller.excluded(&deploymentAccessor{newTestDeployment("test", map[string]string{"example.com/exclude": "true"})}))
+	assert.False(t, controller.excluded(&deploymentAccessor{newTestDeployment("test", map[string]string{ExcludeAnnotationName: "true"})}))
+	_, err = WithExcludeAnnotation("invalid annotation")
+	assert.Error(t, err)
+}
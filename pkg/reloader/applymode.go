@@ -0,0 +1,100 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// ApplyModeUpdate reloads workloads by updating them as a whole
+	ApplyModeUpdate = "update"
+	// ApplyModeServerSide reloads workloads with server-side apply, only setting the fields the reloader manages
+	ApplyModeServerSide = "server-side"
+)
+
+// WithApplyMode sets how reloaded workloads are written back to the cluster, either ApplyModeUpdate
+// (the default) or ApplyModeServerSide, which only owns the annotations (and label) the reloader sets
+// on their pod template, cooperating with other field managers (e.g. GitOps tools). Annotations removed
+// with WithStrippedAnnotations are owned by other managers, so they are only stripped with ApplyModeUpdate.
+func WithApplyMode(mode string) (Option, error) {
+	switch mode {
+	case ApplyModeUpdate, ApplyModeServerSide:
+	default:
+		return nil, fmt.Errorf("unknown apply mode %q, must be %q or %q", mode, ApplyModeUpdate, ApplyModeServerSide)
+	}
+
+	return func(c *Controller) {
+		c.serverSideApply = mode == ApplyModeServerSide
+	}, nil
+}
+
+// applyWorkload applies the annotations and label the reloader manages on the pod template of the reloaded
+// workload, removing the ones it applied before but are no longer set.
+func (c *Controller) applyWorkload(ctx context.Context, accessor WorkloadAccessor) error {
+	patch, err := c.podTemplateApplyPatch(accessor)
+	if err != nil {
+		return err
+	}
+
+	// The reloader's fields are taken over even if another manager (e.g. an earlier update) set them
+	return accessor.Apply(ctx, c.kubeClient, patch, metav1.PatchOptions{FieldManager: c.fieldManager, Force: ptr.To(true)})
+}
+
+// podTemplateApplyPatch returns the apply patch of the annotations and label the reloader manages
+// on the pod template of the workload, with their values set on the accessor.
+func (c *Controller) podTemplateApplyPatch(accessor WorkloadAccessor) ([]byte, error) {
+	podTemplate := accessor.GetPodTemplate()
+
+	annotations := make(map[string]string)
+	for _, annotation := range []string{
+		c.reloadCountAnnotationForKind(accessor.Kind()),
+		LastReloadTimestampAnnotationName,
+		ReloadTriggerPathsAnnotationName,
+		SecretVersionsAnnotationName,
+	} {
+		if value, ok := podTemplate.Annotations[annotation]; ok {
+			annotations[annotation] = value
+		}
+	}
+	metadata := map[string]interface{}{"annotations": annotations}
+	if value, ok := podTemplate.Labels[ReloadGenerationLabelName]; ok && c.reloadGenerationLabel {
+		metadata["labels"] = map[string]string{ReloadGenerationLabelName: value}
+	}
+
+	apiVersion := "apps/v1"
+	if isBatchKind(accessor.Kind()) {
+		apiVersion = "batch/v1"
+	}
+	spec := map[string]interface{}{"template": map[string]interface{}{"metadata": metadata}}
+	if accessor.Kind() == CronJobKind {
+		spec = map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": spec}}
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       accessor.Kind(),
+		"metadata": map[string]interface{}{
+			"name":      accessor.GetName(),
+			"namespace": accessor.GetNamespace(),
+		},
+		"spec": spec,
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestReloadWorkloadServerSideApply(t *testing.T) {
+	ctx := context.Background()
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName: "true",
+		"gitops.example.com/owner": "team-a",
+	})
+	controller := newTestController()
+	// The fake clientset of NewClientset supports server-side apply
+	kubeClient := fake.NewClientset(deployment)
+	controller.kubeClient = kubeClient
+	option, err := WithApplyMode(ApplyModeServerSide)
+	require.NoError(t, err)
+	option(controller)
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+
+	changes := []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 2}}
+	require.NoError(t, controller.reloadWorkload(ctx, testWorkload, changes))
+
+	var patches []k8stesting.PatchAction
+	for _, action := range kubeClient.Actions() {
+		assert.NotEqual(t, "update", action.GetVerb())
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			patches = append(patches, patch)
+		}
+	}
+	require.Len(t, patches, 1)
+	assert.Equal(t, types.ApplyPatchType, patches[0].GetPatchType())
+
+	reloaded, err := kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	annotations := reloaded.Spec.Template.Annotations
+	assert.Equal(t, "1", annotations[ReloadCountAnnotationName])
+	assert.Equal(t, "secret/data/foo", annotations[ReloadTriggerPathsAnnotationName])
+	// The annotations of other managers are kept
+	assert.Equal(t, "team-a", annotations["gitops.example.com/owner"])
+	assert.Equal(t, "true", annotations[SecretReloadAnnotationName])
+
+	var managers []string
+	for _, managedFields := range reloaded.ManagedFields {
+		if managedFields.Operation == metav1.ManagedFieldsOperationApply {
+			managers = append(managers, managedFields.Manager)
+		}
+	}
+	assert.Equal(t, []string{DefaultFieldManager}, managers)
+
+	// The next reload increments the applied reload count
+	require.NoError(t, controller.reloadWorkload(ctx, testWorkload, changes))
+	reloaded, err = kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "2", reloaded.Spec.Template.Annotations[ReloadCountAnnotationName])
+}
+
+func TestPodTemplateApplyPatch(t *testing.T) {
+	cronJob := &cronJobAccessor{&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}}
+	cronJob.SetPodTemplateAnnotation(SecretReloadAnnotationName, "true")
+	cronJob.SetPodTemplateAnnotation(ReloadCountAnnotationName, "3")
+
+	patch, err := newTestController().podTemplateApplyPatch(cronJob)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"apiVersion": "batch/v1",
+		"kind": "CronJob",
+		"metadata": {"name": "test", "namespace": "default"},
+		"spec": {"jobTemplate": {"spec": {"template": {"metadata": {"annotations": {"`+ReloadCountAnnotationName+`": "3"}}}}}}
+	}`, string(patch))
+}
+
+func TestWithApplyMode(t *testing.T) {
+	controller := newTestController()
+	option, err := WithApplyMode(ApplyModeUpdate)
+	require.NoError(t, err)
+	option(controller)
+	assert.False(t, controller.serverSideApply)
+
+	_, err = WithApplyMode("client-side")
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+const (
+	// ChangeDetectionVersion detects changes of secrets by their KV version, or the expiry of PKI certificates
+	ChangeDetectionVersion = "version"
+	// ChangeDetectionWorkloadHash detects changes by the checksum of the data of all secrets of a workload
+	ChangeDetectionWorkloadHash = "workload-hash"
+)
+
+// WithChangeDetection sets how changes of secrets are detected, either ChangeDetectionVersion (the default)
+// or ChangeDetectionWorkloadHash, which also detects changes of unversioned (e.g. KV v1) secrets, and
+// ignores new versions that didn't change the data of the secret.
+func WithChangeDetection(mode string) (Option, error) {
+	switch mode {
+	case ChangeDetectionVersion, ChangeDetectionWorkloadHash:
+	default:
+		return nil, fmt.Errorf("unknown change detection %q, must be %q or %q", mode, ChangeDetectionVersion, ChangeDetectionWorkloadHash)
+	}
+
+	return func(c *Controller) {
+		c.changeDetection = mode
+	}, nil
+}
+
+// readSecret is a secret read in a reloader cycle, compared per workload with workload-hash change detection
+type readSecret struct {
+	oldVersion int
+	version    int
+	keyHashes  map[string]string
+}
+
+// secretDataHash returns the checksum of the secret's data, limited to the given keys if any.
+func secretDataHash(keyHashes map[string]string, keys []string) string {
+	if len(keys) == 0 {
+		keys = slices.Sorted(maps.Keys(keyHashes))
+	} else {
+		keys = slices.Sorted(slices.Values(keys))
+	}
+
+	hash := sha256.New()
+	for _, key := range keys {
+		// A missing key hashes differently than any value
+		fmt.Fprintf(hash, "%q:%q\n", key, keyHashes[key])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// combinedSecretHash returns the checksum of all the secrets of a workload, from the checksums of their data.
+func combinedSecretHash(secretHashes map[string]string) string {
+	paths := slices.Sorted(maps.Keys(secretHashes))
+
+	hash := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(hash, "%q:%q\n", path, secretHashes[path])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// compareWorkloadHashes adds the workloads whose combined secret checksum changed to workloadsToReload,
+// updating the stored checksums with the read secrets. Secrets not read in the cycle keep their stored checksum,
+// and secrets new to a workload only set the baseline, as adding them to the workload rolls it out anyway.
+func (c *Controller) compareWorkloadHashes(
+	secretWorkloads map[string][]workload,
+	readSecrets map[string]readSecret,
+	secretKeys map[workload]map[string][]string,
+	workloadsToReload map[workload][]secretChange,
+	logger *slog.Logger,
+) {
+	readWorkloadSecrets := make(map[workload][]string)
+	for secretPath, workloads := range secretWorkloads {
+		if _, ok := readSecrets[secretPath]; !ok {
+			continue
+		}
+		for _, readWorkload := range workloads {
+			readWorkloadSecrets[readWorkload] = append(readWorkloadSecrets[readWorkload], secretPath)
+		}
+	}
+
+	c.secretVersionsMu.Lock()
+	defer c.secretVersionsMu.Unlock()
+
+	for readWorkload, secretPaths := range readWorkloadSecrets {
+		stored := c.workloadSecretHashes[readWorkload]
+		current := maps.Clone(stored)
+		if current == nil {
+			current = make(map[string]string, len(secretPaths))
+		}
+
+		var changes []secretChange
+		for _, secretPath := range secretPaths {
+			secret := readSecrets[secretPath]
+			hash := secretDataHash(secret.keyHashes, secretKeys[readWorkload][secretPath])
+			if storedHash, ok := stored[secretPath]; ok && storedHash != hash {
+				changes = append(changes, secretChange{path: secretPath, oldVersion: secret.oldVersion, newVersion: secret.version})
+			}
+			current[secretPath] = hash
+		}
+		c.workloadSecretHashes[readWorkload] = current
+
+		if len(changes) == 0 {
+			continue
+		}
+		logger.Debug(fmt.Sprintf("Secrets checksum of workload %s changed from %s to %s", readWorkload, combinedSecretHash(stored), combinedSecretHash(current)))
+		workloadsToReload[readWorkload] = append(workloadsToReload[readWorkload], changes...)
+	}
+}
+
+// pruneWorkloadSecretHashes removes the checksums of secrets not used by their workload anymore,
+// must be called with secretVersionsMu held.
+func (c *Controller) pruneWorkloadSecretHashes(workloadSecrets map[workload][]string) {
+	for storedWorkload, secretHashes := range c.workloadSecretHashes {
+		secretPaths, ok := workloadSecrets[storedWorkload]
+		if !ok {
+			delete(c.workloadSecretHashes, storedWorkload)
+			continue
+		}
+		for secretPath := range secretHashes {
+			if !slices.Contains(secretPaths, secretPath) {
+				delete(secretHashes, secretPath)
+			}
+		}
+	}
+}
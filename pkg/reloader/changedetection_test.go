@@ -0,0 +1,169 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// kv1VaultClientMock returns unversioned KV v1 secrets
+type kv1VaultClientMock struct {
+	data map[string]map[string]interface{}
+}
+
+func (c *kv1VaultClientMock) ReadWithContext(_ context.Context, path string) (*vaultapi.Secret, error) {
+	data, ok := c.data[path]
+	if !ok {
+		return nil, nil
+	}
+
+	return &vaultapi.Secret{Data: data}, nil
+}
+
+func TestWithChangeDetection(t *testing.T) {
+	for _, mode := range []string{ChangeDetectionVersion, ChangeDetectionWorkloadHash} {
+		option, err := WithChangeDetection(mode)
+		require.NoError(t, err)
+		controller := newTestController()
+		option(controller)
+		assert.Equal(t, mode, controller.changeDetection)
+	}
+
+	_, err := WithChangeDetection("checksum")
+	assert.Error(t, err)
+}
+
+func TestCombinedSecretHash(t *testing.T) {
+	hashes := map[string]string{
+		"secret/data/foo": secretDataHash(map[string]string{"user": "a", "password": "b"}, nil),
+		"secret/data/bar": secretDataHash(map[string]string{"token": "c"}, nil),
+	}
+	combined := combinedSecretHash(hashes)
+	assert.Len(t, combined, 64)
+
+	// The checksum doesn't depend on the order of the secrets and keys
+	assert.Equal(t, combined, combinedSecretHash(map[string]string{
+		"secret/data/bar": secretDataHash(map[string]string{"token": "c"}, nil),
+		"secret/data/foo": secretDataHash(map[string]string{"password": "b", "user": "a"}, nil),
+	}))
+
+	// Changing the data of any secret changes the checksum
+	assert.NotEqual(t, combined, combinedSecretHash(map[string]string{
+		"secret/data/foo": secretDataHash(map[string]string{"user": "a", "password": "changed"}, nil),
+		"secret/data/bar": hashes["secret/data/bar"],
+	}))
+
+	// Values can't be moved between keys or secrets without changing the checksum
+	assert.NotEqual(t,
+		secretDataHash(map[string]string{"a": "b:c"}, nil),
+		secretDataHash(map[string]string{"a:b": "c"}, nil),
+	)
+	assert.NotEqual(t, combined, combinedSecretHash(map[string]string{
+		"secret/data/foo": hashes["secret/data/bar"],
+		"secret/data/bar": hashes["secret/data/foo"],
+	}))
+
+	// Only the referenced keys are part of the checksum
+	assert.Equal(t,
+		secretDataHash(map[string]string{"user": "a", "password": "b"}, []string{"user"}),
+		secretDataHash(map[string]string{"user": "a", "password": "changed"}, []string{"user"}),
+	)
+	assert.NotEqual(t,
+		secretDataHash(map[string]string{"user": "a"}, []string{"user"}),
+		secretDataHash(map[string]string{}, []string{"user"}),
+	)
+}
+
+func TestCheckSecretVersionsWorkloadHash(t *testing.T) {
+	controller := newTestController()
+	controller.changeDetection = ChangeDetectionWorkloadHash
+
+	workload1 := workload{name: "test1", namespace: "default", kind: DeploymentKind}
+	workload2 := workload{name: "test2", namespace: "default", kind: DeploymentKind}
+	secretWorkloads := map[string][]workload{
+		"secret/data/foo": {workload1, workload2},
+		"secret/data/bar": {workload1},
+	}
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{}, data: map[string]map[string]interface{}{}}
+	vaultClient.setData("secret/data/foo", 1, map[string]interface{}{"password": "secret1"})
+	vaultClient.setData("secret/data/bar", 1, map[string]interface{}{"token": "token1"})
+
+	checkSecretVersions := func() map[workload][]secretChange {
+		workloadsToReload, errs := controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+		require.Empty(t, errs)
+		return workloadsToReload
+	}
+
+	assert.Empty(t, checkSecretVersions())
+	combined := combinedSecretHash(controller.workloadSecretHashes[workload1])
+
+	t.Run("new version without data change", func(t *testing.T) {
+		vaultClient.setData("secret/data/foo", 2, map[string]interface{}{"password": "secret1"})
+		assert.Empty(t, checkSecretVersions())
+		assert.Equal(t, combined, combinedSecretHash(controller.workloadSecretHashes[workload1]))
+	})
+
+	t.Run("data change", func(t *testing.T) {
+		vaultClient.setData("secret/data/bar", 2, map[string]interface{}{"token": "token2"})
+		assert.Equal(t, map[workload][]secretChange{
+			workload1: {{path: "secret/data/bar", oldVersion: 1, newVersion: 2}},
+		}, checkSecretVersions())
+		assert.NotEqual(t, combined, combinedSecretHash(controller.workloadSecretHashes[workload1]))
+
+		// The new checksum is the baseline for the next cycle
+		assert.Empty(t, checkSecretVersions())
+	})
+
+	t.Run("shared secret", func(t *testing.T) {
+		vaultClient.setData("secret/data/foo", 3, map[string]interface{}{"password": "secret2"})
+		change := secretChange{path: "secret/data/foo", oldVersion: 2, newVersion: 3}
+		assert.Equal(t, map[workload][]secretChange{
+			workload1: {change},
+			workload2: {change},
+		}, checkSecretVersions())
+	})
+
+	t.Run("pruned", func(t *testing.T) {
+		controller.workloadSecrets.Store(workload1, []string{"secret/data/foo"})
+		controller.pruneSecretVersions(controller.workloadSecrets.GetSecretWorkloadsMap(), controller.workloadSecrets.GetWorkloadSecretsMap())
+		assert.Equal(t, []string{"secret/data/foo"}, slices.Sorted(maps.Keys(controller.workloadSecretHashes[workload1])))
+		assert.NotContains(t, controller.workloadSecretHashes, workload2)
+	})
+}
+
+func TestCheckSecretVersionsWorkloadHashKV1(t *testing.T) {
+	controller := newTestController()
+	controller.changeDetection = ChangeDetectionWorkloadHash
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	secretWorkloads := map[string][]workload{"kv/app": {testWorkload}}
+	vaultClient := &kv1VaultClientMock{data: map[string]map[string]interface{}{"kv/app": {"password": "secret1"}}}
+
+	workloadsToReload, errs := controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+	require.Empty(t, errs)
+	assert.Empty(t, workloadsToReload)
+
+	vaultClient.data["kv/app"] = map[string]interface{}{"password": "secret2"}
+	workloadsToReload, errs = controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+	require.Empty(t, errs)
+	assert.Equal(t, map[workload][]secretChange{testWorkload: {{path: "kv/app"}}}, workloadsToReload)
+}
@@ -0,0 +1,140 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// reloadCoalescer keeps the time workloads were last reloaded at, and the changes of the workloads
+// whose reload is suppressed until the end of their coalesce window.
+type reloadCoalescer struct {
+	window time.Duration
+
+	mu         sync.Mutex
+	lastReload map[workload]time.Time
+	// pending map[Workload][]secretChange, a reload is scheduled at the end of the window for each
+	pending map[workload][]secretChange
+	// scheduled tracks the goroutines of the scheduled reloads, so they are finished on shutdown
+	scheduled sync.WaitGroup
+}
+
+// WithReloadCoalescing suppresses reloads of a workload for the given window after it was reloaded,
+// reloading it once at the end of the window with the changes detected meanwhile, so secrets changing
+// across successive short poll periods don't reload it repeatedly. Zero, the default, disables coalescing.
+func WithReloadCoalescing(window time.Duration) Option {
+	return func(c *Controller) {
+		if window > 0 {
+			c.reloadCoalescer = &reloadCoalescer{
+				window:     window,
+				lastReload: make(map[workload]time.Time),
+				pending:    make(map[workload][]secretChange),
+			}
+		}
+	}
+}
+
+// recordReload starts the coalesce window of the reloaded workload, forgetting the windows that ended.
+func (c *Controller) recordReload(reloaded workload) {
+	if c.reloadCoalescer == nil {
+		return
+	}
+
+	c.reloadCoalescer.mu.Lock()
+	defer c.reloadCoalescer.mu.Unlock()
+
+	now := c.clock.Now()
+	for recorded, lastReload := range c.reloadCoalescer.lastReload {
+		if !now.Before(lastReload.Add(c.reloadCoalescer.window)) {
+			delete(c.reloadCoalescer.lastReload, recorded)
+		}
+	}
+	c.reloadCoalescer.lastReload[reloaded] = now
+}
+
+// coalesceReloads removes the workloads reloaded within the coalesce window from workloadsToReload,
+// scheduling their reload at the end of the window with the latest changes.
+func (c *Controller) coalesceReloads(ctx context.Context, workloadsToReload map[workload][]secretChange, logger *slog.Logger) {
+	if c.reloadCoalescer == nil {
+		return
+	}
+
+	c.reloadCoalescer.mu.Lock()
+	defer c.reloadCoalescer.mu.Unlock()
+
+	now := c.clock.Now()
+	for coalesced, changes := range workloadsToReload {
+		lastReload, ok := c.reloadCoalescer.lastReload[coalesced]
+		if !ok {
+			continue
+		}
+		windowEnd := lastReload.Add(c.reloadCoalescer.window)
+		if !now.Before(windowEnd) {
+			continue
+		}
+
+		delete(workloadsToReload, coalesced)
+		pendingChanges, scheduled := c.reloadCoalescer.pending[coalesced]
+		c.reloadCoalescer.pending[coalesced] = mergeSecretChanges(pendingChanges, changes)
+		if scheduled {
+			continue
+		}
+
+		logger.Info(fmt.Sprintf("Workload %s was reloaded recently, coalescing its reload until %s", coalesced, windowEnd.Format(time.RFC3339)))
+		c.reloadCoalescer.scheduled.Add(1)
+		go c.reloadCoalescedWorkload(ctx, coalesced, windowEnd)
+	}
+}
+
+// reloadCoalescedWorkload reloads the workload with the changes detected within its coalesce window, once it ends.
+func (c *Controller) reloadCoalescedWorkload(ctx context.Context, coalesced workload, windowEnd time.Time) {
+	defer c.reloadCoalescer.scheduled.Done()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-c.clock.After(windowEnd.Sub(c.clock.Now())):
+	}
+
+	c.reloadCoalescer.mu.Lock()
+	changes, ok := c.reloadCoalescer.pending[coalesced]
+	delete(c.reloadCoalescer.pending, coalesced)
+	c.reloadCoalescer.mu.Unlock()
+	// The pending changes were taken on shutdown meanwhile
+	if !ok {
+		return
+	}
+
+	// Workloads deleted since their reload was coalesced are not reloaded
+	if _, ok := c.workloadSecrets.GetWorkloadSecretsMap()[coalesced]; !ok {
+		return
+	}
+
+	ctx = WithCorrelationID(ctx, string(uuid.NewUUID()))
+	logger := c.logger.With(
+		slog.String("worker", "reloader"),
+		slog.String("correlation_id", correlationIDFromContext(ctx)),
+	)
+
+	workloadsToReload := map[workload][]secretChange{coalesced: changes}
+	c.deferOutsideReloadWindow(workloadsToReload, logger)
+	c.reloadWorkloads(ctx, workloadsToReload, logger)
+}
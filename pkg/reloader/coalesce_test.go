@@ -0,0 +1,114 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestReloadChangedWorkloadsCoalescing(t *testing.T) {
+	window := time.Minute
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	fakeClock := testingclock.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	controller.clock = fakeClock
+	WithReloadCoalescing(window)(controller)
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/foo"})
+	controller.secretVersions["secret/data/foo"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 1}}
+	getDeployment := func() map[string]string {
+		deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		return deployment.Spec.Template.Annotations
+	}
+	reloadChangedWorkloads := func(version int) CycleSummary {
+		vaultClient.setVersion("secret/data/foo", version)
+		return controller.reloadChangedWorkloads(
+			context.Background(),
+			vaultClient,
+			controller.workloadSecrets.GetSecretWorkloadsMap(),
+			controller.logger,
+		)
+	}
+
+	// The first change reloads the workload right away
+	summary := reloadChangedWorkloads(2)
+	assert.Equal(t, 1, summary.WorkloadsReloaded)
+	assert.Equal(t, "1", getDeployment()[ReloadCountAnnotationName])
+
+	// Rapid changes within the window are coalesced
+	for version := 3; version <= 5; version++ {
+		fakeClock.Step(10 * time.Second)
+		summary := reloadChangedWorkloads(version)
+		assert.Equal(t, 1, summary.SecretsChanged)
+		assert.Equal(t, 0, summary.WorkloadsReloaded)
+		assert.Equal(t, "1", getDeployment()[ReloadCountAnnotationName])
+	}
+
+	// The workload is reloaded once at the end of the window, with the latest changes
+	require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+	fakeClock.Step(window - 30*time.Second)
+	assert.Eventually(t, func() bool { return getDeployment()[ReloadCountAnnotationName] == "2" }, time.Second, time.Millisecond)
+	assert.Equal(t, "secret/data/foo", getDeployment()[ReloadTriggerPathsAnnotationName])
+	controller.reloadCoalescer.mu.Lock()
+	assert.Empty(t, controller.reloadCoalescer.pending)
+	controller.reloadCoalescer.mu.Unlock()
+
+	// Changes after the window of the coalesced reload reload the workload right away again
+	fakeClock.Step(window)
+	summary = reloadChangedWorkloads(6)
+	assert.Equal(t, 1, summary.WorkloadsReloaded)
+	assert.Equal(t, "3", getDeployment()[ReloadCountAnnotationName])
+}
+
+func TestCoalesceReloadsMergesChanges(t *testing.T) {
+	controller := newTestController()
+	fakeClock := testingclock.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	controller.clock = fakeClock
+	WithReloadCoalescing(time.Minute)(controller)
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	otherWorkload := workload{name: "other", namespace: "default", kind: DeploymentKind}
+	controller.recordReload(testWorkload)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for version := 2; version <= 4; version++ {
+		workloadsToReload := map[workload][]secretChange{
+			testWorkload:  {{path: "secret/data/foo", oldVersion: version - 1, newVersion: version}},
+			otherWorkload: {{path: "secret/data/foo", oldVersion: version - 1, newVersion: version}},
+		}
+		controller.coalesceReloads(ctx, workloadsToReload, controller.logger)
+
+		// Only workloads reloaded recently are coalesced
+		assert.Equal(t, []workload{otherWorkload}, slices.Collect(maps.Keys(workloadsToReload)))
+	}
+
+	controller.reloadCoalescer.mu.Lock()
+	defer controller.reloadCoalescer.mu.Unlock()
+	assert.Equal(t, map[workload][]secretChange{
+		testWorkload: {{path: "secret/data/foo", oldVersion: 1, newVersion: 4}},
+	}, controller.reloadCoalescer.pending)
+}
@@ -17,10 +17,12 @@ package reloader
 import (
 	"fmt"
 	"log/slog"
+	"maps"
 	"regexp"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bank-vaults/secrets-webhook/pkg/common"
 	corev1 "k8s.io/api/core/v1"
@@ -29,8 +31,12 @@ import (
 type workloadSecretsStore interface {
 	Store(workload workload, secrets []string)
 	Delete(workload workload)
+	SetConfig(workload workload, config workloadConfig)
+	SetSecretKeys(workload workload, secretKeys map[string][]string)
 	GetWorkloadSecretsMap() map[workload][]string
 	GetSecretWorkloadsMap() map[string][]workload
+	GetConfigs() map[workload]workloadConfig
+	GetSecretKeys() map[workload]map[string][]string
 }
 
 type workload struct {
@@ -39,121 +45,434 @@ type workload struct {
 	kind      string
 }
 
+// workloadConfig holds the per-workload overrides of the global settings
+type workloadConfig struct {
+	// pollPeriod overrides the period the workload's secrets are checked with, zero means the default period
+	pollPeriod time.Duration
+	// reloadThreshold overrides the amount of changed secrets needed to reload the workload
+	reloadThreshold *reloadThreshold
+	// minVersionDelta overrides the version increase of a secret needed to reload the workload, zero means the default delta
+	minVersionDelta int
+	// vaultRole is the Vault role the workload's secrets are read with, empty means the default role
+	vaultRole string
+}
+
 type workloadSecrets struct {
 	sync.RWMutex
 	workloadSecretsMap map[workload][]string
+	// secretWorkloadsMap is the inverse of workloadSecretsMap, maintained on every change so it can be read cheaply.
+	// Its slices are never modified in place, as they are shared with the maps returned by GetSecretWorkloadsMap.
+	secretWorkloadsMap map[string][]workload
+	configs            map[workload]workloadConfig
+	// secretKeys map[Workload]map[secretPath][]key
+	secretKeys map[workload]map[string][]string
 }
 
 func newWorkloadSecrets() workloadSecretsStore {
 	return &workloadSecrets{
 		workloadSecretsMap: make(map[workload][]string),
+		secretWorkloadsMap: make(map[string][]workload),
+		configs:            make(map[workload]workloadConfig),
+		secretKeys:         make(map[workload]map[string][]string),
 	}
 }
 
 func (w *workloadSecrets) Store(workload workload, secrets []string) {
 	w.Lock()
 	defer w.Unlock()
+	w.removeFromIndex(workload, w.workloadSecretsMap[workload])
 	w.workloadSecretsMap[workload] = secrets
+	w.addToIndex(workload, secrets)
 }
 
 func (w *workloadSecrets) Delete(workload workload) {
 	w.Lock()
 	defer w.Unlock()
+	w.removeFromIndex(workload, w.workloadSecretsMap[workload])
 	delete(w.workloadSecretsMap, workload)
+	delete(w.configs, workload)
+	delete(w.secretKeys, workload)
 }
 
-func (w *workloadSecrets) GetWorkloadSecretsMap() map[workload][]string {
-	return w.workloadSecretsMap
+// SetConfig stores the overrides of the workload, a zero config means no overrides.
+func (w *workloadSecrets) SetConfig(workload workload, config workloadConfig) {
+	w.Lock()
+	defer w.Unlock()
+	if config == (workloadConfig{}) {
+		delete(w.configs, workload)
+		return
+	}
+	w.configs[workload] = config
 }
 
-func (w *workloadSecrets) GetSecretWorkloadsMap() map[string][]workload {
+// SetSecretKeys stores the keys the workload references of its secrets, secrets without
+// referenced keys are used as a whole.
+func (w *workloadSecrets) SetSecretKeys(workload workload, secretKeys map[string][]string) {
 	w.Lock()
 	defer w.Unlock()
-	secretWorkloads := make(map[string][]workload)
+	if len(secretKeys) == 0 {
+		delete(w.secretKeys, workload)
+		return
+	}
+	w.secretKeys[workload] = secretKeys
+}
+
+func (w *workloadSecrets) GetWorkloadSecretsMap() map[workload][]string {
+	w.RLock()
+	defer w.RUnlock()
+	workloadSecrets := make(map[workload][]string, len(w.workloadSecretsMap))
 	for workload, secretPaths := range w.workloadSecretsMap {
-		for _, secretPath := range secretPaths {
-			secretWorkloads[secretPath] = append(secretWorkloads[secretPath], workload)
-		}
+		workloadSecrets[workload] = secretPaths
+	}
+	return workloadSecrets
+}
+
+func (w *workloadSecrets) GetSecretWorkloadsMap() map[string][]workload {
+	w.RLock()
+	defer w.RUnlock()
+	secretWorkloads := make(map[string][]workload, len(w.secretWorkloadsMap))
+	for secretPath, workloads := range w.secretWorkloadsMap {
+		secretWorkloads[secretPath] = workloads
 	}
 	return secretWorkloads
 }
 
-func (c *Controller) collectWorkloadSecrets(workload workload, template corev1.PodTemplateSpec) {
+// addToIndex adds the workload to the inverse index for each of its secrets, must be called with the lock held.
+func (w *workloadSecrets) addToIndex(workload workload, secrets []string) {
+	for _, secretPath := range secrets {
+		// Clip so append copies the slice instead of writing into a shared backing array
+		w.secretWorkloadsMap[secretPath] = append(slices.Clip(w.secretWorkloadsMap[secretPath]), workload)
+	}
+}
+
+// removeFromIndex removes the workload from the inverse index for each of its secrets, must be called with the lock held.
+func (w *workloadSecrets) removeFromIndex(removed workload, secrets []string) {
+	for _, secretPath := range secrets {
+		workloads, ok := w.secretWorkloadsMap[secretPath]
+		if !ok {
+			continue
+		}
+
+		workloads = slices.DeleteFunc(slices.Clone(workloads), func(indexed workload) bool { return indexed == removed })
+		if len(workloads) == 0 {
+			delete(w.secretWorkloadsMap, secretPath)
+			continue
+		}
+		w.secretWorkloadsMap[secretPath] = workloads
+	}
+}
+
+func (w *workloadSecrets) GetConfigs() map[workload]workloadConfig {
+	w.RLock()
+	defer w.RUnlock()
+	configs := make(map[workload]workloadConfig, len(w.configs))
+	for workload, config := range w.configs {
+		configs[workload] = config
+	}
+	return configs
+}
+
+func (w *workloadSecrets) GetSecretKeys() map[workload]map[string][]string {
+	w.RLock()
+	defer w.RUnlock()
+	secretKeys := make(map[workload]map[string][]string, len(w.secretKeys))
+	for workload, keys := range w.secretKeys {
+		secretKeys[workload] = keys
+	}
+	return secretKeys
+}
+
+// collectionSourceAgentConfigMap is the collection source label of secrets collected from vault-agent ConfigMaps
+const collectionSourceAgentConfigMap = "vault_agent_configmap"
+
+func (c *Controller) collectWorkloadSecrets(workload workload, workloadAnnotations map[string]string, template corev1.PodTemplateSpec) {
 	collectorLogger := c.logger.With(slog.String("worker", "collector"))
 
-	// Collect secrets from different locations
-	vaultSecretPaths := collectSecrets(template)
+	// Collect secrets from different locations, keeping track of where they were found
+	sources := c.collectSecretSources(template)
+	if c.collectWorkloadAnnotations {
+		sources.add(collectionSourceWorkloadAnnotation, c.collectSecretsFromAnnotations(workloadAnnotations)...)
+	}
+	// A failing source doesn't prevent collecting the secrets of the others
+	agentSecretPaths, err := c.collectSecretsFromAgentConfigMap(workload.namespace, template.GetAnnotations())
+	if err != nil {
+		collectorLogger.Warn(fmt.Errorf("failed to collect some secrets of %s %s/%s, collecting the rest: %w", workload.kind, workload.namespace, workload.name, err).Error())
+		c.metrics.collectionFailures.WithLabelValues(workload.namespace, workload.kind, collectionSourceAgentConfigMap).Inc()
+	}
+	sources.add(collectionSourceAgentConfigMap, agentSecretPaths...)
+	referencedSecretPaths := c.collectSecretsFromSecretReferences(workload.namespace, template, collectorLogger)
+	sources.add(collectionSourceSecretReference, referencedSecretPaths...)
+	// Secrets read from another Vault than the default one are tracked apart
+	target := c.getWorkloadVaultTarget(template.GetAnnotations())
+	vaultRole := c.getServiceAccountVaultRole(workload.namespace, template, collectorLogger)
+	vaultSecretPaths := c.resolveSecretPaths(workload.namespace, sources.paths())
+	vaultSecretPaths = c.expandWildcardSecretPaths(workload, target, vaultRole, vaultSecretPaths, collectorLogger)
+	slices.Sort(vaultSecretPaths)
+	vaultSecretPaths = slices.Compact(vaultSecretPaths)
+	vaultSecretPaths = c.dropDisallowedSecretPaths(workload, vaultSecretPaths, collectorLogger)
+	vaultSecretPaths = c.dropPinnedSecretPaths(workload, workloadAnnotations, vaultSecretPaths, collectorLogger)
 
+	if len(vaultSecretPaths) == 0 && err != nil {
+		// The secrets collected before are kept, instead of dropping the workload over a failing source
+		collectorLogger.Warn(fmt.Sprintf("No secrets collected from %s %s/%s, keeping its previously collected secrets", workload.kind, workload.namespace, workload.name))
+		return
+	}
 	if len(vaultSecretPaths) == 0 {
+		// The workload may have been collected before all of its secrets got pinned or removed
 		collectorLogger.Debug("No Vault secret paths found in container env vars")
+		c.workloadSecrets.Delete(workload)
 		return
 	}
 	collectorLogger.Debug(fmt.Sprintf("Vault secret paths found: %v", vaultSecretPaths))
+	if !c.checkVaultNamespaceMismatch(workload, target, collectorLogger) {
+		c.workloadSecrets.Delete(workload)
+		return
+	}
+	for i, secretPath := range vaultSecretPaths {
+		vaultSecretPaths[i] = qualifySecretPath(target, secretPath)
+	}
+	c.logSecretSources(workload, target, sources, vaultSecretPaths, collectorLogger)
 
 	// Add workload and secrets to workloadSecrets map
 	c.workloadSecrets.Store(workload, vaultSecretPaths)
+	config := getWorkloadConfig(template.GetAnnotations(), collectorLogger)
+	config.vaultRole = vaultRole
+	c.workloadSecrets.SetConfig(workload, config)
+	if c.subkeyAwareReload || c.missingKeyDetection {
+		secretKeys := c.collectWorkloadSecretKeys(workloadAnnotations, template, append(agentSecretPaths, referencedSecretPaths...))
+		c.workloadSecrets.SetSecretKeys(workload, qualifySecretKeys(target, c.resolveSecretKeys(workload.namespace, secretKeys)))
+	}
 	collectorLogger.Info(fmt.Sprintf("Collected secrets from %s %s/%s", workload.kind, workload.namespace, workload.name))
 }
 
-func collectSecrets(template corev1.PodTemplateSpec) []string {
+// dropDisallowedSecretPaths removes the secret paths that don't match any of the allowed secret paths, if set.
+func (c *Controller) dropDisallowedSecretPaths(workload workload, vaultSecretPaths []string, logger *slog.Logger) []string {
+	if len(c.allowedSecretPaths) == 0 {
+		return vaultSecretPaths
+	}
+
+	return slices.DeleteFunc(vaultSecretPaths, func(secretPath string) bool {
+		if slices.ContainsFunc(c.allowedSecretPaths, func(re *regexp.Regexp) bool { return re.MatchString(secretPath) }) {
+			return false
+		}
+
+		logger.Warn(fmt.Sprintf("Secret path %s of %s %s/%s is not allowed, it won't be read", secretPath, workload.kind, workload.namespace, workload.name))
+		c.metrics.disallowedSecretPaths.WithLabelValues(workload.namespace, workload.kind).Inc()
+		return true
+	})
+}
+
+// getWorkloadConfig returns the overrides set on the workload with annotations.
+func getWorkloadConfig(annotations map[string]string, logger *slog.Logger) workloadConfig {
+	return workloadConfig{
+		pollPeriod:      getPollPeriod(annotations, logger),
+		reloadThreshold: getReloadThreshold(annotations, logger),
+		minVersionDelta: getMinVersionDelta(annotations, logger),
+	}
+}
+
+// MinPollPeriod is the shortest poll period a workload can override the default one with,
+// so a single workload can't make the reloader check its secrets in Vault on every scheduler tick
+const MinPollPeriod = 10 * time.Second
+
+// getPollPeriod returns the poll period override set on the workload, or zero if not set or invalid.
+// Periods shorter than MinPollPeriod are raised to it.
+func getPollPeriod(annotations map[string]string, logger *slog.Logger) time.Duration {
+	value := annotations[PollPeriodAnnotationName]
+	if value == "" {
+		return 0
+	}
+
+	period, err := time.ParseDuration(value)
+	if err != nil || period <= 0 {
+		logger.Warn(fmt.Sprintf("Invalid poll period %q, using the default period", value))
+		return 0
+	}
+	if period < MinPollPeriod {
+		logger.Warn(fmt.Sprintf("Poll period %q is shorter than the minimum, using %s", value, MinPollPeriod))
+		return MinPollPeriod
+	}
+
+	return period
+}
+
+// getReloadThreshold returns the reload threshold override set on the workload, or nil if not set or invalid.
+func getReloadThreshold(annotations map[string]string, logger *slog.Logger) *reloadThreshold {
+	value := annotations[ReloadThresholdAnnotationName]
+	if value == "" {
+		return nil
+	}
+
+	threshold, err := parseReloadThreshold(value)
+	if err != nil {
+		logger.Warn(fmt.Errorf("invalid reload threshold, using the default threshold: %w", err).Error())
+		return nil
+	}
+
+	return &threshold
+}
+
+func (c *Controller) collectSecrets(template corev1.PodTemplateSpec) []string {
+	return c.collectSecretSources(template).paths()
+}
+
+func collectSecretsFromContainerEnvVars(containers []corev1.Container) []string {
+	vaultSecretPaths := []string{}
+	for _, reference := range containerSecretReferences(containers) {
+		if secret, _, ok := parseSecretReference(reference); ok {
+			vaultSecretPaths = append(vaultSecretPaths, secret)
+		}
+	}
+
+	return vaultSecretPaths
+}
+
+// collectSecretKeysFromContainerEnvVars returns the keys referenced of each secret in the container env vars.
+func collectSecretKeysFromContainerEnvVars(containers []corev1.Container) map[string][]string {
+	secretKeys := make(map[string][]string)
+	for _, reference := range containerSecretReferences(containers) {
+		if secret, key, ok := parseSecretReference(reference); ok {
+			secretKeys[secret] = append(secretKeys[secret], key)
+		}
+	}
+
+	for secret, keys := range secretKeys {
+		slices.Sort(keys)
+		secretKeys[secret] = slices.Compact(keys)
+	}
+
+	return secretKeys
+}
+
+// collectWorkloadSecretKeys returns the keys the workload references of each of its secrets,
+// leaving out the secrets that are also used as a whole, e.g. through annotations, vault-agent templates
+// or referenced Kubernetes Secrets (see wholeSecretPaths).
+func (c *Controller) collectWorkloadSecretKeys(workloadAnnotations map[string]string, template corev1.PodTemplateSpec, wholeSecretPaths []string) map[string][]string {
 	containers := []corev1.Container{}
 	containers = append(containers, template.Spec.Containers...)
 	containers = append(containers, template.Spec.InitContainers...)
+	secretKeys := collectSecretKeysFromContainerEnvVars(containers)
 
-	vaultSecretPaths := []string{}
-	vaultSecretPaths = append(vaultSecretPaths, collectSecretsFromContainerEnvVars(containers)...)
-	vaultSecretPaths = append(vaultSecretPaths, collectSecretsFromAnnotations(template.GetAnnotations())...)
+	wholeSecrets := c.collectSecretsFromAnnotations(template.GetAnnotations())
+	if c.collectWorkloadAnnotations {
+		wholeSecrets = append(wholeSecrets, c.collectSecretsFromAnnotations(workloadAnnotations)...)
+	}
+	wholeSecrets = append(wholeSecrets, wholeSecretPaths...)
+	for _, secret := range wholeSecrets {
+		delete(secretKeys, secret)
+		if isWildcardSecretPath(secret) {
+			maps.DeleteFunc(secretKeys, func(secretPath string, _ []string) bool {
+				return matchesWildcardSecretPath(secret, secretPath)
+			})
+		}
+	}
 
-	// Remove duplicates
-	slices.Sort(vaultSecretPaths)
-	return slices.Compact(vaultSecretPaths)
+	return secretKeys
 }
 
-func collectSecretsFromContainerEnvVars(containers []corev1.Container) []string {
-	vaultSecretPaths := []string{}
+// containerSecretReferences returns the secret references in the env vars of the containers,
+// including the ones referenced inline.
+func containerSecretReferences(containers []corev1.Container) []string {
+	references := []string{}
 	// iterate through all environment variables and extract secrets
 	for _, container := range containers {
 		for _, env := range container.Env {
-			// Skip if env var does not contain a vault secret or is a secret with pinned version
-			if isValidPrefix(env.Value) && unversionedSecretValue(env.Value) {
-				secret := regexp.MustCompile(`vault:(.*?)#`).FindStringSubmatch(env.Value)[1]
-				if secret != "" {
-					vaultSecretPaths = append(vaultSecretPaths, secret)
+			switch {
+			case isValidPrefix(env.Value):
+				references = append(references, env.Value)
+
+			case strings.Contains(env.Value, "vault:"):
+				// Secrets can also be referenced inline, e.g. "postgres://${vault:secret/data/db#user}@db"
+				for _, reference := range inlineSecretRegexp.FindAllStringSubmatch(env.Value, -1) {
+					references = append(references, reference[1])
 				}
+				// or embedded in structured values, e.g. `{"user":"vault:secret/data/db#user"}`
+				references = append(references, embeddedSecretReferences(inlineSecretRegexp.ReplaceAllString(env.Value, ""))...)
 			}
 		}
 	}
 
-	return vaultSecretPaths
+	return references
+}
+
+// parseSecretReference returns the secret path and key of a secret reference with any of the prefixes
+// the webhook supports, e.g. "vault:secret/data/app#key" or ">>vault:secret/data/app#key".
+// References without a key (e.g. "vault:login") and secrets with pinned version are skipped.
+func parseSecretReference(reference string) (string, string, bool) {
+	if !isValidPrefix(reference) || !unversionedSecretValue(reference) {
+		return "", "", false
+	}
+
+	reference = strings.TrimPrefix(reference, ">>")
+	secret, key, _ := strings.Cut(strings.TrimPrefix(reference, "vault:"), "#")
+	secret = normalizeSecretPath(secret)
+
+	return secret, key, secret != ""
 }
 
-func collectSecretsFromAnnotations(annotations map[string]string) []string {
+// normalizeSecretPath returns the canonical form of a secret path, without empty segments
+// (e.g. "secret/data//app/" becomes "secret/data/app"), so the same secret is only stored and read once.
+func normalizeSecretPath(secretPath string) string {
+	segments := strings.FieldsFunc(secretPath, func(r rune) bool { return r == '/' })
+	return strings.Join(segments, "/")
+}
+
+// collectSecretsFromAnnotations collects the unversioned secret paths of the vault-from-path annotation,
+// or of the deprecated vault-env-from-path annotation if the former is not set, unless disabled.
+func (c *Controller) collectSecretsFromAnnotations(annotations map[string]string) []string {
 	vaultSecretPaths := []string{}
 
 	secretPaths := annotations[common.VaultFromPathAnnotation]
 	if secretPaths != "" {
-		for _, secretPath := range strings.Split(secretPaths, ",") {
-			if unversionedAnnotationSecretValue(secretPath) {
-				vaultSecretPaths = append(vaultSecretPaths, secretPath)
-			}
-		}
+		vaultSecretPaths = append(vaultSecretPaths, c.annotationSecretPaths(secretPaths)...)
 	}
 
 	// This is here to preserve backwards compatibility with the deprecated annotation
-	if len(vaultSecretPaths) == 0 {
+	if len(vaultSecretPaths) == 0 && !c.skipDeprecatedAnnotation {
 		deprecatedSecretPaths := annotations[common.VaultEnvFromPathAnnotationDeprecated]
 		if deprecatedSecretPaths != "" {
-			for _, secretPath := range strings.Split(deprecatedSecretPaths, ",") {
-				if unversionedAnnotationSecretValue(secretPath) {
-					vaultSecretPaths = append(vaultSecretPaths, secretPath)
-				}
-			}
+			vaultSecretPaths = append(vaultSecretPaths, c.annotationSecretPaths(deprecatedSecretPaths)...)
 		}
 	}
 
 	return vaultSecretPaths
 }
 
+// annotationSecretPaths returns the unversioned secret paths of the comma separated secret paths
+// of an annotation. Paths ending with a wildcard (e.g. "secret/data/app/*") are kept as they are,
+// they are expanded to the secrets listed from Vault when the workload is collected (see expandWildcardSecretPaths).
+func (c *Controller) annotationSecretPaths(secretPaths string) []string {
+	vaultSecretPaths := []string{}
+	for _, secretPath := range strings.Split(secretPaths, ",") {
+		if !unversionedAnnotationSecretValue(secretPath) {
+			continue
+		}
+
+		vaultSecretPaths = append(vaultSecretPaths, normalizeSecretPath(secretPath))
+	}
+
+	return vaultSecretPaths
+}
+
+// implementation based on bank-vaults/vault-sdk/injector/vault/injector.go
+var inlineSecretRegexp = regexp.MustCompile(`\${([>]{0,2}vault:.*?#*}?)}`)
+
+// embeddedSecretRegexp matches the secret references embedded in JSON or YAML values, delimited by quotes,
+// whitespace or punctuation, capturing the prefix, path, key and pinned version (with its "#") of each.
+// Template keys (e.g. "#${.PASSWORD}") are matched as a whole.
+var embeddedSecretRegexp = regexp.MustCompile(`(?:^|[\s"'=,:;({\[])(>>)?vault:([^\s"'#,{}\[\]]+)#(\$\{[^}]*\}|[^\s"'#,{}\[\]]+)(#[^\s"'#,{}\[\]]*)?`)
+
+// embeddedSecretReferences returns all secret references embedded in the value, e.g. in the values of
+// a JSON config `{"user":"vault:secret/data/db#user","password":"vault:secret/data/db#password"}`.
+func embeddedSecretReferences(value string) []string {
+	var references []string
+	for _, match := range embeddedSecretRegexp.FindAllStringSubmatch(value, -1) {
+		references = append(references, match[1]+"vault:"+match[2]+"#"+match[3]+match[4])
+	}
+
+	return references
+}
+
 // implementation based on bank-vaults/secrets-webhook/pkg/provider/vault/provider.go
 func isValidPrefix(value string) bool {
 	return strings.HasPrefix(value, "vault:") || strings.HasPrefix(value, ">>vault:")
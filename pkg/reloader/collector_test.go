@@ -15,9 +15,20 @@
 package reloader
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/bank-vaults/secrets-webhook/pkg/common"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -59,15 +70,103 @@ func TestWorkloadSecretsStore(t *testing.T) {
 		assert.ElementsMatch(t, secretWorkloadsMap["secret/data/docker"], []workload{workload2})
 	})
 
+	t.Run("SetConfig", func(t *testing.T) {
+		store.SetConfig(workload1, workloadConfig{pollPeriod: 10 * time.Second})
+		store.SetConfig(workload2, workloadConfig{pollPeriod: 10 * time.Second})
+		store.SetConfig(workload2, workloadConfig{})
+		assert.Equal(t, map[workload]workloadConfig{workload1: {pollPeriod: 10 * time.Second}}, store.GetConfigs())
+	})
+
+	t.Run("SetSecretKeys", func(t *testing.T) {
+		store.SetSecretKeys(workload1, map[string][]string{"secret/data/mysql": {"password"}})
+		store.SetSecretKeys(workload2, map[string][]string{"secret/data/docker": {"token"}})
+		store.SetSecretKeys(workload2, nil)
+		assert.Equal(t, map[workload]map[string][]string{
+			workload1: {"secret/data/mysql": {"password"}},
+		}, store.GetSecretKeys())
+	})
+
 	t.Run("delete from workloadSecrets map", func(t *testing.T) {
 		// check workload secret deleting
 		store.Delete(workload1)
 		assert.Equal(t, map[workload][]string{
 			workload2: {"secret/data/accounts/aws", "secret/data/docker"},
 		}, store.GetWorkloadSecretsMap())
+		assert.Empty(t, store.GetConfigs())
+		assert.Empty(t, store.GetSecretKeys())
 	})
 }
 
+func TestWorkloadSecretsStoreDelimiterInNames(t *testing.T) {
+	// Workloads are keyed on the workload struct instead of a composite string, so names
+	// that would collide when joined with a delimiter (e.g. "team-a" + "-" + "app") are kept apart
+	store := newWorkloadSecrets()
+	workload1 := workload{name: "app", namespace: "team-a", kind: DeploymentKind}
+	workload2 := workload{name: "a-app", namespace: "team", kind: DeploymentKind}
+	workload3 := workload{name: "a.app", namespace: "team", kind: DeploymentKind}
+
+	store.Store(workload1, []string{"secret/data/one"})
+	store.Store(workload2, []string{"secret/data/two"})
+	store.Store(workload3, []string{"secret/data/three"})
+	store.SetConfig(workload1, workloadConfig{pollPeriod: time.Minute})
+	store.Delete(workload2)
+
+	assert.Equal(t, map[workload][]string{
+		workload1: {"secret/data/one"},
+		workload3: {"secret/data/three"},
+	}, store.GetWorkloadSecretsMap())
+	assert.Equal(t, map[workload]workloadConfig{workload1: {pollPeriod: time.Minute}}, store.GetConfigs())
+}
+
+func TestWorkloadSecretsStoreIndex(t *testing.T) {
+	store := newWorkloadSecrets()
+	// rebuild the inverse of the store the way it was before the index was maintained incrementally
+	rebuild := func() map[string][]workload {
+		secretWorkloads := make(map[string][]workload)
+		for workload, secretPaths := range store.GetWorkloadSecretsMap() {
+			for _, secretPath := range secretPaths {
+				secretWorkloads[secretPath] = append(secretWorkloads[secretPath], workload)
+			}
+		}
+		return secretWorkloads
+	}
+
+	random := rand.New(rand.NewSource(1)) //nolint:gosec
+	workloads := make([]workload, 5)
+	for i := range workloads {
+		workloads[i] = workload{name: fmt.Sprintf("test-%d", i), namespace: "default", kind: DeploymentKind}
+	}
+
+	var previous, previousSnapshot map[string][]workload
+	for i := 0; i < 200; i++ {
+		mutated := workloads[random.Intn(len(workloads))]
+		if random.Intn(4) == 0 {
+			store.Delete(mutated)
+		} else {
+			var secretPaths []string
+			for j := 0; j < random.Intn(4); j++ {
+				secretPaths = append(secretPaths, fmt.Sprintf("secret/data/%d", random.Intn(6)))
+			}
+			store.Store(mutated, secretPaths)
+		}
+
+		expected := rebuild()
+		actual := store.GetSecretWorkloadsMap()
+		require.Len(t, actual, len(expected), "mutation %d", i)
+		for secretPath, workloads := range expected {
+			assert.ElementsMatch(t, workloads, actual[secretPath], "mutation %d: %s", i, secretPath)
+		}
+
+		// maps returned earlier are not affected by later changes
+		assert.Equal(t, previousSnapshot, previous, "mutation %d", i)
+		previous = actual
+		previousSnapshot = make(map[string][]workload, len(actual))
+		for secretPath, workloads := range actual {
+			previousSnapshot[secretPath] = slices.Clone(workloads)
+		}
+	}
+}
+
 func TestCollectSecrets(t *testing.T) {
 	template := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
@@ -128,5 +227,408 @@ func TestCollectSecrets(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, []string{"secret/data/accounts/aws", "secret/data/foo", "secret/data/mysql"}, collectSecrets(template))
+	assert.Equal(t, []string{"secret/data/accounts/aws", "secret/data/foo", "secret/data/mysql"}, newTestController().collectSecrets(template))
+}
+
+func TestCollectSecretsFromDeprecatedAnnotation(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				common.VaultEnvFromPathAnnotationDeprecated: "secret/data/deprecated",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Env: []corev1.EnvVar{{Name: "PASSWORD", Value: "vault:secret/data/env#password"}}},
+			},
+		},
+	}
+
+	t.Run("fallback enabled", func(t *testing.T) {
+		controller := newTestController()
+		assert.Equal(t, []string{"secret/data/deprecated", "secret/data/env"}, controller.collectSecrets(template))
+	})
+
+	t.Run("fallback disabled", func(t *testing.T) {
+		controller := newTestController()
+		WithDeprecatedAnnotationFallback(false)(controller)
+		assert.Equal(t, []string{"secret/data/env"}, controller.collectSecrets(template))
+		assert.Empty(t, controller.collectSecretsFromAnnotations(template.GetAnnotations()))
+
+		// The current annotation is still collected
+		current := template.DeepCopy()
+		current.Annotations[common.VaultFromPathAnnotation] = "secret/data/current"
+		assert.Equal(t, []string{"secret/data/current", "secret/data/env"}, controller.collectSecrets(*current))
+	})
+}
+
+func TestCollectSecretsFromContainerEnvVarsPrefixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+	}{
+		{
+			name:     "vault prefix",
+			value:    "vault:secret/data/app#password",
+			expected: []string{"secret/data/app"},
+		},
+		{
+			name:     "force prefix",
+			value:    ">>vault:secret/data/app#password",
+			expected: []string{"secret/data/app"},
+		},
+		{
+			name:     "force prefix with pinned version",
+			value:    ">>vault:secret/data/app#password#2",
+			expected: []string{},
+		},
+		{
+			name:     "login",
+			value:    "vault:login",
+			expected: []string{},
+		},
+		{
+			name:     "inline",
+			value:    "postgres://${vault:secret/data/db#user}:${vault:secret/data/db-password#password}@db:5432",
+			expected: []string{"secret/data/db", "secret/data/db-password"},
+		},
+		{
+			name:     "inline with force prefix",
+			value:    "Bearer ${>>vault:secret/data/api#token}",
+			expected: []string{"secret/data/api"},
+		},
+		{
+			name:     "inline with pinned version",
+			value:    "Bearer ${vault:secret/data/api#token#3}",
+			expected: []string{},
+		},
+		{
+			name:     "prefix not at the start",
+			value:    "not-vault:secret/data/app#password",
+			expected: []string{},
+		},
+		{
+			name:     "embedded in JSON",
+			value:    `{"db":{"user":"vault:secret/data/db#user","password":">>vault:secret/data/db-password#password"},"api":["vault:secret/data/api#token"]}`,
+			expected: []string{"secret/data/db", "secret/data/db-password", "secret/data/api"},
+		},
+		{
+			name:     "embedded in YAML",
+			value:    "db:\n  user: vault:secret/data/db#user\n  password: 'vault:secret/data/db#${.PASSWORD}'\n",
+			expected: []string{"secret/data/db", "secret/data/db"},
+		},
+		{
+			name:     "embedded with pinned version",
+			value:    `{"user":"vault:secret/data/db#user#2","token":"vault:secret/data/api#token"}`,
+			expected: []string{"secret/data/api"},
+		},
+		{
+			name:     "embedded and inline",
+			value:    `{"url":"postgres://${vault:secret/data/db#user}@db","token":"vault:secret/data/api#token"}`,
+			expected: []string{"secret/data/db", "secret/data/api"},
+		},
+		{
+			name:     "embedded without key",
+			value:    `{"auth":"vault:login","url":"https://vault:8200"}`,
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			secrets := collectSecretsFromContainerEnvVars([]corev1.Container{
+				{Name: "app", Env: []corev1.EnvVar{{Name: "VALUE", Value: ttp.value}}},
+			})
+			assert.Equal(t, ttp.expected, secrets)
+		})
+	}
+}
+
+func TestCollectWorkloadSecretKeys(t *testing.T) {
+	controller := newTestController()
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/env",
+			},
+		},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Env: []corev1.EnvVar{{Name: "MYSQL_PASSWORD", Value: "vault:secret/data/mysql#password"}}},
+			},
+			Containers: []corev1.Container{
+				{
+					Env: []corev1.EnvVar{
+						{Name: "MYSQL_USER", Value: ">>vault:secret/data/mysql#user"},
+						{Name: "MYSQL_URL", Value: "mysql://${vault:secret/data/mysql#user}:${vault:secret/data/mysql#password}@mysql"},
+						{Name: "ENV", Value: "vault:secret/data/env#name"},
+						{Name: "PINNED", Value: "vault:secret/data/pinned#key#2"},
+						{Name: "CONFIG", Value: `{"mysql":{"host":"vault:secret/data/mysql#host","port":3306,"user":"vault:secret/data/mysql#user"}}`},
+					},
+				},
+			},
+		},
+	}
+
+	secretKeys := controller.collectWorkloadSecretKeys(nil, template, nil)
+	// secret/data/env is used as a whole through the annotation
+	assert.Equal(t, map[string][]string{"secret/data/mysql": {"host", "password", "user"}}, secretKeys)
+}
+
+func TestNormalizeSecretPath(t *testing.T) {
+	tests := map[string]string{
+		"secret/data/foo":     "secret/data/foo",
+		"secret/data//foo":    "secret/data/foo",
+		"secret//data///foo":  "secret/data/foo",
+		"secret/data/foo/":    "secret/data/foo",
+		"secret/data/foo//":   "secret/data/foo",
+		"/secret/data/foo":    "secret/data/foo",
+		"//secret/data//foo/": "secret/data/foo",
+		"/":                   "",
+	}
+
+	for path, expected := range tests {
+		assert.Equal(t, expected, normalizeSecretPath(path), path)
+	}
+}
+
+func TestCollectSecretsNormalizesPaths(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo/,secret//data/bar",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "app",
+					Env: []corev1.EnvVar{
+						{Name: "FOO", Value: "vault:secret/data//foo#key"},
+						{Name: "BAR", Value: ">>vault:secret/data/bar/#key"},
+						{Name: "BAZ", Value: "https://${vault:secret/data///baz#host}"},
+						{Name: "ROOT", Value: "vault:/#key"},
+					},
+				},
+			},
+		},
+	}
+
+	// The different forms of the same secret are only collected once
+	assert.Equal(t, []string{"secret/data/bar", "secret/data/baz", "secret/data/foo"}, newTestController().collectSecrets(template))
+	assert.Equal(t, map[string][]string{
+		"secret/data/foo": {"key"},
+		"secret/data/bar": {"key"},
+		"secret/data/baz": {"host"},
+	}, collectSecretKeysFromContainerEnvVars(template.Spec.Containers))
+}
+
+func TestCollectSecretsWithPassthrough(t *testing.T) {
+	// Passthrough only keeps the listed VAULT_* settings of vault-env in the environment of the process,
+	// the secrets injected, and so collected, are the same
+	for _, annotation := range []string{common.VaultPassthroughAnnotation, common.VaultEnvPassthroughAnnotationDeprecated} {
+		t.Run(annotation, func(t *testing.T) {
+			controller := newTestController()
+			testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+			deployment := newTestDeployment("test", map[string]string{
+				SecretReloadAnnotationName: "true",
+				annotation:                 "VAULT_ADDR,VAULT_ROLE,VAULT_NAMESPACE",
+				"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/from-path",
+			})
+			deployment.Spec.Template.Spec.Containers = []corev1.Container{
+				{
+					Name: "app",
+					Env: []corev1.EnvVar{
+						{Name: "VAULT_ADDR", Value: "https://vault.other:8200"},
+						{Name: "VAULT_ROLE", Value: "app"},
+						{Name: "VAULT_NAMESPACE", Value: "team"},
+						{Name: "PASSWORD", Value: "vault:secret/data/app#password"},
+						{Name: "DSN", Value: "postgres://${vault:secret/data/db#user}@db"},
+					},
+				},
+			}
+
+			controller.handleObject(deployment)
+			assert.Equal(t, map[workload][]string{
+				testWorkload: {"secret/data/app", "secret/data/db", "secret/data/from-path"},
+			}, controller.workloadSecrets.GetWorkloadSecretsMap())
+
+			// Changing the passed through settings doesn't change the collected secrets
+			passthroughRemoved := deployment.DeepCopy()
+			delete(passthroughRemoved.Spec.Template.Annotations, annotation)
+			controller.handleObject(passthroughRemoved)
+			assert.Equal(t, map[workload][]string{
+				testWorkload: {"secret/data/app", "secret/data/db", "secret/data/from-path"},
+			}, controller.workloadSecrets.GetWorkloadSecretsMap())
+		})
+	}
+}
+
+func TestCollectWorkloadSecretsAllowedPaths(t *testing.T) {
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/apps/foo,secret/data/infra/root-token",
+	})
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PASSWORD", Value: "vault:secret/data/apps/bar#password"},
+				{Name: "TOKEN", Value: "vault:secret/data/apps-legacy/token#token"},
+			},
+		},
+	}
+	disallowedSecretPaths := func(controller *Controller) float64 {
+		return testutil.ToFloat64(controller.metrics.disallowedSecretPaths.WithLabelValues("default", DeploymentKind))
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		option, err := WithAllowedSecretPaths([]string{"secret/data/apps/.*", "secret/data/infra/root-token"})
+		require.NoError(t, err)
+		controller := newTestController()
+		option(controller)
+
+		controller.handleObject(deployment)
+		assert.Equal(t, map[workload][]string{
+			testWorkload: {"secret/data/apps/bar", "secret/data/apps/foo", "secret/data/infra/root-token"},
+		}, controller.workloadSecrets.GetWorkloadSecretsMap())
+		assert.Equal(t, float64(1), disallowedSecretPaths(controller))
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		// Patterns match the whole path
+		option, err := WithAllowedSecretPaths([]string{"secret/data/apps", "apps/.*"})
+		require.NoError(t, err)
+		controller := newTestController()
+		option(controller)
+
+		controller.handleObject(deployment)
+		assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+		assert.Equal(t, float64(4), disallowedSecretPaths(controller))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := WithAllowedSecretPaths([]string{"secret/data/(apps"})
+		assert.Error(t, err)
+	})
+}
+
+func TestCollectWorkloadSecretsNamespacePathTemplate(t *testing.T) {
+	testWorkload := workload{name: "test", namespace: "team-a", kind: DeploymentKind}
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "shared",
+	})
+	deployment.Namespace = "team-a"
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PASSWORD", Value: "vault:app#password"},
+				{Name: "DSN", Value: "postgres://${vault:db#user}@db"},
+				{Name: "ABSOLUTE", Value: "vault:secret/data/app#password"},
+			},
+		},
+	}
+
+	t.Run("template", func(t *testing.T) {
+		option, err := WithNamespacePathTemplate("secret/data/{namespace}/{path}")
+		require.NoError(t, err)
+		controller := newTestController()
+		option(controller)
+		WithSubkeyAwareReload(true)(controller)
+
+		controller.handleObject(deployment)
+		assert.Equal(t, map[workload][]string{
+			testWorkload: {"secret/data/app", "secret/data/team-a/app", "secret/data/team-a/db", "secret/data/team-a/shared"},
+		}, controller.workloadSecrets.GetWorkloadSecretsMap())
+		assert.Equal(t, map[string][]string{
+			"secret/data/app":        {"password"},
+			"secret/data/team-a/app": {"password"},
+			"secret/data/team-a/db":  {"user"},
+		}, controller.workloadSecrets.GetSecretKeys()[testWorkload])
+	})
+
+	t.Run("no template", func(t *testing.T) {
+		controller := newTestController()
+
+		controller.handleObject(deployment)
+		assert.Equal(t, map[workload][]string{
+			testWorkload: {"app", "db", "secret/data/app", "shared"},
+		}, controller.workloadSecrets.GetWorkloadSecretsMap())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, template := range []string{"secret/data/{namespace}", "secret/data//{path}", "/secret/{path}"} {
+			_, err := WithNamespacePathTemplate(template)
+			assert.Error(t, err, template)
+		}
+	})
+}
+
+func TestGetPollPeriod(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	assert.Equal(t, time.Duration(0), getPollPeriod(map[string]string{}, logger))
+	assert.Equal(t, 30*time.Second, getPollPeriod(map[string]string{PollPeriodAnnotationName: "30s"}, logger))
+	assert.Equal(t, time.Duration(0), getPollPeriod(map[string]string{PollPeriodAnnotationName: "invalid"}, logger))
+	assert.Equal(t, time.Duration(0), getPollPeriod(map[string]string{PollPeriodAnnotationName: "-1m"}, logger))
+
+	// Too short periods are raised to the minimum
+	assert.Equal(t, MinPollPeriod, getPollPeriod(map[string]string{PollPeriodAnnotationName: "1ms"}, logger))
+	assert.Equal(t, MinPollPeriod, getPollPeriod(map[string]string{PollPeriodAnnotationName: "10s"}, logger))
+}
+
+func TestGetReloadThreshold(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	assert.Nil(t, getReloadThreshold(map[string]string{}, logger))
+	assert.Equal(t, &reloadThreshold{count: 2}, getReloadThreshold(map[string]string{ReloadThresholdAnnotationName: "2"}, logger))
+	assert.Equal(t, &reloadThreshold{percent: 50}, getReloadThreshold(map[string]string{ReloadThresholdAnnotationName: "50%"}, logger))
+	assert.Nil(t, getReloadThreshold(map[string]string{ReloadThresholdAnnotationName: "invalid"}, logger))
+}
+
+func TestCollectWorkloadSecretsSources(t *testing.T) {
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:     "true",
+		common.VaultFromPathAnnotation: "secret/data/annotated,secret/data/shared",
+	})
+	deployment.Annotations = map[string]string{common.VaultFromPathAnnotation: "secret/data/workload"}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "app", Env: []corev1.EnvVar{{Name: "SHARED", Value: "vault:secret/data/shared#key"}}},
+	}
+	deployment.Spec.Template.Spec.InitContainers = []corev1.Container{
+		{Name: "init", Env: []corev1.EnvVar{{Name: "INIT", Value: "vault:secret/data/init#key"}}},
+	}
+
+	controller := newTestController()
+	WithWorkloadAnnotations(true)(controller)
+	var logs bytes.Buffer
+	controller.logger = slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	controller.handleObject(deployment)
+	sources := make(map[string][]string)
+	decoder := json.NewDecoder(&logs)
+	for decoder.More() {
+		var record struct {
+			Msg      string   `json:"msg"`
+			Workload string   `json:"workload"`
+			Path     string   `json:"path"`
+			Sources  []string `json:"sources"`
+		}
+		require.NoError(t, decoder.Decode(&record))
+		if record.Msg == "Secret path collected" {
+			assert.Equal(t, "Deployment default/test", record.Workload)
+			sources[record.Path] = record.Sources
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"secret/data/annotated": {collectionSourcePodTemplateAnnotation},
+		"secret/data/init":      {collectionSourceInitContainerEnv},
+		"secret/data/shared":    {collectionSourceContainerEnv, collectionSourcePodTemplateAnnotation},
+		"secret/data/workload":  {collectionSourceWorkloadAnnotation},
+	}, sources)
 }
@@ -0,0 +1,96 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultReloaderConcurrency is the number of secrets checked and workloads reloaded at the same time in a cycle by default
+const DefaultReloaderConcurrency = 10
+
+// WithReloaderConcurrency sets the number of secrets checked, and of workloads reloaded, at the same time in a cycle,
+// defaults to DefaultReloaderConcurrency.
+func WithReloaderConcurrency(concurrency int) (Option, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("invalid reloader concurrency %d, must be at least 1", concurrency)
+	}
+
+	return func(c *Controller) {
+		c.reloaderConcurrency = concurrency
+	}, nil
+}
+
+// WithKindReloadConcurrency limits the number of workloads of a kind reloaded at the same time in a cycle,
+// by kind (e.g. 1 for StatefulSets to reload them one at a time, while Deployments are reloaded at once).
+// Kinds without a limit, or with a limit of 0, are only limited by the reloader concurrency.
+func WithKindReloadConcurrency(limits map[string]int) (Option, error) {
+	kindLimits := make(map[string]int)
+	for kind, limit := range limits {
+		switch kind {
+		case DeploymentKind, DaemonSetKind, StatefulSetKind, CronJobKind, JobKind:
+		default:
+			return nil, fmt.Errorf("invalid workload kind %q", kind)
+		}
+		if limit < 0 {
+			return nil, fmt.Errorf("invalid reload concurrency %d of %s, must not be negative", limit, kind)
+		}
+		if limit > 0 {
+			kindLimits[kind] = limit
+		}
+	}
+
+	return func(c *Controller) {
+		c.kindReloadConcurrency = kindLimits
+	}, nil
+}
+
+// newKindReloadSlots returns a semaphore for each kind of workloads with a reload concurrency limit.
+func (c *Controller) newKindReloadSlots() map[string]chan struct{} {
+	slots := make(map[string]chan struct{}, len(c.kindReloadConcurrency))
+	for kind, limit := range c.kindReloadConcurrency {
+		slots[kind] = make(chan struct{}, limit)
+	}
+
+	return slots
+}
+
+// runWorkerPool calls work with each of the items, from at most size workers at the same time,
+// returning once all of them are done.
+func runWorkerPool[K comparable, V any](size int, items map[K]V, work func(K, V)) {
+	size = min(max(size, 1), len(items))
+	type item struct {
+		key   K
+		value V
+	}
+	queue := make(chan item)
+	var wg sync.WaitGroup
+	for range size {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				work(item.key, item.value)
+			}
+		}()
+	}
+
+	for key, value := range items {
+		queue <- item{key: key, value: value}
+	}
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,179 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+)
+
+func TestReloadWorkloadsKindConcurrency(t *testing.T) {
+	var objects []runtime.Object
+	workloadsToReload := make(map[workload][]secretChange)
+	change := []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 2}}
+	for i := range 4 {
+		name := fmt.Sprintf("test-%d", i)
+		objects = append(objects,
+			newTestDeployment(name, nil),
+			&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}},
+		)
+		workloadsToReload[workload{name: name, namespace: "default", kind: DeploymentKind}] = change
+		workloadsToReload[workload{name: name, namespace: "default", kind: StatefulSetKind}] = change
+	}
+	controller := newTestController(objects...)
+	option, err := WithKindReloadConcurrency(map[string]int{DeploymentKind: 2, StatefulSetKind: 1, DaemonSetKind: 0})
+	require.NoError(t, err)
+	option(controller)
+
+	// The fake clientset serializes its calls, so updates are tracked outside of it
+	kubeClient := &inFlightUpdatesClient{Interface: controller.kubeClient, inFlight: make(map[string]int), maxInFlight: make(map[string]int)}
+	controller.kubeClient = kubeClient
+
+	errs := controller.reloadWorkloads(context.Background(), workloadsToReload, controller.logger)
+	require.Empty(t, errs)
+	assert.Equal(t, map[string]int{DeploymentKind: 2, StatefulSetKind: 1}, kubeClient.maxInFlight)
+}
+
+// inFlightUpdatesClient tracks the most updates of each kind of workloads in flight at the same time
+type inFlightUpdatesClient struct {
+	kubernetes.Interface
+	mu          sync.Mutex
+	inFlight    map[string]int
+	maxInFlight map[string]int
+}
+
+func (c *inFlightUpdatesClient) AppsV1() typedappsv1.AppsV1Interface {
+	return &inFlightUpdatesAppsClient{AppsV1Interface: c.Interface.AppsV1(), client: c}
+}
+
+func (c *inFlightUpdatesClient) update(kind string) {
+	c.mu.Lock()
+	c.inFlight[kind]++
+	c.maxInFlight[kind] = max(c.maxInFlight[kind], c.inFlight[kind])
+	c.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	c.mu.Lock()
+	c.inFlight[kind]--
+	c.mu.Unlock()
+}
+
+type inFlightUpdatesAppsClient struct {
+	typedappsv1.AppsV1Interface
+	client *inFlightUpdatesClient
+}
+
+func (c *inFlightUpdatesAppsClient) Deployments(namespace string) typedappsv1.DeploymentInterface {
+	return &inFlightDeployments{DeploymentInterface: c.AppsV1Interface.Deployments(namespace), client: c.client}
+}
+
+func (c *inFlightUpdatesAppsClient) StatefulSets(namespace string) typedappsv1.StatefulSetInterface {
+	return &inFlightStatefulSets{StatefulSetInterface: c.AppsV1Interface.StatefulSets(namespace), client: c.client}
+}
+
+type inFlightDeployments struct {
+	typedappsv1.DeploymentInterface
+	client *inFlightUpdatesClient
+}
+
+func (d *inFlightDeployments) Update(ctx context.Context, deployment *appsv1.Deployment, opts metav1.UpdateOptions) (*appsv1.Deployment, error) {
+	d.client.update(DeploymentKind)
+	return d.DeploymentInterface.Update(ctx, deployment, opts)
+}
+
+type inFlightStatefulSets struct {
+	typedappsv1.StatefulSetInterface
+	client *inFlightUpdatesClient
+}
+
+func (s *inFlightStatefulSets) Update(ctx context.Context, statefulSet *appsv1.StatefulSet, opts metav1.UpdateOptions) (*appsv1.StatefulSet, error) {
+	s.client.update(StatefulSetKind)
+	return s.StatefulSetInterface.Update(ctx, statefulSet, opts)
+}
+
+func TestWithKindReloadConcurrencyInvalid(t *testing.T) {
+	_, err := WithKindReloadConcurrency(map[string]int{"ReplicaSet": 1})
+	assert.Error(t, err)
+
+	_, err = WithKindReloadConcurrency(map[string]int{StatefulSetKind: -1})
+	assert.Error(t, err)
+}
+
+func TestCheckSecretVersionsReloaderConcurrency(t *testing.T) {
+	controller := newTestController()
+	option, err := WithReloaderConcurrency(3)
+	require.NoError(t, err)
+	option(controller)
+
+	secretWorkloads := make(map[string][]workload)
+	versions := make(map[string]int)
+	for i := range 20 {
+		secretPath := fmt.Sprintf("secret/data/test-%d", i)
+		secretWorkloads[secretPath] = []workload{{name: fmt.Sprintf("test-%d", i), namespace: "default", kind: DeploymentKind}}
+		versions[secretPath] = 1
+	}
+	vaultClient := &inFlightReadsVaultClient{reader: &versionedVaultClientMock{versions: versions}}
+
+	_, errs := controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+	require.Empty(t, errs)
+	assert.Equal(t, 3, vaultClient.maxInFlight)
+	assert.Equal(t, 20, vaultClient.reads)
+	assert.Len(t, controller.secretVersions, 20)
+}
+
+// inFlightReadsVaultClient tracks the most reads of secrets in flight at the same time
+type inFlightReadsVaultClient struct {
+	reader      vaultSecretReader
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	reads       int
+}
+
+func (c *inFlightReadsVaultClient) ReadWithContext(ctx context.Context, path string) (*vaultapi.Secret, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.reads++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight)
+	c.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+
+	return c.reader.ReadWithContext(ctx, path)
+}
+
+func TestWithReloaderConcurrencyInvalid(t *testing.T) {
+	for _, concurrency := range []int{0, -1} {
+		_, err := WithReloaderConcurrency(concurrency)
+		assert.Error(t, err, concurrency)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bank-vaults/secrets-webhook/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	// agentTemplateActionRegexp matches the actions of consul-template templates, e.g. {{ with secret "secret/data/app" }}
+	agentTemplateActionRegexp = regexp.MustCompile(`(?s){{(.*?)}}`)
+	// agentTemplateCommentRegexp matches the comments of consul-template templates, e.g. {{/* secret "secret/data/old" */}}
+	agentTemplateCommentRegexp = regexp.MustCompile(`(?s){{-?\s*/\*.*?\*/\s*-?}}`)
+	// agentTemplateSecretRegexp matches the secret paths read in template actions, e.g. secret "secret/data/app"
+	// or secret `secret/data/app`
+	agentTemplateSecretRegexp = regexp.MustCompile("\\bsecret\\s+(?:\"([^\"]+)\"|`([^`]+)`)")
+	// agentVaultReadRegexp matches the secret paths read with vault.read, e.g. vault.read("secret/data/app")
+	agentVaultReadRegexp = regexp.MustCompile(`\bvault\.read\(\s*(?:"([^"]+)"|'([^']+)')\s*\)`)
+)
+
+// WithVaultAgentConfigMaps enables collecting secrets from the vault-agent ConfigMaps referenced by
+// workloads, re-collecting the secrets of the workloads whenever their ConfigMap changes.
+func WithVaultAgentConfigMaps(configMapInformer coreinformers.ConfigMapInformer) Option {
+	return func(c *Controller) {
+		c.configMapsLister = configMapInformer.Lister()
+		c.configMapsSynced = configMapInformer.Informer().HasSynced
+
+		_, _ = configMapInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.handleConfigMap,
+			UpdateFunc: func(_, newObj interface{}) { c.handleConfigMap(newObj) },
+		})
+	}
+}
+
+// handleConfigMap re-collects the secrets of the workloads referencing the ConfigMap as vault-agent config.
+func (c *Controller) handleConfigMap(obj interface{}) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok || configMap == nil {
+		c.logger.Error(fmt.Sprintf("error decoding ConfigMap, invalid type: %T", obj))
+		return
+	}
+
+	for _, accessor := range c.workloadsUsingAgentConfigMap(configMap.Namespace, configMap.Name) {
+		c.logger.Debug(fmt.Sprintf("vault-agent ConfigMap %s/%s changed, re-collecting secrets of %s %s", configMap.Namespace, configMap.Name, accessor.Kind(), accessor.GetName()))
+		c.processWorkload(accessor)
+	}
+}
+
+// workloadsUsingAgentConfigMap returns the workloads in the namespace referencing the ConfigMap as vault-agent config.
+func (c *Controller) workloadsUsingAgentConfigMap(namespace, name string) []WorkloadAccessor {
+	var objects []interface{}
+
+	deployments, err := c.deploymentsLister.Deployments(namespace).List(labels.Everything())
+	if err != nil {
+		c.logger.Error(fmt.Errorf("failed to list Deployments: %w", err).Error())
+	}
+	for _, deployment := range deployments {
+		objects = append(objects, deployment)
+	}
+
+	daemonSets, err := c.daemonSetsLister.DaemonSets(namespace).List(labels.Everything())
+	if err != nil {
+		c.logger.Error(fmt.Errorf("failed to list DaemonSets: %w", err).Error())
+	}
+	for _, daemonSet := range daemonSets {
+		objects = append(objects, daemonSet)
+	}
+
+	statefulSets, err := c.statefulSetsLister.StatefulSets(namespace).List(labels.Everything())
+	if err != nil {
+		c.logger.Error(fmt.Errorf("failed to list StatefulSets: %w", err).Error())
+	}
+	for _, statefulSet := range statefulSets {
+		objects = append(objects, statefulSet)
+	}
+
+	cronJobs, err := c.cronJobsLister.CronJobs(namespace).List(labels.Everything())
+	if err != nil {
+		c.logger.Error(fmt.Errorf("failed to list CronJobs: %w", err).Error())
+	}
+	for _, cronJob := range cronJobs {
+		objects = append(objects, cronJob)
+	}
+
+	jobs, err := c.jobsLister.Jobs(namespace).List(labels.Everything())
+	if err != nil {
+		c.logger.Error(fmt.Errorf("failed to list Jobs: %w", err).Error())
+	}
+	for _, job := range jobs {
+		objects = append(objects, job)
+	}
+
+	var workloads []WorkloadAccessor
+	for _, object := range objects {
+		accessor, ok := newWorkloadAccessor(object)
+		if ok && agentConfigMapName(accessor.GetPodTemplate().GetAnnotations()) == name {
+			workloads = append(workloads, accessor)
+		}
+	}
+
+	return workloads
+}
+
+// collectSecretsFromAgentConfigMap collects the secrets used in the templates of the vault-agent ConfigMap
+// referenced by the workload, if any. A referenced ConfigMap that is missing or can't be read is an error.
+func (c *Controller) collectSecretsFromAgentConfigMap(namespace string, annotations map[string]string) ([]string, error) {
+	name := agentConfigMapName(annotations)
+	if c.configMapsLister == nil || name == "" {
+		return nil, nil
+	}
+
+	configMap, err := c.configMapsLister.ConfigMaps(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		// Secrets are collected once the ConfigMap is created
+		return nil, fmt.Errorf("vault-agent ConfigMap %s/%s not found", namespace, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault-agent ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	return collectSecretsFromAgentConfig(configMap.Data), nil
+}
+
+func agentConfigMapName(annotations map[string]string) string {
+	if name := annotations[common.VaultAgentConfigmapAnnotation]; name != "" {
+		return name
+	}
+
+	// This is here to preserve backwards compatibility with the deprecated annotation
+	return annotations[common.VaultAgentConfigmapAnnotationDeprecated]
+}
+
+// collectSecretsFromAgentConfig collects the unversioned secret paths used in vault-agent templates.
+func collectSecretsFromAgentConfig(data map[string]string) []string {
+	vaultSecretPaths := []string{}
+	for _, value := range data {
+		for _, secretPath := range parseAgentTemplateSecrets(value) {
+			secretPath, query, _ := strings.Cut(secretPath, "?")
+			// Skip secrets with pinned version
+			if strings.Contains(query, "version=") {
+				continue
+			}
+			vaultSecretPaths = append(vaultSecretPaths, normalizeSecretPath(secretPath))
+		}
+	}
+
+	return vaultSecretPaths
+}
+
+// parseAgentTemplateSecrets returns the secret paths read in the templates of a vault-agent config, either
+// the template files themselves or HCL/JSON configs with inlined templates, in the order they are read.
+// Paths built dynamically, e.g. with printf, can't be collected.
+func parseAgentTemplateSecrets(content string) []string {
+	// Templates inlined in HCL or JSON strings have their quotes escaped
+	content = strings.ReplaceAll(content, `\"`, `"`)
+	content = agentTemplateCommentRegexp.ReplaceAllString(content, "")
+
+	var secretPaths []string
+	for _, action := range agentTemplateActionRegexp.FindAllStringSubmatch(content, -1) {
+		secretPaths = appendRegexpPaths(secretPaths, agentTemplateSecretRegexp, action[1])
+	}
+
+	return appendRegexpPaths(secretPaths, agentVaultReadRegexp, content)
+}
+
+// appendRegexpPaths appends the paths matched by the regexp, captured by either of its groups, to secretPaths.
+func appendRegexpPaths(secretPaths []string, re *regexp.Regexp, content string) []string {
+	for _, match := range re.FindAllStringSubmatch(content, -1) {
+		if match[1] != "" {
+			secretPaths = append(secretPaths, match[1])
+		} else {
+			secretPaths = append(secretPaths, match[2])
+		}
+	}
+
+	return secretPaths
+}
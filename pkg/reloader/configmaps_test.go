@@ -0,0 +1,249 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/bank-vaults/secrets-webhook/pkg/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestCollectSecretsFromAgentConfig(t *testing.T) {
+	secrets := collectSecretsFromAgentConfig(map[string]string{
+		"config.hcl": `
+template {
+  contents = <<EOH
+{{- with secret "secret/data/app" }}{{ .Data.data.password }}{{ end }}
+{{- with secret "secret/data/pinned?version=2" }}{{ .Data.data.password }}{{ end }}
+EOH
+  destination = "/vault/secrets/config"
+}`,
+		"db.tpl": `{{ with secret "database/data/db" }}{{ .Data.data.url }}{{ end }}`,
+		"other":  `no secrets here`,
+	})
+
+	assert.ElementsMatch(t, []string{"secret/data/app", "database/data/db"}, secrets)
+}
+
+func TestParseAgentTemplateSecrets(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "nested blocks",
+			content: `
+{{- with secret "secret/data/db" }}
+{{- range $key, $value := .Data.data }}
+{{- if eq $key "password" }}
+{{- with secret "secret/data/db-admin" }}admin={{ .Data.data.user }}{{ end }}
+{{- end }}
+{{ $key }}={{ $value }}
+{{- end }}
+{{- end }}`,
+			expected: []string{"secret/data/db", "secret/data/db-admin"},
+		},
+		{
+			name: "HCL config with heredoc and inlined templates",
+			content: `
+vault {
+  address = "https://vault:8200"
+}
+template {
+  contents = <<EOH
+{{ with secret "secret/data/app" }}{{ .Data.data.token }}{{ end }}
+EOH
+  destination = "/vault/secrets/app"
+}
+template {
+  contents    = "{{ with secret \"secret/data/api\" }}{{ .Data.data.key }}{{ end }}"
+  destination = "/vault/secrets/api"
+}`,
+			expected: []string{"secret/data/app", "secret/data/api"},
+		},
+		{
+			name:     "JSON config",
+			content:  `{"template": [{"contents": "{{ with secret \"secret/data/json\" }}{{ .Data.data.key }}{{ end }}"}]}`,
+			expected: []string{"secret/data/json"},
+		},
+		{
+			name:     "raw string and assignment",
+			content:  "{{ $db := secret `secret/data/raw` }}{{ $db.Data.data.url }}",
+			expected: []string{"secret/data/raw"},
+		},
+		{
+			name:     "vault.read",
+			content:  `password = vault.read("secret/data/read").data.password` + "\n" + `token = vault.read( 'secret/data/quoted' ).data.token`,
+			expected: []string{"secret/data/read", "secret/data/quoted"},
+		},
+		{
+			name: "comments and text outside of actions",
+			content: `
+{{/* {{ with secret "secret/data/commented" }}{{ end }} */}}
+{{- /* secret "secret/data/trimmed-comment" */ -}}
+The secret "secret/data/text" is not read here.`,
+		},
+		{
+			name:    "dynamic path",
+			content: `{{ with secret (printf "secret/data/%s" (env "APP")) }}{{ end }}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, parseAgentTemplateSecrets(test.content))
+		})
+	}
+}
+
+func TestCollectWorkloadSecretsFromDeprecatedAgentConfigMapAnnotation(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	require.NoError(t, indexer.Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "sidecar-config", Namespace: "default"},
+		Data: map[string]string{
+			"config.hcl": `
+template {
+  contents = "{{ with secret \"secret/data/db\" }}{{ .Data.data.password }}{{ end }}"
+}
+template {
+  contents = "{{ with secret \"secret/data/api\" }}{{ .Data.data.token }}{{ end }}"
+}`,
+		},
+	}))
+
+	controller := newTestController()
+	controller.configMapsLister = corelisters.NewConfigMapLister(indexer)
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				SecretReloadAnnotationName:                     "true",
+				common.VaultAgentConfigmapAnnotationDeprecated: "sidecar-config",
+			},
+		},
+	}
+
+	controller.collectWorkloadSecrets(testWorkload, nil, template)
+
+	assert.Equal(t, map[workload][]string{
+		testWorkload: {"secret/data/api", "secret/data/db"},
+	}, controller.workloadSecrets.GetWorkloadSecretsMap())
+}
+
+func TestCollectWorkloadSecretsPartialFailure(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	controller := newTestController()
+	controller.configMapsLister = corelisters.NewConfigMapLister(indexer)
+	collectionFailures := func(namespace string) float64 {
+		return testutil.ToFloat64(controller.metrics.collectionFailures.WithLabelValues(namespace, DeploymentKind, collectionSourceAgentConfigMap))
+	}
+
+	// The env secrets are collected, even though the referenced vault-agent ConfigMap is missing
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				SecretReloadAnnotationName:           "true",
+				common.VaultAgentConfigmapAnnotation: "agent-config",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "app",
+				Env:  []corev1.EnvVar{{Name: "PASSWORD", Value: "vault:secret/data/db#password"}},
+			}},
+		},
+	}
+	controller.collectWorkloadSecrets(testWorkload, nil, template)
+	assert.Equal(t, []string{"secret/data/db"}, controller.workloadSecrets.GetWorkloadSecretsMap()[testWorkload])
+	assert.Equal(t, float64(1), collectionFailures("default"))
+
+	// A workload whose only source fails keeps its previously collected secrets
+	agentWorkload := workload{name: "agent", namespace: "other", kind: DeploymentKind}
+	agentTemplate := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				SecretReloadAnnotationName:           "true",
+				common.VaultAgentConfigmapAnnotation: "agent-config",
+			},
+		},
+	}
+	controller.workloadSecrets.Store(agentWorkload, []string{"secret/data/api"})
+	controller.collectWorkloadSecrets(agentWorkload, nil, agentTemplate)
+	assert.Equal(t, []string{"secret/data/api"}, controller.workloadSecrets.GetWorkloadSecretsMap()[agentWorkload])
+	assert.Equal(t, float64(1), collectionFailures("other"))
+}
+
+func TestVaultAgentConfigMapChange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:           "true",
+		common.VaultAgentConfigmapAnnotation: "agent-config",
+	})
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent-config", Namespace: "default"},
+		Data: map[string]string{
+			"config.hcl": `template { contents = "{{ with secret \"secret/data/foo\" }}{{ end }}" }`,
+		},
+	}
+	kubeClient := fake.NewSimpleClientset(deployment, configMap)
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+
+	controller := NewController(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		kubeClient,
+		informerFactory.Apps().V1().Deployments(),
+		informerFactory.Apps().V1().DaemonSets(),
+		informerFactory.Apps().V1().StatefulSets(),
+		informerFactory.Batch().V1().CronJobs(),
+		informerFactory.Batch().V1().Jobs(),
+		WithMetricsRegisterer(prometheus.NewRegistry()),
+		WithVaultAgentConfigMaps(informerFactory.Core().V1().ConfigMaps()),
+	)
+	informerFactory.Start(ctx.Done())
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), controller.deploymentsSynced, controller.configMapsSynced))
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"secret/data/foo"}, controller.workloadSecrets.GetWorkloadSecretsMap()[testWorkload])
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A new path in the ConfigMap is collected for the workload
+	configMap.Data["config.hcl"] += `
+template { contents = "{{ with secret \"secret/data/bar\" }}{{ end }}" }`
+	_, err := kubeClient.CoreV1().ConfigMaps("default").Update(ctx, configMap, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"secret/data/bar", "secret/data/foo"}, controller.workloadSecrets.GetWorkloadSecretsMap()[testWorkload])
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -17,36 +17,75 @@ package reloader
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	vaultapi "github.com/hashicorp/vault/api"
-	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
+	"github.com/prometheus/client_golang/prometheus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	appsinformers "k8s.io/client-go/informers/apps/v1"
+	batchinformers "k8s.io/client-go/informers/batch/v1"
 	"k8s.io/client-go/kubernetes"
 	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
 )
 
 const (
 	DeploymentKind  = "Deployment"
 	DaemonSetKind   = "DaemonSet"
 	StatefulSetKind = "StatefulSet"
-
-	SecretReloadAnnotationName = "secrets-reloader.security.bank-vaults.io/reload-on-secret-change"
-	ReloadCountAnnotationName  = "secrets-reloader.security.bank-vaults.io/secret-reload-count"
+	CronJobKind     = "CronJob"
+	JobKind         = "Job"
+
+	SecretReloadAnnotationName        = "secrets-reloader.security.bank-vaults.io/reload-on-secret-change"
+	ReloadCountAnnotationName         = "secrets-reloader.security.bank-vaults.io/secret-reload-count"
+	PollPeriodAnnotationName          = "secrets-reloader.security.bank-vaults.io/poll-period"
+	ReloadThresholdAnnotationName     = "secrets-reloader.security.bank-vaults.io/reload-threshold"
+	LastReloadTimestampAnnotationName = "secrets-reloader.security.bank-vaults.io/last-reload-timestamp"
+	ReloadTriggerPathsAnnotationName  = "secrets-reloader.security.bank-vaults.io/reload-triggered-by"
+	SecretVersionsAnnotationName      = "secrets-reloader.security.bank-vaults.io/secret-versions"
+	// ExcludeAnnotationName opts a workload out of all reloader behavior, regardless of its other annotations
+	ExcludeAnnotationName = "secrets-reloader.security.bank-vaults.io/exclude"
+
+	// ReloadGenerationLabelName is the pod template label the reload count is propagated to, if enabled
+	ReloadGenerationLabelName = "vault-reload-generation"
+
+	// DefaultFieldManager is the field manager the changes made to workloads are attributed to by default
+	DefaultFieldManager = "vault-secrets-reloader"
 )
 
 // Controller is the controller implementation for Foo resources
 type Controller struct {
-	kubeClient  kubernetes.Interface
-	vaultClient *vaultapi.Client
+	kubeClient    kubernetes.Interface
+	vaultClient   *vaultapi.Client
+	vaultClientMu sync.Mutex
+	// vaultLoginRetrying is set while a goroutine waits to retry logging in to Vault, without holding vaultClientMu
+	vaultLoginRetrying bool
+	// vaultConfig is read from the environment once, and not changed afterwards, so it can be read without locking
 	vaultConfig *VaultConfig
 	logger      *slog.Logger
+	metrics     *metrics
+	clock       clock.Clock
+	// vaultAuth is the auth method the default client logged in with, the clients of other roles and Vaults use it too
+	vaultAuth vaultAuth
+	// roleVaultClients map[role]*vaultapi.Client, for the roles set on the ServiceAccounts of workloads
+	roleVaultClients map[string]*vaultapi.Client
+	// targetVaultClients map[vaultTargetClient]*vaultapi.Client, for the Vaults set on the pod templates of workloads
+	targetVaultClients map[vaultTargetClient]*vaultapi.Client
+	// kvMounts are the mounts of the secrets read so far, to follow KV v1 to v2 upgrades
+	kvMounts kvMounts
 
 	deploymentsLister  appslisters.DeploymentLister
 	deploymentsSynced  cache.InformerSynced
@@ -54,10 +93,286 @@ type Controller struct {
 	daemonSetsLister   appslisters.DaemonSetLister
 	statefulSetsLister appslisters.StatefulSetLister
 	statefulSetsSynced cache.InformerSynced
+	cronJobsLister     batchlisters.CronJobLister
+	cronJobsSynced     cache.InformerSynced
+	jobsLister         batchlisters.JobLister
+	jobsSynced         cache.InformerSynced
+	configMapsLister   corelisters.ConfigMapLister
+	configMapsSynced   cache.InformerSynced
+	featureFlagsSynced cache.InformerSynced
+	// runtimeFlags are set from the feature flags ConfigMap, if any
+	runtimeFlags atomic.Pointer[runtimeFlags]
 
 	// workloadSecrets map[Workload][]string
-	workloadSecrets workloadSecretsStore
-	secretVersions  map[string]int
+	workloadSecrets  workloadSecretsStore
+	secretVersions   map[string]int
+	secretVersionsMu sync.Mutex
+	// secretKeyHashes map[secretPath]map[key]hash, only kept with subkey-aware reloading
+	secretKeyHashes map[string]map[string]string
+	// workloadSecretHashes map[Workload]map[secretPath]hash, only kept with workload-hash change detection
+	workloadSecretHashes map[workload]map[string]string
+	// versionBaselines map[Workload]map[secretPath]version, the versions minimum version deltas are counted from
+	versionBaselines map[workload]map[string]int
+	// seededVersions map[Workload]map[secretPath]version, the versions workloads were last reloaded at in one-shot mode
+	seededVersions map[workload]map[string]int
+	// pinnedVersions of the secrets of workloads, set with the pinned versions annotation
+	pinnedVersions *pinnedVersions
+	// deletedSecrets map[secretPath]bool, the secrets whose latest version is deleted, only kept when reloading on deletion
+	deletedSecrets map[string]bool
+
+	metricsRegisterer           prometheus.Registerer
+	metricsPrefix               string
+	vaultEventsEnabled          bool
+	collectWorkloadAnnotations  bool
+	skipDeprecatedAnnotation    bool
+	eventOutput                 *eventOutput
+	eventSink                   EventSink
+	kubeEvents                  *kubeEvents
+	reloadThreshold             reloadThreshold
+	minVersionDelta             int
+	namespaceFilter             namespaceFilter
+	skippedOwners               []metav1.TypeMeta
+	allowedSecretPaths          []*regexp.Regexp
+	namespacePathTemplate       string
+	vaultNamespaceMismatch      string
+	secretAliases               map[string][]string
+	strippedAnnotations         []string
+	reloadViaPodDelete          bool
+	podVersionCheck             bool
+	recreateReloadOptIn         bool
+	scalingSignal               *scalingSignal
+	fieldManager                string
+	cycleHistory                *cycleHistory
+	subkeyAwareReload           bool
+	missingKeyDetection         bool
+	secretPathValidation        bool
+	reloadWindow                reloadWindow
+	reloadGenerationLabel       bool
+	secretReloadAnnotation      string
+	reloadCountAnnotation       string
+	kindSuffixedReloadCount     bool
+	excludeAnnotation           string
+	runPendingReloadsOnShutdown bool
+	serverSideApply             bool
+	shutdownFlushes             []func(ctx context.Context) error
+	dependencies                *workloadDependencies
+	resyncCollectionInterval    time.Duration
+	cacheSyncTimeout            time.Duration
+	settleDelay                 time.Duration
+	resyncQueue                 *resyncQueue
+	changeDetection             string
+	workloadInfoMetrics         bool
+	reloadVerifications         *reloadVerifications
+	reloadCoalescer             *reloadCoalescer
+	reloadLoopBreaker           *reloadLoopBreaker
+	secretVersionsConfigMap     *secretVersionsConfigMap
+	secretVersionsSaveBatching  secretVersionsSaveBatching
+	reloadCap                   *reloadCap
+	reloaderConcurrency         int
+	// kindReloadConcurrency map[kind]limit, of the kinds of workloads whose concurrent reloads are limited
+	kindReloadConcurrency map[string]int
+	// pendingReloads map[Workload][]secretChange, reloads deferred until the reload window, or until they are allowed
+	pendingReloads   map[workload][]secretChange
+	pendingReloadsMu sync.Mutex
+}
+
+// Option configures optional behavior of the Controller.
+type Option func(*Controller)
+
+// WithMetricsRegisterer sets the Prometheus registerer the Controller's metrics are registered with,
+// defaults to prometheus.DefaultRegisterer.
+func WithMetricsRegisterer(registerer prometheus.Registerer) Option {
+	return func(c *Controller) {
+		c.metricsRegisterer = registerer
+	}
+}
+
+// WithWorkloadAnnotations enables collecting secrets from the annotations of the workload
+// itself, in addition to the annotations of its pod template.
+func WithWorkloadAnnotations(enabled bool) Option {
+	return func(c *Controller) {
+		c.collectWorkloadAnnotations = enabled
+	}
+}
+
+// WithDeprecatedAnnotationFallback sets whether secrets are collected from the deprecated vault-env-from-path
+// annotation of workloads that don't have the vault-from-path annotation set, enabled by default.
+func WithDeprecatedAnnotationFallback(enabled bool) Option {
+	return func(c *Controller) {
+		c.skipDeprecatedAnnotation = !enabled
+	}
+}
+
+// WithEventOutput enables writing reload decisions as JSON events to w, one event per line.
+func WithEventOutput(w io.Writer) Option {
+	return func(c *Controller) {
+		c.eventOutput = newEventOutput(w)
+	}
+}
+
+// WithReloadThreshold sets the amount of a workload's secrets that need to change within a cycle
+// to reload it, either as a number (e.g. "2") or as a percentage (e.g. "50%"), defaults to "1".
+func WithReloadThreshold(threshold string) (Option, error) {
+	t, err := parseReloadThreshold(threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *Controller) {
+		c.reloadThreshold = t
+	}, nil
+}
+
+// WithSkippedOwners makes the controller ignore workloads that are managed by one of the given
+// controllers, identified by the apiVersion and kind of their owner, e.g. "example.com/v1/Operator".
+func WithSkippedOwners(owners []string) (Option, error) {
+	skippedOwners := make([]metav1.TypeMeta, 0, len(owners))
+	for _, owner := range owners {
+		i := strings.LastIndex(owner, "/")
+		if i <= 0 || i == len(owner)-1 {
+			return nil, fmt.Errorf("invalid owner %q, must be in apiVersion/kind format", owner)
+		}
+		skippedOwners = append(skippedOwners, metav1.TypeMeta{APIVersion: owner[:i], Kind: owner[i+1:]})
+	}
+
+	return func(c *Controller) {
+		c.skippedOwners = skippedOwners
+	}, nil
+}
+
+// WithAllowedSecretPaths restricts the secrets read by the controller to the ones whose path fully matches
+// one of the given regular expressions, e.g. "secret/data/apps/.*". The collected paths of workloads that
+// don't match any of them are dropped. No expressions, the default, allows every path.
+func WithAllowedSecretPaths(patterns []string) (Option, error) {
+	allowedSecretPaths := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed secret path %q: %w", pattern, err)
+		}
+		allowedSecretPaths = append(allowedSecretPaths, re)
+	}
+
+	return func(c *Controller) {
+		c.allowedSecretPaths = allowedSecretPaths
+	}, nil
+}
+
+// WithStrippedAnnotations sets pod template annotations that are removed from workloads
+// when they are reloaded, e.g. ones that make GitOps tools conflict with the reloader.
+func WithStrippedAnnotations(names []string) Option {
+	return func(c *Controller) {
+		c.strippedAnnotations = names
+	}
+}
+
+// WithReloadViaPodDelete enables evicting the pods of workloads when they are reloaded,
+// for workloads whose pods are not replaced when their pod template changes (e.g. OnDelete update strategy).
+func WithReloadViaPodDelete(enabled bool) Option {
+	return func(c *Controller) {
+		c.reloadViaPodDelete = enabled
+	}
+}
+
+// WithReloadGenerationLabel enables setting the ReloadGenerationLabelName label on the pod template
+// of reloaded workloads to their reload count, so the reloaded pods can be selected.
+func WithReloadGenerationLabel(enabled bool) Option {
+	return func(c *Controller) {
+		c.reloadGenerationLabel = enabled
+	}
+}
+
+// WithWorkloadInfoMetrics enables the reloader_workload_info metric, with a series for every secret of
+// every tracked workload, updated every reloader cycle. Mind its cardinality in large clusters.
+func WithWorkloadInfoMetrics(enabled bool) Option {
+	return func(c *Controller) {
+		c.workloadInfoMetrics = enabled
+	}
+}
+
+// WithDependentReloads enables reloading the workloads that depend on a reloaded workload, transitively,
+// declared with the DependsOnAnnotationName annotation on their pod template.
+func WithDependentReloads(enabled bool) Option {
+	return func(c *Controller) {
+		if enabled {
+			c.dependencies = newWorkloadDependencies()
+		}
+	}
+}
+
+// WithScalingSignal defers reloads while the given ConfigMap, in namespace/name format, has the given
+// annotation (defaults to ScalingInProgressAnnotationName) set to "true", e.g. during cluster autoscaling.
+func WithScalingSignal(configMap, annotation string) (Option, error) {
+	signal, err := parseScalingSignal(configMap, annotation)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *Controller) {
+		c.scalingSignal = signal
+	}, nil
+}
+
+// WithReloadWindow confines reloads to the given time ranges of the day, in the local time of the reloader,
+// as comma separated ranges in HH:MM-HH:MM format (e.g. "22:00-06:00"). Changes are still detected outside
+// of the window, and the affected workloads are reloaded once it opens.
+func WithReloadWindow(window string) (Option, error) {
+	w, err := parseReloadWindow(window)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *Controller) {
+		c.reloadWindow = w
+	}, nil
+}
+
+// WithFieldManager sets the field manager the changes made to workloads are attributed to,
+// defaults to DefaultFieldManager.
+func WithFieldManager(fieldManager string) Option {
+	return func(c *Controller) {
+		c.fieldManager = fieldManager
+	}
+}
+
+// WithSubkeyAwareReload makes workloads that reference specific keys of a secret (e.g. "vault:secret/data/app#key")
+// only reload when the value of one of those keys changed, instead of on every new version of the secret.
+func WithSubkeyAwareReload(enabled bool) Option {
+	return func(c *Controller) {
+		c.subkeyAwareReload = enabled
+	}
+}
+
+// WithCycleHistorySize sets the amount of recent reloader cycle summaries kept for debugging,
+// defaults to DefaultCycleHistorySize, 0 disables keeping them.
+func WithCycleHistorySize(size int) Option {
+	return func(c *Controller) {
+		c.cycleHistory = newCycleHistory(size)
+	}
+}
+
+// WithCacheSyncTimeout sets how long the Controller waits for the informer caches to sync on startup before
+// giving up with an error, e.g. when it isn't allowed to list the watched resources, 0 waits indefinitely.
+func WithCacheSyncTimeout(timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.cacheSyncTimeout = timeout
+	}
+}
+
+// WithShutdownFlush adds a function that is called when the Controller shuts down,
+// e.g. to push the final metric values to a Prometheus push gateway.
+func WithShutdownFlush(flush func(ctx context.Context) error) Option {
+	return func(c *Controller) {
+		c.shutdownFlushes = append(c.shutdownFlushes, flush)
+	}
+}
+
+// WithVaultEvents enables reloading workloads based on KV secret events received
+// from Vault's event notification system, in addition to periodic polling.
+func WithVaultEvents(enabled bool) Option {
+	return func(c *Controller) {
+		c.vaultEventsEnabled = enabled
+	}
 }
 
 // NewController returns a new sample controller
@@ -67,38 +382,84 @@ func NewController(
 	deploymentInformer appsinformers.DeploymentInformer,
 	daemonSetInformer appsinformers.DaemonSetInformer,
 	statefulSetInformer appsinformers.StatefulSetInformer,
+	cronJobInformer batchinformers.CronJobInformer,
+	jobInformer batchinformers.JobInformer,
+	opts ...Option,
 ) *Controller {
 	controller := &Controller{
-		kubeClient:         kubeClient,
-		logger:             logger,
-		deploymentsLister:  deploymentInformer.Lister(),
-		deploymentsSynced:  deploymentInformer.Informer().HasSynced,
-		daemonSetsLister:   daemonSetInformer.Lister(),
-		daemonSetsSynced:   daemonSetInformer.Informer().HasSynced,
-		statefulSetsLister: statefulSetInformer.Lister(),
-		statefulSetsSynced: deploymentInformer.Informer().HasSynced,
-		workloadSecrets:    newWorkloadSecrets(),
-		secretVersions:     make(map[string]int),
+		kubeClient:             kubeClient,
+		vaultConfig:            getVaultConfigFromEnv(),
+		logger:                 logger,
+		deploymentsLister:      deploymentInformer.Lister(),
+		deploymentsSynced:      deploymentInformer.Informer().HasSynced,
+		daemonSetsLister:       daemonSetInformer.Lister(),
+		daemonSetsSynced:       daemonSetInformer.Informer().HasSynced,
+		statefulSetsLister:     statefulSetInformer.Lister(),
+		statefulSetsSynced:     statefulSetInformer.Informer().HasSynced,
+		cronJobsLister:         cronJobInformer.Lister(),
+		cronJobsSynced:         cronJobInformer.Informer().HasSynced,
+		jobsLister:             jobInformer.Lister(),
+		jobsSynced:             jobInformer.Informer().HasSynced,
+		workloadSecrets:        newWorkloadSecrets(),
+		secretVersions:         make(map[string]int),
+		secretKeyHashes:        make(map[string]map[string]string),
+		workloadSecretHashes:   make(map[workload]map[string]string),
+		versionBaselines:       make(map[workload]map[string]int),
+		changeDetection:        ChangeDetectionVersion,
+		pendingReloads:         make(map[workload][]secretChange),
+		pinnedVersions:         newPinnedVersions(),
+		metricsRegisterer:      prometheus.DefaultRegisterer,
+		metricsPrefix:          DefaultMetricsPrefix,
+		reloadThreshold:        defaultReloadThreshold,
+		clock:                  clock.RealClock{},
+		fieldManager:           DefaultFieldManager,
+		cycleHistory:           newCycleHistory(DefaultCycleHistorySize),
+		reloaderConcurrency:    DefaultReloaderConcurrency,
+		eventSink:              NoopEventSink{},
+		secretReloadAnnotation: SecretReloadAnnotationName,
+		reloadCountAnnotation:  ReloadCountAnnotationName,
+		excludeAnnotation:      ExcludeAnnotationName,
+	}
+
+	for _, opt := range opts {
+		opt(controller)
+	}
+
+	controller.metrics = newMetrics(controller.metricsRegisterer, controller.metricsPrefix)
+	if controller.workloadInfoMetrics {
+		controller.metrics.registerWorkloadInfo(controller.metricsRegisterer)
 	}
 
 	logger.Info("Setting up event handlers")
 
-	// Set up event handlers for Deployments, DaemonSets and StatefulSets
+	// Set up event handlers for Deployments, DaemonSets, StatefulSets, CronJobs and Jobs
 	_, _ = deploymentInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    controller.handleObject,
-		UpdateFunc: func(_, newObj interface{}) { controller.handleObject(newObj) },
+		UpdateFunc: controller.handleUpdate,
 		DeleteFunc: controller.handleObjectDelete,
 	})
 
 	_, _ = daemonSetInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    controller.handleObject,
-		UpdateFunc: func(_, newObj interface{}) { controller.handleObject(newObj) },
+		UpdateFunc: controller.handleUpdate,
 		DeleteFunc: controller.handleObjectDelete,
 	})
 
 	_, _ = statefulSetInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    controller.handleObject,
-		UpdateFunc: func(_, newObj interface{}) { controller.handleObject(newObj) },
+		UpdateFunc: controller.handleUpdate,
+		DeleteFunc: controller.handleObjectDelete,
+	})
+
+	_, _ = cronJobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.handleObject,
+		UpdateFunc: controller.handleUpdate,
+		DeleteFunc: controller.handleObjectDelete,
+	})
+
+	_, _ = jobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.handleObject,
+		UpdateFunc: controller.handleUpdate,
 		DeleteFunc: controller.handleObjectDelete,
 	})
 
@@ -115,17 +476,89 @@ func (c *Controller) Run(ctx context.Context, reloaderPeriod time.Duration) erro
 	c.logger.Info("Starting vault-secrets-reloader controller")
 
 	// Wait for the caches to be synced before starting reloader
-	c.logger.Info("Waiting for informer caches to sync")
+	if err := c.waitForCacheSync(ctx); err != nil {
+		return err
+	}
 
-	if !cache.WaitForCacheSync(ctx.Done(), c.deploymentsSynced, c.daemonSetsSynced, c.statefulSetsSynced) {
-		return fmt.Errorf("failed to wait for caches to sync")
+	// Seed the secret versions with the ones persisted before the restart, so changes made meanwhile are detected
+	if err := c.loadSecretVersions(ctx); err != nil {
+		c.logger.Warn(err.Error())
 	}
 
-	// Launch reloader to reload resources with changed secrets
-	go wait.UntilWithContext(ctx, c.runReloader, reloaderPeriod)
+	// Launch reporting the secret paths collected on startup that are misconfigured
+	if c.secretPathValidation {
+		go c.runSecretPathValidation(ctx)
+	}
+
+	// Launch reloader to reload resources with changed secrets, secrets are checked
+	// with the poll period of the workloads using them, or reloaderPeriod by default
+	go c.runReloaderScheduler(ctx, reloaderPeriod)
+
+	// Launch reloading the workloads whose reload was deferred once the reload window opens
+	if c.reloadWindow != nil {
+		go c.runReloadWindow(ctx)
+	}
+
+	// Launch reloading the workloads whose pinned versions changed
+	go c.runPinnedVersionReloader(ctx)
+
+	// Launch re-collecting the workloads queued on informer resyncs
+	if c.resyncQueue != nil {
+		go c.runResyncCollector(ctx)
+	}
+
+	// Launch event watcher to reload resources as soon as Vault reports a secret change,
+	// periodic reloading keeps working as a fallback if the event stream is unavailable
+	if c.vaultEventsEnabled {
+		go wait.UntilWithContext(ctx, c.runEventWatcher, eventWatcherRetryPeriod)
+	}
 
 	<-ctx.Done()
 	c.logger.Info("Shutting down reloader")
+	c.waitForScheduledReloads()
+	c.flush()
+
+	return nil
+}
+
+// waitForCacheSync waits for the caches of the informers to be synced.
+func (c *Controller) waitForCacheSync(ctx context.Context) error {
+	c.logger.Info("Waiting for informer caches to sync")
+
+	cacheSyncs := map[string]cache.InformerSynced{
+		"Deployments":  c.deploymentsSynced,
+		"DaemonSets":   c.daemonSetsSynced,
+		"StatefulSets": c.statefulSetsSynced,
+		"CronJobs":     c.cronJobsSynced,
+		"Jobs":         c.jobsSynced,
+	}
+	if c.configMapsSynced != nil {
+		cacheSyncs["ConfigMaps"] = c.configMapsSynced
+	}
+	if c.featureFlagsSynced != nil {
+		cacheSyncs["feature flags ConfigMap"] = c.featureFlagsSynced
+	}
+
+	syncCtx := ctx
+	if c.cacheSyncTimeout > 0 {
+		var cancel context.CancelFunc
+		syncCtx, cancel = context.WithTimeout(ctx, c.cacheSyncTimeout)
+		defer cancel()
+	}
+	if !cache.WaitForCacheSync(syncCtx.Done(), slices.Collect(maps.Values(cacheSyncs))...) {
+		if ctx.Err() == nil && syncCtx.Err() != nil {
+			var unsynced []string
+			for name, synced := range cacheSyncs {
+				if !synced() {
+					unsynced = append(unsynced, name)
+				}
+			}
+			slices.Sort(unsynced)
+			return fmt.Errorf("informer caches of %s did not sync within %s, check that the reloader is allowed to list and watch them",
+				strings.Join(unsynced, ", "), c.cacheSyncTimeout)
+		}
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
 
 	return nil
 }
@@ -135,78 +568,136 @@ func (c *Controller) Run(ctx context.Context, reloaderPeriod time.Duration) erro
 // shared store if it is a workload and has the reload annotation set.
 func (c *Controller) handleObject(obj interface{}) {
 	// Get required params from supported workloads
-	var workloadData workload
-	var podTemplateSpec corev1.PodTemplateSpec
-	switch o := obj.(type) {
-	case *appsv1.Deployment:
-		workloadData = workload{name: o.Name, namespace: o.Namespace, kind: DeploymentKind}
-		podTemplateSpec = o.Spec.Template
-
-	case *appsv1.DaemonSet:
-		workloadData = workload{name: o.Name, namespace: o.Namespace, kind: DaemonSetKind}
-		podTemplateSpec = o.Spec.Template
-
-	case *appsv1.StatefulSet:
-		workloadData = workload{name: o.Name, namespace: o.Namespace, kind: StatefulSetKind}
-		podTemplateSpec = o.Spec.Template
-
-	default:
-		// Unsupported workload
-		c.logger.Error("error decoding object, invalid type")
+	accessor, deleted, ok := c.workloadAccessorFromEvent(obj)
+	if !ok {
+		return
+	}
+	if deleted {
+		// The object is gone, don't keep collecting secrets for it
+		c.deleteWorkload(accessor)
 		return
 	}
 
-	// Process workload, skip if reload annotation not present
-	if podTemplateSpec.GetAnnotations()[SecretReloadAnnotationName] != "true" {
+	c.processWorkload(accessor)
+}
+
+// processWorkload collects the secrets of the workload if it has the reload annotation set.
+func (c *Controller) processWorkload(accessor WorkloadAccessor) {
+	workloadData := workloadFromAccessor(accessor)
+	podTemplateSpec := accessor.GetPodTemplate()
+
+	// Workloads outside of the watched namespaces are never stored
+	if !c.namespaceFilter.allows(workloadData.namespace) {
+		return
+	}
+
+	// Excluded workloads are never tracked, regardless of the annotations enabling reloading
+	if c.excluded(accessor) {
+		c.logger.Debug(fmt.Sprintf("Skipping excluded workload %#v", workloadData))
+		c.forgetWorkload(workloadData)
+		return
+	}
+
+	// Process workload, skip if reload annotation not present. The annotation may have been removed
+	// from a collected workload, so it's removed from the store, to stop checking its secrets.
+	if podTemplateSpec.GetAnnotations()[c.secretReloadAnnotation] != "true" {
+		c.forgetWorkload(workloadData)
 		return
 	}
+
+	// The pod template of Jobs can't be changed once they started, so they can't be reloaded
+	if job, ok := accessor.(*jobAccessor); ok && !job.podTemplateMutable() {
+		c.logger.Debug(fmt.Sprintf("Skipping started Job %#v", workloadData))
+		c.forgetWorkload(workloadData)
+		return
+	}
+
+	// Skip workloads managed by controllers that don't tolerate changes made by us
+	if owner, ok := c.skippedOwner(accessor); ok {
+		c.logger.Debug(fmt.Sprintf("Skipping workload %#v managed by %s %s", workloadData, owner.APIVersion, owner.Kind))
+		c.forgetWorkload(workloadData)
+		return
+	}
+
 	c.logger.Debug(fmt.Sprintf("Processing workload: %#v", workloadData))
-	c.collectWorkloadSecrets(workloadData, podTemplateSpec)
+	c.collectWorkloadSecrets(workloadData, accessor.GetAnnotations(), *podTemplateSpec)
+	if c.dependencies != nil {
+		// Workloads without secrets of their own can still be reloaded with the workloads they depend on
+		c.dependencies.Set(workloadData, parseDependsOn(podTemplateSpec.GetAnnotations()[DependsOnAnnotationName]))
+	}
 }
 
 // handleObjectDelete will take any resource implementing metav1.Object and deletes
 // it from the shared store if it is a workload and has the reload annotation set.
 func (c *Controller) handleObjectDelete(obj interface{}) {
-	var object metav1.Object
-	var ok bool
-	if object, ok = obj.(metav1.Object); !ok {
-		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
-		if !ok {
-			c.logger.Error("error decoding object, invalid type")
-			return
-		}
-		object, ok = tombstone.Obj.(metav1.Object)
-		if !ok {
-			c.logger.Error("error decoding object tombstone, invalid type")
-			return
-		}
-		c.logger.Debug(fmt.Sprintf("Recovered deleted object: %s", object.GetName()))
+	accessor, _, ok := c.workloadAccessorFromEvent(obj)
+	if !ok {
+		return
 	}
 
-	var workloadData workload
-	var podTemplateSpec corev1.PodTemplateSpec
-	switch o := object.(type) {
-	case *appsv1.Deployment:
-		workloadData = workload{name: o.GetName(), namespace: o.GetNamespace(), kind: DeploymentKind}
-		podTemplateSpec = o.Spec.Template
+	c.deleteWorkload(accessor)
+}
 
-	case *appsv1.DaemonSet:
-		workloadData = workload{name: o.GetName(), namespace: o.GetNamespace(), kind: DaemonSetKind}
-		podTemplateSpec = o.Spec.Template
+// workloadAccessorFromEvent returns an accessor for the workload of an informer event, unwrapping
+// the tombstones of deleted objects, in which case deleted is true. Invalid objects are logged.
+func (c *Controller) workloadAccessorFromEvent(obj interface{}) (accessor WorkloadAccessor, deleted bool, ok bool) {
+	if tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown); isTombstone {
+		obj = tombstone.Obj
+		deleted = true
+	}
 
-	case *appsv1.StatefulSet:
-		workloadData = workload{name: o.GetName(), namespace: o.GetNamespace(), kind: StatefulSetKind}
-		podTemplateSpec = o.Spec.Template
+	accessor, ok = newWorkloadAccessor(obj)
+	if !ok {
+		if deleted {
+			c.logger.Error(fmt.Sprintf("error decoding object tombstone, invalid type: %T", obj))
+		} else {
+			c.logger.Error(fmt.Sprintf("error decoding object, invalid type: %T", obj))
+		}
+		return nil, false, false
+	}
+	if deleted {
+		c.logger.Debug(fmt.Sprintf("Recovered deleted object: %s", accessor.GetName()))
+	}
+
+	return accessor, deleted, true
+}
 
-	default:
-		c.logger.Error("error decoding object, invalid type")
+// deleteWorkload deletes the workload from the shared store if it has the reload annotation set.
+func (c *Controller) deleteWorkload(accessor WorkloadAccessor) {
+	workloadData := workloadFromAccessor(accessor)
+	if !c.namespaceFilter.allows(workloadData.namespace) {
 		return
 	}
 
 	// Delete workload, skip if reload annotation not present
-	if podTemplateSpec.GetAnnotations()[SecretReloadAnnotationName] != "true" {
+	if accessor.GetPodTemplate().GetAnnotations()[c.secretReloadAnnotation] != "true" {
 		return
 	}
 	c.logger.Debug(fmt.Sprintf("Deleting workload from store: %#v", workloadData))
+	c.forgetWorkload(workloadData)
+}
+
+// forgetWorkload removes the workload from the store, if it was collected.
+func (c *Controller) forgetWorkload(workloadData workload) {
 	c.workloadSecrets.Delete(workloadData)
+	c.pinnedVersions.delete(workloadData)
+	if c.reloadLoopBreaker != nil {
+		c.reloadLoopBreaker.forget(workloadData)
+	}
+	if c.dependencies != nil {
+		c.dependencies.Delete(workloadData)
+	}
+}
+
+// skippedOwner returns the owner of the object that makes it skipped, if any.
+func (c *Controller) skippedOwner(object metav1.Object) (metav1.OwnerReference, bool) {
+	for _, owner := range object.GetOwnerReferences() {
+		for _, skippedOwner := range c.skippedOwners {
+			if owner.APIVersion == skippedOwner.APIVersion && owner.Kind == skippedOwner.Kind {
+				return owner, true
+			}
+		}
+	}
+
+	return metav1.OwnerReference{}, false
 }
@@ -0,0 +1,370 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+)
+
+func newTestController(objects ...runtime.Object) *Controller {
+	return &Controller{
+		kubeClient:             fake.NewSimpleClientset(objects...),
+		vaultConfig:            &VaultConfig{},
+		logger:                 slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:                newMetrics(prometheus.NewRegistry(), DefaultMetricsPrefix),
+		workloadSecrets:        newWorkloadSecrets(),
+		secretVersions:         make(map[string]int),
+		secretKeyHashes:        make(map[string]map[string]string),
+		workloadSecretHashes:   make(map[workload]map[string]string),
+		versionBaselines:       make(map[workload]map[string]int),
+		changeDetection:        ChangeDetectionVersion,
+		pendingReloads:         make(map[workload][]secretChange),
+		pinnedVersions:         newPinnedVersions(),
+		reloadThreshold:        defaultReloadThreshold,
+		clock:                  clock.RealClock{},
+		fieldManager:           DefaultFieldManager,
+		cycleHistory:           newCycleHistory(DefaultCycleHistorySize),
+		reloaderConcurrency:    DefaultReloaderConcurrency,
+		eventSink:              NoopEventSink{},
+		secretReloadAnnotation: SecretReloadAnnotationName,
+		reloadCountAnnotation:  ReloadCountAnnotationName,
+		excludeAnnotation:      ExcludeAnnotationName,
+	}
+}
+
+func newTestDeployment(name string, annotations map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: annotations,
+				},
+			},
+		},
+	}
+}
+
+func TestHandleObjectWorkloadAnnotations(t *testing.T) {
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/template",
+	})
+	deployment.Annotations = map[string]string{
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/workload,secret/data/template",
+	}
+	workloadData := workload{name: "test", namespace: "default", kind: DeploymentKind}
+
+	t.Run("disabled", func(t *testing.T) {
+		controller := newTestController()
+		controller.handleObject(deployment)
+
+		assert.Equal(t, []string{"secret/data/template"}, controller.workloadSecrets.GetWorkloadSecretsMap()[workloadData])
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		controller := newTestController()
+		controller.collectWorkloadAnnotations = true
+		controller.handleObject(deployment)
+
+		assert.Equal(t, []string{"secret/data/template", "secret/data/workload"}, controller.workloadSecrets.GetWorkloadSecretsMap()[workloadData])
+	})
+}
+
+func TestHandleObjectCustomReloadAnnotation(t *testing.T) {
+	controller := newTestController()
+	option, err := WithSecretReloadAnnotation("example.com/reload")
+	require.NoError(t, err)
+	option(controller)
+
+	for name, annotation := range map[string]string{"default": SecretReloadAnnotationName, "custom": "example.com/reload"} {
+		controller.handleObject(newTestDeployment(name, map[string]string{
+			annotation: "true",
+			"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/app",
+		}))
+	}
+
+	// The default annotation doesn't enable reloading anymore
+	assert.Equal(t, map[workload][]string{
+		{name: "custom", namespace: "default", kind: DeploymentKind}: {"secret/data/app"},
+	}, controller.workloadSecrets.GetWorkloadSecretsMap())
+}
+
+func TestHandleObjectAllSecretsPinned(t *testing.T) {
+	controller := newTestController()
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+	})
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "app", Env: []corev1.EnvVar{{Name: "PASSWORD", Value: "vault:secret/data/bar#password"}}},
+	}
+
+	controller.handleObject(deployment)
+	controller.workloadSecrets.SetConfig(testWorkload, workloadConfig{pollPeriod: time.Minute})
+	assert.Equal(t, map[workload][]string{testWorkload: {"secret/data/bar", "secret/data/foo"}}, controller.workloadSecrets.GetWorkloadSecretsMap())
+
+	// Pin the versions of all secrets
+	pinned := deployment.DeepCopy()
+	pinned.Spec.Template.Annotations["secrets-webhook.security.bank-vaults.io/vault-from-path"] = "secret/data/foo#2"
+	pinned.Spec.Template.Spec.Containers[0].Env[0].Value = "vault:secret/data/bar#password#3"
+	controller.handleObject(pinned)
+
+	assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+	assert.Empty(t, controller.workloadSecrets.GetSecretWorkloadsMap())
+	assert.Empty(t, controller.workloadSecrets.GetConfigs())
+}
+
+func TestHandleObjectReloadAnnotationRemoved(t *testing.T) {
+	controller := newTestController()
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+	})
+
+	controller.handleUpdate(deployment, deployment)
+	assert.Equal(t, map[workload][]string{testWorkload: {"secret/data/foo"}}, controller.workloadSecrets.GetWorkloadSecretsMap())
+
+	// Opting out stops checking the secrets of the workload
+	optedOut := deployment.DeepCopy()
+	delete(optedOut.Spec.Template.Annotations, SecretReloadAnnotationName)
+	controller.handleUpdate(deployment, optedOut)
+	assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+	assert.Empty(t, controller.workloadSecrets.GetSecretWorkloadsMap())
+
+	// Setting the annotation to anything but "true" opts out as well
+	controller.handleUpdate(optedOut, deployment)
+	disabled := deployment.DeepCopy()
+	disabled.Spec.Template.Annotations[SecretReloadAnnotationName] = "false"
+	controller.handleUpdate(deployment, disabled)
+	assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+
+	// Opting in again collects the workload again
+	controller.handleUpdate(disabled, deployment)
+	assert.Equal(t, map[workload][]string{testWorkload: {"secret/data/foo"}}, controller.workloadSecrets.GetWorkloadSecretsMap())
+}
+
+func TestHandleObjectExcluded(t *testing.T) {
+	controller := newTestController()
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+	})
+	controller.handleObject(deployment)
+	assert.Equal(t, map[workload][]string{testWorkload: {"secret/data/foo"}}, controller.workloadSecrets.GetWorkloadSecretsMap())
+
+	// Excluding a tracked workload removes it, even though reloading is enabled
+	excluded := deployment.DeepCopy()
+	excluded.Spec.Template.Annotations[ExcludeAnnotationName] = "true"
+	controller.handleUpdate(deployment, excluded)
+	assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+	assert.Empty(t, controller.workloadSecrets.GetSecretWorkloadsMap())
+
+	// The annotation in the metadata of the workload itself excludes it as well
+	workloadExcluded := deployment.DeepCopy()
+	workloadExcluded.Annotations = map[string]string{ExcludeAnnotationName: "true"}
+	controller.handleObject(workloadExcluded)
+	assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+
+	// Anything but "true" doesn't exclude the workload
+	notExcluded := deployment.DeepCopy()
+	notExcluded.Spec.Template.Annotations[ExcludeAnnotationName] = "false"
+	controller.handleObject(notExcluded)
+	assert.Equal(t, map[workload][]string{testWorkload: {"secret/data/foo"}}, controller.workloadSecrets.GetWorkloadSecretsMap())
+}
+
+func TestHandleObjectBatchWorkloads(t *testing.T) {
+	controller := newTestController()
+	podTemplate := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{SecretReloadAnnotationName: "true"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Env:  []corev1.EnvVar{{Name: "PASSWORD", Value: "vault:secret/data/batch#password"}},
+		}}},
+	}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "cron", Namespace: "default"},
+		Spec:       batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: podTemplate}}},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "default"},
+		Spec:       batchv1.JobSpec{Template: podTemplate, Suspend: ptr.To(true)},
+	}
+	controller.handleObject(cronJob)
+	controller.handleObject(job)
+	assert.Equal(t, map[workload][]string{
+		{name: "cron", namespace: "default", kind: CronJobKind}: {"secret/data/batch"},
+		{name: "job", namespace: "default", kind: JobKind}:      {"secret/data/batch"},
+	}, controller.workloadSecrets.GetWorkloadSecretsMap())
+
+	// The pod template of a started Job can't be changed anymore, so it's not tracked
+	started := job.DeepCopy()
+	started.Spec.Suspend = ptr.To(false)
+	started.Status.StartTime = &metav1.Time{Time: time.Now()}
+	controller.handleUpdate(job, started)
+	assert.Equal(t, map[workload][]string{
+		{name: "cron", namespace: "default", kind: CronJobKind}: {"secret/data/batch"},
+	}, controller.workloadSecrets.GetWorkloadSecretsMap())
+}
+
+func TestHandleObjectSkippedOwners(t *testing.T) {
+	option, err := WithSkippedOwners([]string{"example.com/v1alpha1/Operator", "apps/v1/ReplicaSet"})
+	require.NoError(t, err)
+
+	controller := newTestController()
+	option(controller)
+
+	owned := newTestDeployment("owned", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+	})
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "example.com/v1alpha1", Kind: "Operator", Name: "operator"}}
+
+	notOwned := newTestDeployment("not-owned", owned.Spec.Template.Annotations)
+	// Same kind in another API group should not be skipped
+	otherGroup := newTestDeployment("other-group", owned.Spec.Template.Annotations)
+	otherGroup.OwnerReferences = []metav1.OwnerReference{{APIVersion: "other.com/v1", Kind: "Operator", Name: "operator"}}
+
+	// Workload stored before getting an owner should be removed
+	controller.workloadSecrets.Store(workload{name: "owned", namespace: "default", kind: DeploymentKind}, []string{"secret/data/foo"})
+
+	controller.handleObject(owned)
+	controller.handleObject(notOwned)
+	controller.handleObject(otherGroup)
+
+	assert.Equal(t, map[workload][]string{
+		{name: "not-owned", namespace: "default", kind: DeploymentKind}:   {"secret/data/foo"},
+		{name: "other-group", namespace: "default", kind: DeploymentKind}: {"secret/data/foo"},
+	}, controller.workloadSecrets.GetWorkloadSecretsMap())
+
+	t.Run("invalid owner", func(t *testing.T) {
+		_, err := WithSkippedOwners([]string{"Operator"})
+		assert.Error(t, err)
+	})
+}
+
+func TestHandleObjectUnexpectedTypes(t *testing.T) {
+	annotations := map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+	}
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+
+	unexpected := []interface{}{
+		nil,
+		"garbage",
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+		(*appsv1.Deployment)(nil),
+		cache.DeletedFinalStateUnknown{Key: "default/test", Obj: "garbage"},
+		cache.DeletedFinalStateUnknown{Key: "default/test", Obj: nil},
+	}
+
+	for _, handler := range []string{"handleObject", "handleObjectDelete"} {
+		t.Run(handler, func(t *testing.T) {
+			controller := newTestController()
+			controller.handleObject(newTestDeployment("test", annotations))
+
+			handle := controller.handleObject
+			if handler == "handleObjectDelete" {
+				handle = controller.handleObjectDelete
+			}
+			for _, obj := range unexpected {
+				assert.NotPanics(t, func() { handle(obj) }, "%#v", obj)
+			}
+
+			// The stored workload is left alone
+			assert.Equal(t, map[workload][]string{testWorkload: {"secret/data/foo"}}, controller.workloadSecrets.GetWorkloadSecretsMap())
+		})
+	}
+
+	t.Run("tombstone in update", func(t *testing.T) {
+		controller := newTestController()
+		controller.handleObject(newTestDeployment("test", annotations))
+
+		controller.handleObject(cache.DeletedFinalStateUnknown{Key: "default/test", Obj: newTestDeployment("test", annotations)})
+
+		assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+	})
+
+	t.Run("tombstone in delete", func(t *testing.T) {
+		controller := newTestController()
+		controller.handleObject(newTestDeployment("test", annotations))
+
+		controller.handleObjectDelete(cache.DeletedFinalStateUnknown{Key: "default/test", Obj: newTestDeployment("test", annotations)})
+
+		assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+	})
+}
+
+func TestRunCacheSyncTimeout(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	// Listing DaemonSets is forbidden, so their informer never syncs
+	kubeClient.PrependReactor("list", "daemonsets", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(appsv1.Resource("daemonsets"), "", assert.AnError)
+	})
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	controller := NewController(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		kubeClient,
+		informerFactory.Apps().V1().Deployments(),
+		informerFactory.Apps().V1().DaemonSets(),
+		informerFactory.Apps().V1().StatefulSets(),
+		informerFactory.Batch().V1().CronJobs(),
+		informerFactory.Batch().V1().Jobs(),
+		WithMetricsRegisterer(prometheus.NewRegistry()),
+		WithCacheSyncTimeout(200*time.Millisecond),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	informerFactory.Start(ctx.Done())
+	done := make(chan error)
+	go func() {
+		done <- controller.Run(ctx, time.Hour)
+	}()
+
+	select {
+	case err := <-done:
+		require.EqualError(t, err, "informer caches of DaemonSets did not sync within 200ms, check that the reloader is allowed to list and watch them")
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not give up waiting for the caches to sync")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DependsOnAnnotationName is the pod template annotation listing the workloads in the same namespace a workload
+// depends on, as comma separated names (matching any kind) or kind/name pairs, e.g. "Deployment/api,cache".
+const DependsOnAnnotationName = "secrets-reloader.security.bank-vaults.io/depends-on"
+
+// workloadReference references a workload in the namespace of the dependent workload,
+// an empty kind matches workloads of any kind
+type workloadReference struct {
+	kind string
+	name string
+}
+
+// parseDependsOn parses the value of the depends-on annotation.
+func parseDependsOn(value string) []workloadReference {
+	var references []workloadReference
+	for _, reference := range strings.Split(value, ",") {
+		reference = strings.TrimSpace(reference)
+		if reference == "" {
+			continue
+		}
+
+		kind, name, ok := strings.Cut(reference, "/")
+		if !ok {
+			kind, name = "", reference
+		}
+		references = append(references, workloadReference{kind: kind, name: name})
+	}
+
+	return references
+}
+
+// workloadDependencies keeps track of the workloads each workload depends on
+type workloadDependencies struct {
+	sync.RWMutex
+	dependencies map[workload][]workloadReference
+}
+
+func newWorkloadDependencies() *workloadDependencies {
+	return &workloadDependencies{
+		dependencies: make(map[workload][]workloadReference),
+	}
+}
+
+// Set stores the workloads the workload depends on, no dependencies remove the workload.
+func (d *workloadDependencies) Set(workload workload, dependencies []workloadReference) {
+	d.Lock()
+	defer d.Unlock()
+	if len(dependencies) == 0 {
+		delete(d.dependencies, workload)
+		return
+	}
+	d.dependencies[workload] = dependencies
+}
+
+func (d *workloadDependencies) Delete(workload workload) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.dependencies, workload)
+}
+
+// Dependents returns the workloads that directly depend on the workload.
+func (d *workloadDependencies) Dependents(dependency workload) []workload {
+	d.RLock()
+	defer d.RUnlock()
+
+	var dependents []workload
+	for dependent, references := range d.dependencies {
+		if dependent.namespace != dependency.namespace {
+			continue
+		}
+		for _, reference := range references {
+			if reference.name == dependency.name && (reference.kind == "" || reference.kind == dependency.kind) {
+				dependents = append(dependents, dependent)
+				break
+			}
+		}
+	}
+	slices.SortFunc(dependents, compareWorkloads)
+
+	return dependents
+}
+
+func compareWorkloads(a, b workload) int {
+	if c := strings.Compare(a.namespace, b.namespace); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.kind, b.kind); c != 0 {
+		return c
+	}
+	return strings.Compare(a.name, b.name)
+}
+
+// addDependentWorkloads adds the workloads that depend on the workloads to reload, transitively,
+// with the changes of the workloads they depend on. Every workload is only added once, so
+// dependency cycles end when they get back to a workload that is already reloaded.
+func (c *Controller) addDependentWorkloads(workloadsToReload map[workload][]secretChange, logger *slog.Logger) {
+	if c.dependencies == nil {
+		return
+	}
+
+	queue := make([]workload, 0, len(workloadsToReload))
+	for workload := range workloadsToReload {
+		queue = append(queue, workload)
+	}
+	// Traverse in a stable order, so dependents reachable from multiple workloads get the same changes every time
+	slices.SortFunc(queue, compareWorkloads)
+
+	for len(queue) > 0 {
+		dependency := queue[0]
+		queue = queue[1:]
+
+		for _, dependent := range c.dependencies.Dependents(dependency) {
+			if _, ok := workloadsToReload[dependent]; ok {
+				continue
+			}
+
+			logger.Info(fmt.Sprintf("Reloading workload %s as it depends on %s", dependent, dependency))
+			workloadsToReload[dependent] = workloadsToReload[dependency]
+			queue = append(queue, dependent)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseDependsOn(t *testing.T) {
+	assert.Empty(t, parseDependsOn(""))
+	assert.Empty(t, parseDependsOn(" , "))
+	assert.Equal(t, []workloadReference{
+		{kind: DeploymentKind, name: "api"},
+		{name: "cache"},
+	}, parseDependsOn("Deployment/api, cache,"))
+}
+
+func TestReloadDependentWorkloads(t *testing.T) {
+	controller := newTestController(
+		newTestDeployment("a", map[string]string{SecretReloadAnnotationName: "true"}),
+		newTestDeployment("b", map[string]string{SecretReloadAnnotationName: "true", DependsOnAnnotationName: "a"}),
+		newTestDeployment("c", map[string]string{SecretReloadAnnotationName: "true", DependsOnAnnotationName: "Deployment/b"}),
+		// Only depends on a StatefulSet with the same name
+		newTestDeployment("d", map[string]string{SecretReloadAnnotationName: "true", DependsOnAnnotationName: "StatefulSet/a"}),
+	)
+	controller.dependencies = newWorkloadDependencies()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(t, err)
+		controller.handleObject(deployment)
+	}
+
+	workloadA := workload{name: "a", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.Store(workloadA, []string{"secret/data/foo"})
+	controller.secretVersions["secret/data/foo"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 2}}
+	reloadCount := func(name string) string {
+		deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(t, err)
+		return deployment.Spec.Template.Annotations[ReloadCountAnnotationName]
+	}
+	reloadChangedWorkloads := func() CycleSummary {
+		return controller.reloadChangedWorkloads(
+			context.Background(),
+			vaultClient,
+			controller.workloadSecrets.GetSecretWorkloadsMap(),
+			controller.logger,
+		)
+	}
+
+	t.Run("chain", func(t *testing.T) {
+		summary := reloadChangedWorkloads()
+		assert.Equal(t, 3, summary.WorkloadsReloaded)
+		assert.Equal(t, "1", reloadCount("a"))
+		assert.Equal(t, "1", reloadCount("b"))
+		assert.Equal(t, "1", reloadCount("c"))
+		assert.Empty(t, reloadCount("d"))
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		controller.dependencies.Set(workloadA, parseDependsOn("c"))
+
+		// Every workload in the cycle is reloaded once
+		vaultClient.setVersion("secret/data/foo", 3)
+		summary := reloadChangedWorkloads()
+		assert.Equal(t, 3, summary.WorkloadsReloaded)
+		assert.Equal(t, "2", reloadCount("a"))
+		assert.Equal(t, "2", reloadCount("b"))
+		assert.Equal(t, "2", reloadCount("c"))
+	})
+
+	t.Run("deleted dependent", func(t *testing.T) {
+		deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "b", metav1.GetOptions{})
+		require.NoError(t, err)
+		controller.handleObjectDelete(deployment)
+
+		vaultClient.setVersion("secret/data/foo", 4)
+		summary := reloadChangedWorkloads()
+		assert.Equal(t, 1, summary.WorkloadsReloaded)
+		assert.Equal(t, "3", reloadCount("a"))
+		assert.Equal(t, "2", reloadCount("c"))
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	ReloadEventType = "reload"
+
+	ReloadActionReloaded = "reloaded"
+	ReloadActionFailed   = "failed"
+)
+
+// ReloadEvent is a machine-readable record of a reload decision,
+// its schema is meant to be stable for external consumers.
+type ReloadEvent struct {
+	Type      string              `json:"type"`
+	Timestamp time.Time           `json:"timestamp"`
+	Workload  ReloadEventWorkload `json:"workload"`
+	Paths     []string            `json:"paths"`
+	Versions  map[string]Versions `json:"versions"`
+	Action    string              `json:"action"`
+	Error     string              `json:"error,omitempty"`
+	// CorrelationID identifies the reload cycle the decision was made in
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+type ReloadEventWorkload struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// Versions are the stored and current versions of a changed secret
+type Versions struct {
+	Old int `json:"old"`
+	New int `json:"new"`
+}
+
+// eventOutput writes reload events as JSON lines
+type eventOutput struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newEventOutput(w io.Writer) *eventOutput {
+	return &eventOutput{encoder: json.NewEncoder(w)}
+}
+
+func (o *eventOutput) write(event ReloadEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.encoder.Encode(event)
+}
+
+func newReloadEvent(ctx context.Context, workload workload, changes []secretChange, reloadErr error, now time.Time) ReloadEvent {
+	event := ReloadEvent{
+		Type:      ReloadEventType,
+		Timestamp: now.UTC(),
+		Workload: ReloadEventWorkload{
+			Kind:      workload.kind,
+			Namespace: workload.namespace,
+			Name:      workload.name,
+		},
+		Paths:    make([]string, 0, len(changes)),
+		Versions: make(map[string]Versions, len(changes)),
+		Action:   ReloadActionReloaded,
+
+		CorrelationID: correlationIDFromContext(ctx),
+	}
+
+	for _, change := range changes {
+		event.Paths = append(event.Paths, change.path)
+		event.Versions[change.path] = Versions{Old: change.oldVersion, New: change.newVersion}
+	}
+
+	if reloadErr != nil {
+		event.Action = ReloadActionFailed
+		event.Error = reloadErr.Error()
+	}
+
+	return event
+}
+
+// emitReloadEvent writes the reload decision to the event output, if configured, and publishes it to the event sink.
+func (c *Controller) emitReloadEvent(ctx context.Context, workload workload, changes []secretChange, reloadErr error) {
+	event := newReloadEvent(ctx, workload, changes, reloadErr, c.clock.Now())
+	if c.eventOutput != nil {
+		err := c.eventOutput.write(event)
+		if err != nil {
+			c.logger.Error(fmt.Errorf("failed to write reload event: %w", err).Error())
+		}
+	}
+
+	err := c.eventSink.Publish(ctx, event)
+	if err != nil {
+		c.logger.Error(fmt.Errorf("failed to publish reload event of %s %s/%s: %w", workload.kind, workload.namespace, workload.name, err).Error())
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadEventOutput(t *testing.T) {
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	var output bytes.Buffer
+	controller.eventOutput = newEventOutput(&output)
+
+	changes := []secretChange{
+		{path: "secret/data/bar", oldVersion: 1, newVersion: 2},
+		{path: "secret/data/foo", oldVersion: 3, newVersion: 5},
+	}
+
+	t.Run("reloaded", func(t *testing.T) {
+		output.Reset()
+		controller.reloadWorkloads(WithCorrelationID(context.Background(), "request-1"), map[workload][]secretChange{
+			{name: "test", namespace: "default", kind: DeploymentKind}: changes,
+		}, controller.logger)
+
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal(output.Bytes(), &event))
+
+		timestamp, err := time.Parse(time.RFC3339Nano, event["timestamp"].(string))
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), timestamp, time.Minute)
+		delete(event, "timestamp")
+
+		assert.Equal(t, map[string]interface{}{
+			"type": "reload",
+			"workload": map[string]interface{}{
+				"kind":      "Deployment",
+				"namespace": "default",
+				"name":      "test",
+			},
+			"paths": []interface{}{"secret/data/bar", "secret/data/foo"},
+			"versions": map[string]interface{}{
+				"secret/data/bar": map[string]interface{}{"old": float64(1), "new": float64(2)},
+				"secret/data/foo": map[string]interface{}{"old": float64(3), "new": float64(5)},
+			},
+			"action":         "reloaded",
+			"correlation_id": "request-1",
+		}, event)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		output.Reset()
+		controller.reloadWorkloads(context.Background(), map[workload][]secretChange{
+			{name: "missing", namespace: "default", kind: DeploymentKind}: changes,
+		}, controller.logger)
+
+		var event ReloadEvent
+		require.NoError(t, json.Unmarshal(output.Bytes(), &event))
+		assert.Equal(t, ReloadActionFailed, event.Action)
+		assert.Equal(t, `deployments.apps "missing" not found`, event.Error)
+	})
+}
@@ -0,0 +1,211 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+const (
+	// vaultKVEventType matches events of both KV v1 and KV v2 secret engines
+	vaultKVEventType = "kv*"
+
+	eventWatcherRetryPeriod = 10 * time.Second
+)
+
+// secretEvent is a notification about a change of a secret in Vault
+type secretEvent struct {
+	Path string
+}
+
+// secretEventSource streams secret change notifications until the context is
+// cancelled or the underlying connection is lost, in which case the channel is closed.
+type secretEventSource interface {
+	Subscribe(ctx context.Context) (<-chan secretEvent, error)
+}
+
+// vaultEventSource subscribes to Vault's event notification system (Vault 1.16+)
+// through its websocket API.
+type vaultEventSource struct {
+	client    *vaultapi.Client
+	eventType string
+}
+
+func newVaultEventSource(client *vaultapi.Client) secretEventSource {
+	return &vaultEventSource{
+		client:    client,
+		eventType: vaultKVEventType,
+	}
+}
+
+// vaultEvent is the CloudEvents formatted message sent by Vault, only containing the fields we need
+type vaultEvent struct {
+	Data struct {
+		Event struct {
+			Metadata struct {
+				Path     string `json:"path"`
+				DataPath string `json:"data_path"`
+			} `json:"metadata"`
+		} `json:"event"`
+	} `json:"data"`
+}
+
+func (s *vaultEventSource) Subscribe(ctx context.Context) (<-chan secretEvent, error) {
+	subscribeURL, err := url.Parse(s.client.Address())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Vault address: %w", err)
+	}
+
+	switch subscribeURL.Scheme {
+	case "https":
+		subscribeURL.Scheme = "wss"
+	default:
+		subscribeURL.Scheme = "ws"
+	}
+	subscribeURL.Path = "/v1/sys/events/subscribe/" + s.eventType
+	subscribeURL.RawQuery = url.Values{"json": []string{"true"}}.Encode()
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	if transport, ok := s.client.CloneConfig().HttpClient.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Vault-Token", s.client.Token())
+	if namespace := s.client.Namespace(); namespace != "" {
+		headers.Set("X-Vault-Namespace", namespace)
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, subscribeURL.String(), headers)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to Vault events: %w", err)
+	}
+
+	events := make(chan secretEvent)
+	go func() {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		defer close(events)
+
+		// Unblock reading when the context is cancelled
+		go func() {
+			<-ctx.Done()
+			_ = conn.Close()
+		}()
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var event vaultEvent
+			if err := json.Unmarshal(message, &event); err != nil {
+				continue
+			}
+
+			// KV v2 events carry the path used for reading secret data separately
+			path := event.Data.Event.Metadata.DataPath
+			if path == "" {
+				path = event.Data.Event.Metadata.Path
+			}
+			if path == "" {
+				continue
+			}
+
+			select {
+			case events <- secretEvent{Path: strings.TrimPrefix(path, "/")}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// runEventWatcher subscribes to Vault secret events and reloads the affected workloads.
+// It returns when the event stream is lost, so it can be restarted, while periodic
+// reloading keeps detecting changes in the meantime.
+func (c *Controller) runEventWatcher(ctx context.Context) {
+	watcherLogger := c.logger.With(slog.String("worker", "event-watcher"))
+
+	vaultClient, err := c.getVaultClient(ctx)
+	if err != nil {
+		watcherLogger.Error(fmt.Errorf("failed to initialize Vault client: %w", err).Error())
+		return
+	}
+
+	watcherLogger.Info("Watching Vault secret events")
+	err = c.watchSecretEvents(ctx, newVaultEventSource(vaultClient), vaultClient.Logical(), watcherLogger)
+	if err != nil {
+		watcherLogger.Warn(fmt.Errorf("watching Vault secret events stopped, falling back to periodic reloading: %w", err).Error())
+	}
+}
+
+func (c *Controller) watchSecretEvents(ctx context.Context, source secretEventSource, vaultClient vaultSecretReader, logger *slog.Logger) error {
+	events, err := source.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return errors.New("event stream closed")
+			}
+
+			// Workloads may use the secret through one of its aliases
+			secretWorkloads := c.aliasedSecretWorkloads(event.Path)
+			if len(secretWorkloads) == 0 {
+				logger.Debug(fmt.Sprintf("Secret %s is not used by any workload, skipping event", event.Path))
+				continue
+			}
+
+			logger.Debug(fmt.Sprintf("Received event for secret: %s", event.Path))
+			if c.reloadsPaused(ctx, logger) {
+				// The change is picked up by periodic reloading once resumed or scaling is finished
+				continue
+			}
+			workloadsToReload, _ := c.checkSecretVersions(ctx, vaultClient, secretWorkloads, logger)
+			c.deferOutsideReloadWindow(workloadsToReload, logger)
+			c.reloadWorkloads(ctx, workloadsToReload, logger)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeEventSource struct {
+	events chan secretEvent
+	err    error
+}
+
+func (s *fakeEventSource) Subscribe(_ context.Context) (<-chan secretEvent, error) {
+	return s.events, s.err
+}
+
+type versionedVaultClientMock struct {
+	sync.Mutex
+	versions map[string]int
+	errs     map[string]error
+	// data of KV secrets, returned along with their version
+	data map[string]map[string]interface{}
+}
+
+func (c *versionedVaultClientMock) ReadWithContext(_ context.Context, path string) (*vaultapi.Secret, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.errs[path]; err != nil {
+		return nil, err
+	}
+
+	version, ok := c.versions[path]
+	if !ok {
+		return nil, nil
+	}
+
+	secret := &vaultapi.Secret{
+		Data: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"version": json.Number(strconv.Itoa(version)),
+			},
+		},
+	}
+	if data, ok := c.data[path]; ok {
+		secret.Data["data"] = data
+	}
+
+	return secret, nil
+}
+
+func (c *versionedVaultClientMock) setVersion(path string, version int) {
+	c.Lock()
+	defer c.Unlock()
+	c.versions[path] = version
+}
+
+func (c *versionedVaultClientMock) setData(path string, version int, data map[string]interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	c.versions[path] = version
+	c.data[path] = data
+}
+
+func TestWatchSecretEvents(t *testing.T) {
+	deployment := newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"})
+	controller := newTestController(deployment)
+	controller.workloadSecrets.Store(
+		workload{name: "test", namespace: "default", kind: DeploymentKind},
+		[]string{"secret/data/foo"},
+	)
+
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 1}}
+	controller.secretVersions["secret/data/foo"] = 1
+
+	t.Run("subscription error", func(t *testing.T) {
+		source := &fakeEventSource{err: assert.AnError}
+
+		err := controller.watchSecretEvents(context.Background(), source, vaultClient, controller.logger)
+		assert.Equal(t, assert.AnError, err)
+	})
+
+	t.Run("event triggers reload", func(t *testing.T) {
+		source := &fakeEventSource{events: make(chan secretEvent)}
+		done := make(chan error)
+		go func() {
+			done <- controller.watchSecretEvents(context.Background(), source, vaultClient, controller.logger)
+		}()
+
+		// event for an untracked secret should be ignored
+		source.events <- secretEvent{Path: "secret/data/bar"}
+
+		vaultClient.setVersion("secret/data/foo", 2)
+		source.events <- secretEvent{Path: "secret/data/foo"}
+
+		// closing the stream should stop the watcher so that it can be restarted
+		close(source.events)
+		require.Error(t, <-done)
+
+		updated, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "1", updated.Spec.Template.Annotations[ReloadCountAnnotationName])
+		assert.Equal(t, 2, controller.secretVersions["secret/data/foo"])
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultEventSinkTimeout is the default timeout of publishing a reload event to an HTTP event sink.
+const DefaultEventSinkTimeout = 5 * time.Second
+
+// EventSink publishes the reload events of the reloader to an external system, e.g. a message queue.
+// Publish is called once for every reload, concurrently for the workloads reloaded in the same cycle.
+type EventSink interface {
+	Publish(ctx context.Context, event ReloadEvent) error
+}
+
+// NoopEventSink discards reload events, it's the default EventSink.
+type NoopEventSink struct{}
+
+func (NoopEventSink) Publish(context.Context, ReloadEvent) error {
+	return nil
+}
+
+// HTTPEventSink publishes reload events as JSON to an HTTP endpoint with POST requests,
+// e.g. to a webhook receiver or the HTTP bridge of a NATS or Kafka cluster.
+type HTTPEventSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPEventSink returns an HTTPEventSink publishing to the http(s) URL, with the timeout per event.
+func NewHTTPEventSink(sinkURL string, timeout time.Duration) (*HTTPEventSink, error) {
+	parsed, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("event sink URL %q must be an absolute http or https URL", sinkURL)
+	}
+
+	return &HTTPEventSink{url: sinkURL, client: &http.Client{Timeout: timeout}}, nil
+}
+
+func (s *HTTPEventSink) Publish(ctx context.Context, event ReloadEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("event sink responded with %s", response.Status)
+	}
+
+	return nil
+}
+
+// WithEventSink sets the sink reload events are published to, besides the event output.
+func WithEventSink(sink EventSink) Option {
+	return func(c *Controller) {
+		c.eventSink = sink
+	}
+}
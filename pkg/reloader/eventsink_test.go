@@ -0,0 +1,105 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEventSink captures the published reload events
+type fakeEventSink struct {
+	mu     sync.Mutex
+	events []ReloadEvent
+}
+
+func (s *fakeEventSink) Publish(_ context.Context, event ReloadEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+
+	return nil
+}
+
+func TestReloadEventSink(t *testing.T) {
+	controller := newTestController(
+		newTestDeployment("one", map[string]string{SecretReloadAnnotationName: "true"}),
+		newTestDeployment("two", map[string]string{SecretReloadAnnotationName: "true"}),
+	)
+	sink := &fakeEventSink{}
+	WithEventSink(sink)(controller)
+
+	changes := []secretChange{{path: "secret/data/app", oldVersion: 1, newVersion: 2}}
+	controller.reloadWorkloads(WithCorrelationID(context.Background(), "request-1"), map[workload][]secretChange{
+		{name: "one", namespace: "default", kind: DeploymentKind}:     changes,
+		{name: "two", namespace: "default", kind: DeploymentKind}:     changes,
+		{name: "missing", namespace: "default", kind: DeploymentKind}: changes,
+	}, controller.logger)
+
+	require.Len(t, sink.events, 3)
+	slices.SortFunc(sink.events, func(a, b ReloadEvent) int { return strings.Compare(a.Workload.Name, b.Workload.Name) })
+	assert.Equal(t, "missing", sink.events[0].Workload.Name)
+	assert.Equal(t, ReloadActionFailed, sink.events[0].Action)
+	for _, event := range sink.events[1:] {
+		assert.Equal(t, ReloadActionReloaded, event.Action)
+		assert.Equal(t, []string{"secret/data/app"}, event.Paths)
+		assert.Equal(t, map[string]Versions{"secret/data/app": {Old: 1, New: 2}}, event.Versions)
+		assert.Equal(t, "request-1", event.CorrelationID)
+	}
+}
+
+func TestHTTPEventSink(t *testing.T) {
+	var received []ReloadEvent
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var event ReloadEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received = append(received, event)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	sink, err := NewHTTPEventSink(server.URL+"/events", time.Second)
+	require.NoError(t, err)
+	event := ReloadEvent{
+		Type:     ReloadEventType,
+		Workload: ReloadEventWorkload{Kind: DeploymentKind, Namespace: "default", Name: "test"},
+		Paths:    []string{"secret/data/app"},
+		Action:   ReloadActionReloaded,
+	}
+
+	require.NoError(t, sink.Publish(context.Background(), event))
+	assert.Equal(t, []ReloadEvent{event}, received)
+
+	status = http.StatusServiceUnavailable
+	assert.EqualError(t, sink.Publish(context.Background(), event), "event sink responded with 503 Service Unavailable")
+
+	for _, invalid := range []string{"", "events", "ftp://example.com/events", "http://"} {
+		_, err := NewHTTPEventSink(invalid, time.Second)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Keys of the feature flags ConfigMap
+const (
+	// DryRunFeatureFlag set to "true" makes the reloader only log the workloads it would reload
+	DryRunFeatureFlag = "dry-run"
+	// PauseFeatureFlag set to "true" stops checking secrets for changes until it's unset
+	PauseFeatureFlag = "pause"
+	// ReadonlyNamespacesFeatureFlag is a comma separated list of namespaces whose workloads are not reloaded
+	ReadonlyNamespacesFeatureFlag = "readonly-namespaces"
+)
+
+// runtimeFlags are the behaviors of the controller that can be changed while it's running,
+// replaced as a whole whenever the feature flags ConfigMap changes.
+type runtimeFlags struct {
+	dryRun             bool
+	paused             bool
+	readonlyNamespaces []string
+}
+
+// WithFeatureFlags sets the ConfigMap, in namespace/name format, whose data sets the feature flags of the
+// controller that can be flipped without restarting it: DryRunFeatureFlag, PauseFeatureFlag and
+// ReadonlyNamespacesFeatureFlag. The informer only needs to watch the given ConfigMap.
+// The flags are unset while the ConfigMap doesn't exist.
+func WithFeatureFlags(configMapInformer coreinformers.ConfigMapInformer, configMap string) (Option, error) {
+	namespace, name, ok := strings.Cut(configMap, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid ConfigMap %q, must be in namespace/name format", configMap)
+	}
+
+	return func(c *Controller) {
+		c.featureFlagsSynced = configMapInformer.Informer().HasSynced
+
+		isFeatureFlags := func(obj interface{}) (*corev1.ConfigMap, bool) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			configMap, ok := obj.(*corev1.ConfigMap)
+			return configMap, ok && configMap != nil && configMap.Namespace == namespace && configMap.Name == name
+		}
+		_, _ = configMapInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				if configMap, ok := isFeatureFlags(obj); ok {
+					c.setRuntimeFlags(configMap.Data)
+				}
+			},
+			UpdateFunc: func(_, newObj interface{}) {
+				if configMap, ok := isFeatureFlags(newObj); ok {
+					c.setRuntimeFlags(configMap.Data)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if _, ok := isFeatureFlags(obj); ok {
+					c.setRuntimeFlags(nil)
+				}
+			},
+		})
+	}, nil
+}
+
+// setRuntimeFlags replaces the runtime flags of the controller with the ones set in the feature flags ConfigMap data.
+// Invalid values are logged and leave the flag unset.
+func (c *Controller) setRuntimeFlags(data map[string]string) {
+	flags := &runtimeFlags{
+		dryRun: c.parseFeatureFlag(data, DryRunFeatureFlag),
+		paused: c.parseFeatureFlag(data, PauseFeatureFlag),
+	}
+	for _, namespace := range strings.Split(data[ReadonlyNamespacesFeatureFlag], ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			flags.readonlyNamespaces = append(flags.readonlyNamespaces, namespace)
+		}
+	}
+
+	c.runtimeFlags.Store(flags)
+	c.logger.Info("Feature flags updated",
+		slog.Bool(DryRunFeatureFlag, flags.dryRun),
+		slog.Bool(PauseFeatureFlag, flags.paused),
+		slog.Any(ReadonlyNamespacesFeatureFlag, flags.readonlyNamespaces),
+	)
+}
+
+func (c *Controller) parseFeatureFlag(data map[string]string, key string) bool {
+	value, ok := data[key]
+	if !ok {
+		return false
+	}
+
+	enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		c.logger.Warn(fmt.Sprintf("Invalid value %q of feature flag %s, leaving it unset", value, key))
+		return false
+	}
+
+	return enabled
+}
+
+// getRuntimeFlags returns the current runtime flags, all unset if there is no feature flags ConfigMap.
+func (c *Controller) getRuntimeFlags() *runtimeFlags {
+	if flags := c.runtimeFlags.Load(); flags != nil {
+		return flags
+	}
+
+	return &runtimeFlags{}
+}
+
+// reloadsPaused reports whether checking secrets for changes should be skipped, because the reloader is paused
+// or the cluster is scaling. Changes are detected by the first check after it's resumed.
+func (c *Controller) reloadsPaused(ctx context.Context, logger *slog.Logger) bool {
+	if c.getRuntimeFlags().paused {
+		logger.Info("Reloader is paused by feature flag, skipping secret checks")
+		return true
+	}
+
+	return c.scalingInProgress(ctx, logger)
+}
+
+// skipReadonlyReloads removes the workloads that must not be changed from workloadsToReload, logging them:
+// all of them in dry-run mode, or the ones in read-only namespaces.
+func (c *Controller) skipReadonlyReloads(workloadsToReload map[workload][]secretChange, logger *slog.Logger) {
+	flags := c.getRuntimeFlags()
+	for skipped, changes := range workloadsToReload {
+		switch {
+		case flags.dryRun:
+			logger.Info(fmt.Sprintf("Dry run, not reloading workload: %s", skipped), secretChangesAttr(changes))
+		case slices.Contains(flags.readonlyNamespaces, skipped.namespace):
+			logger.Info(fmt.Sprintf("Namespace %s is read-only, not reloading workload: %s", skipped.namespace, skipped), secretChangesAttr(changes))
+		default:
+			continue
+		}
+		delete(workloadsToReload, skipped)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	prodDeployment := newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"})
+	prodDeployment.Namespace = "prod"
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}), prodDeployment)
+	informerFactory := kubeinformers.NewSharedInformerFactory(controller.kubeClient, 0)
+	featureFlagsOption, err := WithFeatureFlags(informerFactory.Core().V1().ConfigMaps(), "reloader/feature-flags")
+	require.NoError(t, err)
+	featureFlagsOption(controller)
+	informerFactory.Start(ctx.Done())
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), controller.featureFlagsSynced))
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	prodWorkload := workload{name: "test", namespace: "prod", kind: DeploymentKind}
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/foo"})
+	controller.workloadSecrets.Store(prodWorkload, []string{"secret/data/foo"})
+	controller.secretVersions["secret/data/foo"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 1}}
+
+	reloadCount := func(namespace string) string {
+		deployment, err := controller.kubeClient.AppsV1().Deployments(namespace).Get(ctx, "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		return deployment.Spec.Template.Annotations[ReloadCountAnnotationName]
+	}
+	reloadChangedWorkloads := func(version int) CycleSummary {
+		vaultClient.setVersion("secret/data/foo", version)
+		return controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+	}
+	featureFlags := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "feature-flags", Namespace: "reloader"}}
+	created := false
+	setFeatureFlags := func(data map[string]string) {
+		featureFlags.Data = data
+		if !created {
+			_, err = controller.kubeClient.CoreV1().ConfigMaps("reloader").Create(ctx, featureFlags, metav1.CreateOptions{})
+			created = true
+		} else {
+			_, err = controller.kubeClient.CoreV1().ConfigMaps("reloader").Update(ctx, featureFlags, metav1.UpdateOptions{})
+		}
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			flags := controller.getRuntimeFlags()
+			return flags.paused == (data[PauseFeatureFlag] == "true") && flags.dryRun == (data[DryRunFeatureFlag] == "true")
+		}, time.Second, time.Millisecond)
+	}
+
+	// Paused, changes are not checked until resumed
+	setFeatureFlags(map[string]string{PauseFeatureFlag: "true", DryRunFeatureFlag: "true"})
+	summary := reloadChangedWorkloads(2)
+	assert.Zero(t, summary.SecretsChecked)
+	assert.Equal(t, 1, controller.secretVersions["secret/data/foo"])
+
+	// Dry run, changes are detected, but no workloads are changed
+	setFeatureFlags(map[string]string{PauseFeatureFlag: "false", DryRunFeatureFlag: "true", ReadonlyNamespacesFeatureFlag: "prod"})
+	summary = reloadChangedWorkloads(2)
+	assert.Equal(t, 1, summary.SecretsChanged)
+	assert.Zero(t, summary.WorkloadsReloaded)
+	assert.Equal(t, 2, controller.secretVersions["secret/data/foo"])
+	assert.Equal(t, "", reloadCount("default"))
+	assert.Equal(t, "", reloadCount("prod"))
+
+	// Only workloads outside of read-only namespaces are reloaded
+	setFeatureFlags(map[string]string{ReadonlyNamespacesFeatureFlag: "staging, prod"})
+	summary = reloadChangedWorkloads(3)
+	assert.Equal(t, 1, summary.WorkloadsReloaded)
+	assert.Equal(t, "1", reloadCount("default"))
+	assert.Equal(t, "", reloadCount("prod"))
+
+	// Without the ConfigMap, all flags are unset
+	require.NoError(t, controller.kubeClient.CoreV1().ConfigMaps("reloader").Delete(ctx, "feature-flags", metav1.DeleteOptions{}))
+	require.Eventually(t, func() bool { return len(controller.getRuntimeFlags().readonlyNamespaces) == 0 }, time.Second, time.Millisecond)
+	summary = reloadChangedWorkloads(4)
+	assert.Equal(t, 2, summary.WorkloadsReloaded)
+	assert.Equal(t, "2", reloadCount("default"))
+	assert.Equal(t, "1", reloadCount("prod"))
+}
+
+func TestSetRuntimeFlagsInvalidValue(t *testing.T) {
+	controller := newTestController()
+	controller.setRuntimeFlags(map[string]string{PauseFeatureFlag: "yes please", DryRunFeatureFlag: "1"})
+	assert.Equal(t, &runtimeFlags{dryRun: true}, controller.getRuntimeFlags())
+
+	_, err := WithFeatureFlags(nil, "feature-flags")
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCycleHistorySize is the default amount of reloader cycle summaries kept for debugging.
+const DefaultCycleHistorySize = 10
+
+// CycleSummary is the outcome of a reloader cycle.
+type CycleSummary struct {
+	Timestamp         time.Time `json:"timestamp"`
+	CorrelationID     string    `json:"correlation_id,omitempty"`
+	SecretsChecked    int       `json:"secrets_checked"`
+	SecretsChanged    int       `json:"secrets_changed"`
+	WorkloadsReloaded int       `json:"workloads_reloaded"`
+	Errors            []string  `json:"errors,omitempty"`
+}
+
+// cycleHistory is a ring buffer of the most recent cycle summaries
+type cycleHistory struct {
+	mu        sync.Mutex
+	summaries []CycleSummary
+	next      int
+	full      bool
+	// last is kept even if the history is disabled, for the final summary on shutdown
+	last    CycleSummary
+	hasLast bool
+}
+
+func newCycleHistory(size int) *cycleHistory {
+	return &cycleHistory{summaries: make([]CycleSummary, max(size, 0))}
+}
+
+// add stores the summary, overwriting the oldest one if the buffer is full.
+func (h *cycleHistory) add(summary CycleSummary) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last = summary
+	h.hasLast = true
+	if len(h.summaries) == 0 {
+		return
+	}
+
+	h.summaries[h.next] = summary
+	h.next = (h.next + 1) % len(h.summaries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the stored summaries, oldest first.
+func (h *cycleHistory) list() []CycleSummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		return append([]CycleSummary{}, h.summaries[:h.next]...)
+	}
+
+	return append(append([]CycleSummary{}, h.summaries[h.next:]...), h.summaries[:h.next]...)
+}
+
+// latest returns the most recent summary, if any cycle ran yet.
+func (h *cycleHistory) latest() (CycleSummary, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.last, h.hasLast
+}
+
+// debugState is the state of the Controller served for debugging
+type debugState struct {
+	Cycles []CycleSummary `json:"cycles"`
+}
+
+// DebugStateHandler returns a handler serving the recent cycle summaries of the Controller as JSON.
+func (c *Controller) DebugStateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(debugState{Cycles: c.cycleHistory.list()})
+	})
+}
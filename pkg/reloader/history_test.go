@@ -0,0 +1,99 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCycleHistoryRetainsMostRecent(t *testing.T) {
+	history := newCycleHistory(3)
+	assert.Empty(t, history.list())
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		history.add(CycleSummary{Timestamp: start.Add(time.Duration(i) * time.Minute), SecretsChecked: i})
+		// Oldest first, up to the last 3
+		summaries := history.list()
+		require.Len(t, summaries, min(i+1, 3))
+		assert.Equal(t, i, summaries[len(summaries)-1].SecretsChecked)
+	}
+
+	summaries := history.list()
+	checked := make([]int, 0, len(summaries))
+	for _, summary := range summaries {
+		checked = append(checked, summary.SecretsChecked)
+	}
+	assert.Equal(t, []int{2, 3, 4}, checked)
+
+	// Modifying the returned summaries doesn't affect the history
+	summaries[0].SecretsChecked = 100
+	assert.Equal(t, 2, history.list()[0].SecretsChecked)
+}
+
+func TestCycleHistoryDisabled(t *testing.T) {
+	history := newCycleHistory(0)
+	_, ok := history.latest()
+	assert.False(t, ok)
+
+	history.add(CycleSummary{SecretsChecked: 1})
+	assert.Empty(t, history.list())
+
+	// The latest summary is kept for the final summary on shutdown
+	latest, ok := history.latest()
+	assert.True(t, ok)
+	assert.Equal(t, 1, latest.SecretsChecked)
+}
+
+func TestDebugStateHandler(t *testing.T) {
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/foo", "secret/data/bar"})
+	controller.secretVersions["secret/data/foo"] = 1
+	vaultClient := &versionedVaultClientMock{
+		versions: map[string]int{"secret/data/foo": 2},
+		errs:     map[string]error{"secret/data/bar": assert.AnError},
+	}
+
+	summary := controller.reloadChangedWorkloads(
+		context.Background(),
+		vaultClient,
+		controller.workloadSecrets.GetSecretWorkloadsMap(),
+		controller.logger,
+	)
+	assert.Equal(t, 2, summary.SecretsChecked)
+	assert.Equal(t, 1, summary.SecretsChanged)
+	assert.Equal(t, 1, summary.WorkloadsReloaded)
+	require.Len(t, summary.Errors, 1)
+	assert.Contains(t, summary.Errors[0], "secret/data/bar")
+	controller.cycleHistory.add(summary)
+
+	recorder := httptest.NewRecorder()
+	controller.DebugStateHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/state", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var state debugState
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &state))
+	assert.Equal(t, []CycleSummary{summary}, state.Cycles)
+}
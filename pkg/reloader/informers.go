@@ -0,0 +1,64 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+)
+
+// RequiredLabelInformerOption restricts the objects listed and watched by an informer factory to the ones
+// matching the label selector (e.g. "secrets-reloader.security.bank-vaults.io/enabled=true"), so only those
+// are cached. As annotations can't be selected on, this keeps the workload caches small in large clusters.
+// It should only be used for the factory of the workload informers.
+func RequiredLabelInformerOption(selector string) (informers.SharedInformerOption, error) {
+	if selector == "" {
+		return nil, fmt.Errorf("empty label selector")
+	}
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %w", selector, err)
+	}
+
+	return informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.LabelSelector = parsed.String()
+	}), nil
+}
+
+// JobInformerOption restricts the Jobs listed and watched by an informer factory to the ones that haven't succeeded,
+// and match the label selector if it's not empty. Succeeded Jobs are never reloaded, as their pod template can't be
+// changed once started, and keeping them out of the cache saves memory in clusters with many finished Jobs.
+// It replaces RequiredLabelInformerOption for the factory of the Job informer.
+func JobInformerOption(selector string) (informers.SharedInformerOption, error) {
+	var parsed labels.Selector
+	if selector != "" {
+		var err error
+		parsed, err = labels.Parse(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %w", selector, err)
+		}
+	}
+
+	return informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		if parsed != nil {
+			options.LabelSelector = parsed.String()
+		}
+		options.FieldSelector = fields.OneTermEqualSelector("status.successful", "0").String()
+	}), nil
+}
@@ -0,0 +1,93 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestRequiredLabelInformerOption(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	labeled := newTestDeployment("labeled", map[string]string{SecretReloadAnnotationName: "true"})
+	labeled.Labels = map[string]string{"reloader": "enabled"}
+	unlabeled := newTestDeployment("unlabeled", map[string]string{SecretReloadAnnotationName: "true"})
+	kubeClient := fake.NewSimpleClientset(labeled, unlabeled)
+
+	var selectors []string
+	kubeClient.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selectors = append(selectors, action.(k8stesting.ListAction).GetListRestrictions().Labels.String())
+		return false, nil, nil
+	})
+
+	option, err := RequiredLabelInformerOption("reloader=enabled")
+	require.NoError(t, err)
+	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, option)
+	deploymentInformer := informerFactory.Apps().V1().Deployments()
+	deploymentInformer.Informer()
+	informerFactory.Start(ctx.Done())
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), deploymentInformer.Informer().HasSynced))
+
+	// Only the labeled workloads are listed and cached
+	assert.Equal(t, []string{"reloader=enabled"}, selectors)
+	deployments, err := deploymentInformer.Lister().List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, deployments, 1)
+	assert.Equal(t, "labeled", deployments[0].Name)
+
+	for _, selector := range []string{"", "reloader==enabled=", "in valid"} {
+		_, err := RequiredLabelInformerOption(selector)
+		assert.Error(t, err, selector)
+	}
+}
+
+func TestJobInformerOption(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := fake.NewSimpleClientset()
+	var restrictions []k8stesting.ListRestrictions
+	kubeClient.PrependReactor("list", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		restrictions = append(restrictions, action.(k8stesting.ListAction).GetListRestrictions())
+		return false, nil, nil
+	})
+
+	option, err := JobInformerOption("reloader=enabled")
+	require.NoError(t, err)
+	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, option)
+	jobInformer := informerFactory.Batch().V1().Jobs()
+	jobInformer.Informer()
+	informerFactory.Start(ctx.Done())
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), jobInformer.Informer().HasSynced))
+
+	// Only labeled Jobs that haven't succeeded are listed
+	require.Len(t, restrictions, 1)
+	assert.Equal(t, "reloader=enabled", restrictions[0].Labels.String())
+	assert.Equal(t, "status.successful=0", restrictions[0].Fields.String())
+
+	_, err = JobInformerOption("in valid")
+	assert.Error(t, err)
+}
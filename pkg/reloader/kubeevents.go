@@ -0,0 +1,134 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	ReloadedEventReason     = "SecretsReloaded"
+	ReloadFailedEventReason = "SecretsReloadFailed"
+)
+
+// kubeEvents records reloads as Kubernetes Events on the reloaded workloads, aggregating the reloads of
+// a secret into a single summary event on summaryObject once they exceed aggregationThreshold in a cycle.
+type kubeEvents struct {
+	recorder             record.EventRecorder
+	summaryObject        *corev1.ObjectReference
+	aggregationThreshold int
+}
+
+// reloadResult is the outcome of reloading a workload in a cycle
+type reloadResult struct {
+	workload workload
+	changes  []secretChange
+	err      error
+}
+
+// WithKubernetesEvents enables recording reloads as Kubernetes Events on the reloaded workloads.
+// If more than aggregationThreshold workloads are reloaded for the same secret in a cycle, a single
+// summary event is recorded on summaryObject (e.g. the Pod of the reloader) for them instead, so mass
+// reloads don't flood the Events API.
+func WithKubernetesEvents(recorder record.EventRecorder, summaryObject *corev1.ObjectReference, aggregationThreshold int) (Option, error) {
+	if recorder == nil || summaryObject == nil || summaryObject.Name == "" {
+		return nil, fmt.Errorf("event recorder and summary object must be set")
+	}
+	if aggregationThreshold < 1 {
+		return nil, fmt.Errorf("invalid aggregation threshold %d, must be positive", aggregationThreshold)
+	}
+
+	return func(c *Controller) {
+		c.kubeEvents = &kubeEvents{
+			recorder:             recorder,
+			summaryObject:        summaryObject,
+			aggregationThreshold: aggregationThreshold,
+		}
+	}, nil
+}
+
+// recordKubernetesEvents records the reloads of a cycle as Kubernetes Events, if enabled.
+func (c *Controller) recordKubernetesEvents(results []reloadResult) {
+	if c.kubeEvents == nil {
+		return
+	}
+
+	var reloaded, failed []reloadResult
+	for _, result := range results {
+		if result.err != nil {
+			failed = append(failed, result)
+		} else {
+			reloaded = append(reloaded, result)
+		}
+	}
+
+	c.kubeEvents.record(reloaded, corev1.EventTypeNormal, ReloadedEventReason, "Reloaded")
+	c.kubeEvents.record(failed, corev1.EventTypeWarning, ReloadFailedEventReason, "Failed reloading")
+}
+
+func (e *kubeEvents) record(results []reloadResult, eventType, reason, action string) {
+	pathWorkloads := make(map[string]int)
+	for _, result := range results {
+		for _, path := range secretChangePaths(result.changes) {
+			pathWorkloads[path]++
+		}
+	}
+
+	var aggregatedPaths []string
+	for path, count := range pathWorkloads {
+		if count > e.aggregationThreshold {
+			aggregatedPaths = append(aggregatedPaths, path)
+		}
+	}
+	slices.Sort(aggregatedPaths)
+	for _, path := range aggregatedPaths {
+		e.recorder.Eventf(e.summaryObject, eventType, reason, "%s %d workloads for new versions of secret %s", action, pathWorkloads[path], path)
+	}
+
+	// The workloads only get events for their changes that weren't aggregated
+	for _, result := range results {
+		paths := slices.DeleteFunc(secretChangePaths(result.changes), func(path string) bool {
+			return slices.Contains(aggregatedPaths, path)
+		})
+		if len(paths) == 0 {
+			continue
+		}
+
+		message := fmt.Sprintf("%s for new versions of secrets: %s", action, strings.Join(paths, ", "))
+		if result.err != nil {
+			message = fmt.Sprintf("%s: %s", message, result.err)
+		}
+		e.recorder.Event(workloadObjectReference(result.workload), eventType, reason, message)
+	}
+}
+
+func workloadObjectReference(workload workload) *corev1.ObjectReference {
+	apiVersion := "apps/v1"
+	if isBatchKind(workload.kind) {
+		apiVersion = "batch/v1"
+	}
+
+	return &corev1.ObjectReference{
+		APIVersion: apiVersion,
+		Kind:       workload.kind,
+		Namespace:  workload.namespace,
+		Name:       workload.name,
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestKubernetesEventsAggregation(t *testing.T) {
+	ctx := context.Background()
+	var objects []runtime.Object
+	for i := range 5 {
+		objects = append(objects, newTestDeployment(fmt.Sprintf("mass-%d", i), map[string]string{SecretReloadAnnotationName: "true"}))
+	}
+	objects = append(objects, newTestDeployment("single", map[string]string{SecretReloadAnnotationName: "true"}))
+	controller := newTestController(objects...)
+
+	recorder := record.NewFakeRecorder(100)
+	option, err := WithKubernetesEvents(recorder, &corev1.ObjectReference{Kind: "Pod", Namespace: "reloader", Name: "reloader"}, 3)
+	require.NoError(t, err)
+	option(controller)
+
+	for i := range 5 {
+		controller.workloadSecrets.Store(workload{name: fmt.Sprintf("mass-%d", i), namespace: "default", kind: DeploymentKind}, []string{"secret/data/shared"})
+	}
+	controller.workloadSecrets.Store(workload{name: "single", namespace: "default", kind: DeploymentKind}, []string{"secret/data/shared", "secret/data/own"})
+	controller.secretVersions["secret/data/shared"] = 1
+	controller.secretVersions["secret/data/own"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/shared": 2, "secret/data/own": 2}}
+
+	// The mass reload for the shared secret is aggregated, only the other secret gets an event on the workload
+	summary := controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+	assert.Equal(t, 6, summary.WorkloadsReloaded)
+	close(recorder.Events)
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	assert.ElementsMatch(t, []string{
+		"Normal SecretsReloaded Reloaded 6 workloads for new versions of secret secret/data/shared",
+		"Normal SecretsReloaded Reloaded for new versions of secrets: secret/data/own",
+	}, events)
+}
+
+func TestKubernetesEventsBelowThreshold(t *testing.T) {
+	controller := newTestController()
+	recorder := record.NewFakeRecorder(10)
+	option, err := WithKubernetesEvents(recorder, &corev1.ObjectReference{Kind: "Pod", Namespace: "reloader", Name: "reloader"}, 3)
+	require.NoError(t, err)
+	option(controller)
+
+	controller.recordKubernetesEvents([]reloadResult{
+		{workload: workload{name: "a", namespace: "default", kind: DeploymentKind}, changes: []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 2}}},
+		{workload: workload{name: "b", namespace: "default", kind: DeploymentKind}, changes: []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 2}}, err: fmt.Errorf("conflict")},
+	})
+	assert.Equal(t, "Normal SecretsReloaded Reloaded for new versions of secrets: secret/data/foo", <-recorder.Events)
+	assert.Equal(t, "Warning SecretsReloadFailed Failed reloading for new versions of secrets: secret/data/foo: conflict", <-recorder.Events)
+
+	for _, threshold := range []int{0, -1} {
+		_, err := WithKubernetesEvents(recorder, &corev1.ObjectReference{Kind: "Pod", Name: "reloader"}, threshold)
+		assert.Error(t, err)
+	}
+	_, err = WithKubernetesEvents(nil, nil, 3)
+	assert.Error(t, err)
+	_, err = WithKubernetesEvents(recorder, &corev1.ObjectReference{Kind: "Pod"}, 3)
+	assert.Error(t, err)
+}
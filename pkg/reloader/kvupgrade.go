@@ -0,0 +1,105 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// kvMounts holds the mounts of the secrets read so far per Vault, to read the secrets of mounts
+// that were upgraded from KV v1 to v2 at their KV v2 path
+type kvMounts struct {
+	mu     sync.Mutex
+	mounts map[vaultTarget][]secretMount
+}
+
+// find returns the known mount of the secret path of the Vault target.
+func (m *kvMounts) find(target vaultTarget, secretPath string) (secretMount, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := slices.IndexFunc(m.mounts[target], func(mount secretMount) bool { return strings.HasPrefix(secretPath, mount.path) })
+	if index < 0 {
+		return secretMount{}, false
+	}
+
+	return m.mounts[target][index], true
+}
+
+// store records the mount of the Vault target, replacing the one known before.
+func (m *kvMounts) store(target vaultTarget, mount secretMount) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.mounts == nil {
+		m.mounts = make(map[vaultTarget][]secretMount)
+	}
+	mounts := slices.DeleteFunc(m.mounts[target], func(known secretMount) bool { return known.path == mount.path })
+	m.mounts[target] = append(mounts, mount)
+}
+
+// kvSecretPath returns the path the secret is read at on the mount: the secrets of KV v2 mounts
+// referenced by their KV v1 path (e.g. "secret/app") are read at their KV v2 path (e.g. "secret/data/app").
+func kvSecretPath(mount secretMount, secretPath string) string {
+	rest, ok := strings.CutPrefix(secretPath, mount.path)
+	if !ok || mount.kvVersion != "2" || strings.HasPrefix(rest, "data/") {
+		return secretPath
+	}
+
+	return mount.path + "data/" + strings.TrimPrefix(rest, "metadata/")
+}
+
+// readKVSecretVersion reads the secret version of the Vault target like readAliasedSecretVersion, at the path matching
+// the KV version of its mount. The mount is looked up when reading the secret fails, as long as it's not known to be
+// KV v2 already, so the secrets of mounts upgraded from KV v1 to v2 keep being read.
+func (c *Controller) readKVSecretVersion(ctx context.Context, vaultClient vaultSecretReader, target vaultTarget, secretPath string, logger *slog.Logger) (int, map[string]string, error) {
+	mount, known := c.kvMounts.find(target, secretPath)
+	readPath := kvSecretPath(mount, secretPath)
+	version, keyHashes, err := c.readAliasedSecretVersion(ctx, vaultClient, readPath, logger)
+	// A deleted secret was read from a KV v2 mount already
+	var deletedErr ErrSecretDeleted
+	if err == nil || errors.As(err, &deletedErr) || (known && mount.kvVersion == "2") {
+		return version, keyHashes, err
+	}
+
+	var mountReader vaultSecretReader = vaultClient
+	if c.vaultConfig.PathPrefix != "" {
+		mountReader = &prefixedSecretReader{reader: vaultClient, prefix: c.vaultConfig.PathPrefix}
+	}
+	detected, lookupErr := lookupSecretMount(ctx, mountReader, secretPath)
+	if lookupErr != nil {
+		logger.Debug(fmt.Errorf("failed to look up the mount of secret %s: %w", secretPath, lookupErr).Error())
+		return version, keyHashes, err
+	}
+	c.kvMounts.store(target, detected)
+	upgradedPath := kvSecretPath(detected, secretPath)
+	if upgradedPath == readPath {
+		return version, keyHashes, err
+	}
+
+	if known {
+		logger.Info(fmt.Sprintf("KV mount %s was upgraded from version %s to %s, reading secret %s at %s", detected.path, mount.kvVersion, detected.kvVersion, secretPath, upgradedPath))
+	} else {
+		logger.Info(fmt.Sprintf("KV mount %s is version %s, reading secret %s at %s", detected.path, detected.kvVersion, secretPath, upgradedPath))
+	}
+
+	return c.readAliasedSecretVersion(ctx, vaultClient, upgradedPath, logger)
+}
@@ -0,0 +1,135 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upgradableKVVaultClientMock serves the secret/app secret of the secret/ mount, as KV v1 or v2
+type upgradableKVVaultClientMock struct {
+	sync.Mutex
+	kvVersion string
+	reads     []string
+}
+
+func (c *upgradableKVVaultClientMock) ReadWithContext(_ context.Context, path string) (*vaultapi.Secret, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.reads = append(c.reads, path)
+
+	switch {
+	case path == vaultMountsPath+"secret/app":
+		return &vaultapi.Secret{Data: map[string]interface{}{
+			"path":    "secret/",
+			"type":    "kv",
+			"options": map[string]interface{}{"version": c.kvVersion},
+		}}, nil
+	case c.kvVersion == "1" && path == "secret/app":
+		return &vaultapi.Secret{Data: map[string]interface{}{"password": "secret"}}, nil
+	case c.kvVersion == "2" && path == "secret/data/app":
+		return &vaultapi.Secret{Data: map[string]interface{}{
+			"data":     map[string]interface{}{"password": "secret"},
+			"metadata": map[string]interface{}{"version": json.Number("1")},
+		}}, nil
+	case c.kvVersion == "2" && path == "secret/app":
+		// KV v2 mounts only respond with a warning to KV v1 paths
+		return &vaultapi.Secret{Warnings: []string{"Invalid path for a versioned K/V secrets engine"}}, nil
+	}
+
+	return nil, nil
+}
+
+func (c *upgradableKVVaultClientMock) upgrade() {
+	c.Lock()
+	defer c.Unlock()
+	c.kvVersion = "2"
+	c.reads = nil
+}
+
+func TestReadSecretVersionKVUpgrade(t *testing.T) {
+	controller := newTestController()
+	option, err := WithChangeDetection(ChangeDetectionWorkloadHash)
+	require.NoError(t, err)
+	option(controller)
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	vaultClient := &upgradableKVVaultClientMock{kvVersion: "1"}
+	ctx := context.Background()
+
+	// KV v1 secrets are read at their path, without looking up their mount
+	version, keyHashes, err := controller.readSecretVersionWithRole(ctx, vaultClient, "", "secret/app", logger)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.Contains(t, keyHashes, "password")
+	assert.Equal(t, []string{"secret/app"}, vaultClient.reads)
+
+	// Once the mount is upgraded, the failing read looks up the mount and the secret is read at its KV v2 path
+	vaultClient.upgrade()
+	version, keyHashes, err = controller.readSecretVersionWithRole(ctx, vaultClient, "", "secret/app", logger)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+	assert.Contains(t, keyHashes, "password")
+	assert.Equal(t, []string{"secret/app", vaultMountsPath + "secret/app", "secret/data/app"}, vaultClient.reads)
+	assert.Contains(t, logs.String(), "KV mount secret/ is version 2, reading secret secret/app at secret/data/app")
+
+	// The KV v2 mount is remembered
+	vaultClient.upgrade()
+	_, _, err = controller.readSecretVersionWithRole(ctx, vaultClient, "", "secret/app", logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"secret/data/app"}, vaultClient.reads)
+}
+
+func TestReadSecretVersionKVUpgradeKnownMount(t *testing.T) {
+	controller := newTestController()
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	vaultClient := &upgradableKVVaultClientMock{kvVersion: "1"}
+	ctx := context.Background()
+
+	// Without a KV version, reading the KV v1 secret fails and its mount is looked up
+	_, _, err := controller.readSecretVersionWithRole(ctx, vaultClient, "", "secret/app", logger)
+	require.Error(t, err)
+	mount, ok := controller.kvMounts.find(vaultTarget{}, "secret/app")
+	require.True(t, ok)
+	assert.Equal(t, secretMount{path: "secret/", kvVersion: "1"}, mount)
+
+	vaultClient.upgrade()
+	version, _, err := controller.readSecretVersionWithRole(ctx, vaultClient, "", "secret/app", logger)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+	assert.Contains(t, logs.String(), "KV mount secret/ was upgraded from version 1 to 2, reading secret secret/app at secret/data/app")
+}
+
+func TestKVSecretPath(t *testing.T) {
+	v1 := secretMount{path: "secret/", kvVersion: "1"}
+	v2 := secretMount{path: "secret/", kvVersion: "2"}
+
+	assert.Equal(t, "secret/app", kvSecretPath(v1, "secret/app"))
+	assert.Equal(t, "secret/data/app", kvSecretPath(v2, "secret/app"))
+	assert.Equal(t, "secret/data/app", kvSecretPath(v2, "secret/data/app"))
+	assert.Equal(t, "secret/data/app", kvSecretPath(v2, "secret/metadata/app"))
+	assert.Equal(t, "other/app", kvSecretPath(v2, "other/app"))
+	assert.Equal(t, "secret/app", kvSecretPath(secretMount{}, "secret/app"))
+}
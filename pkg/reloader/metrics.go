@@ -0,0 +1,169 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultMetricsPrefix is the prefix of the names of the metrics by default
+const DefaultMetricsPrefix = "reloader"
+
+var metricsPrefixRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// WithMetricsPrefix sets the prefix of the names of the metrics, e.g. "myorg_vsr" for "myorg_vsr_vault_sealed",
+// defaults to DefaultMetricsPrefix.
+func WithMetricsPrefix(prefix string) (Option, error) {
+	if !metricsPrefixRegexp.MatchString(prefix) {
+		return nil, fmt.Errorf("invalid metrics prefix %q, must only contain letters, digits and underscores, and not start with a digit", prefix)
+	}
+
+	return func(c *Controller) {
+		c.metricsPrefix = prefix
+	}, nil
+}
+
+type metrics struct {
+	prefix string
+
+	invalidReloadCounts *prometheus.CounterVec
+	vaultSealed         prometheus.Gauge
+	collectionFailures  *prometheus.CounterVec
+	lastCycleTimestamp  prometheus.Gauge
+	workloadsReloaded   *prometheus.CounterVec
+	vaultReadErrors     prometheus.Counter
+	trackedWorkloads    prometheus.Gauge
+	// disallowedSecretPaths is only incremented if allowed secret paths are set
+	disallowedSecretPaths    *prometheus.CounterVec
+	vaultNamespaceMismatches *prometheus.CounterVec
+	// reloadVerificationFailures is only incremented if reload verification is enabled
+	reloadVerificationFailures *prometheus.CounterVec
+	// reloadLoops is only incremented if reload loop detection is enabled
+	reloadLoops *prometheus.CounterVec
+	// secretVersionsSaveFailures is only incremented if secret versions are persisted
+	secretVersionsSaveFailures prometheus.Counter
+	// workloadInfo is only registered if enabled, as it has a series for every secret of every workload
+	workloadInfo *prometheus.GaugeVec
+}
+
+func newMetrics(registerer prometheus.Registerer, prefix string) *metrics {
+	m := &metrics{
+		prefix: prefix,
+		invalidReloadCounts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "invalid_reload_count_annotations_total",
+			Help:      "Number of reload count annotations found with an invalid value and reset.",
+		}, []string{"namespace", "kind"}),
+		vaultSealed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "vault_sealed",
+			Help:      "Whether Vault was sealed at the last check (1), not (0), or its seal status couldn't be checked (-1).",
+		}),
+		collectionFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "collection_failures_total",
+			Help:      "Number of times collecting the secrets of a workload from a source failed, while the other sources were collected.",
+		}, []string{"namespace", "kind", "source"}),
+		disallowedSecretPaths: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "disallowed_secret_paths_total",
+			Help:      "Number of collected secret paths dropped for not matching any of the allowed secret paths.",
+		}, []string{"namespace", "kind"}),
+		vaultNamespaceMismatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "vault_namespace_mismatches_total",
+			Help:      "Number of times a collected workload requested another Vault namespace than the reloader's.",
+		}, []string{"namespace", "kind"}),
+		reloadVerificationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "reload_verification_failures_total",
+			Help:      "Number of reloads that were reverted or didn't roll out by the time they were verified.",
+		}, []string{"namespace", "kind", "reason"}),
+		reloadLoops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "reload_loops_detected_total",
+			Help:      "Number of times a workload was found to be reloaded over and over again for the same secret versions, and stopped being reloaded.",
+		}, []string{"namespace", "kind"}),
+		secretVersionsSaveFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "secret_versions_save_failures_total",
+			Help:      "Number of times saving the secret versions to the ConfigMap failed, they are saved again in the next cycle.",
+		}),
+		lastCycleTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "last_cycle_timestamp_seconds",
+			Help:      "Unix timestamp of the end of the last reloader cycle, to alert on a stalled reloader loop.",
+		}),
+		workloadsReloaded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "workloads_reloaded_total",
+			Help:      "Number of workloads reloaded because of changed secrets.",
+		}, []string{"namespace", "kind"}),
+		vaultReadErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: prefix,
+			Name:      "vault_read_errors_total",
+			Help:      "Number of times reading the version of a secret from Vault failed, besides ignored missing secrets.",
+		}),
+		trackedWorkloads: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: prefix,
+			Name:      "tracked_workloads",
+			Help:      "Number of workloads tracked by the reloader at the last cycle.",
+		}),
+	}
+
+	registerer.MustRegister(
+		m.invalidReloadCounts,
+		m.vaultSealed,
+		m.collectionFailures,
+		m.disallowedSecretPaths,
+		m.vaultNamespaceMismatches,
+		m.reloadVerificationFailures,
+		m.reloadLoops,
+		m.secretVersionsSaveFailures,
+		m.lastCycleTimestamp,
+		m.workloadsReloaded,
+		m.vaultReadErrors,
+		m.trackedWorkloads,
+	)
+
+	return m
+}
+
+// registerWorkloadInfo registers the metric of the secrets used by the tracked workloads.
+func (m *metrics) registerWorkloadInfo(registerer prometheus.Registerer) {
+	m.workloadInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: m.prefix,
+		Name:      "workload_info",
+		Help:      "Secrets used by the workloads tracked by the reloader, always 1.",
+	}, []string{"namespace", "name", "kind", "secret_path"})
+	registerer.MustRegister(m.workloadInfo)
+}
+
+// updateWorkloadInfo replaces the series of the workload info metric with the secrets used by the workloads.
+func (m *metrics) updateWorkloadInfo(workloadSecrets map[workload][]string) {
+	if m.workloadInfo == nil {
+		return
+	}
+
+	m.workloadInfo.Reset()
+	for trackedWorkload, secretPaths := range workloadSecrets {
+		for _, secretPath := range secretPaths {
+			m.workloadInfo.WithLabelValues(trackedWorkload.namespace, trackedWorkload.name, trackedWorkload.kind, secretPath).Set(1)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestWorkloadInfoMetrics(t *testing.T) {
+	workload1 := workload{name: "test1", namespace: "default", kind: DeploymentKind}
+	workload2 := workload{name: "test2", namespace: "other", kind: StatefulSetKind}
+
+	t.Run("enabled", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		controller := newTestController()
+		controller.metrics = newMetrics(registry, DefaultMetricsPrefix)
+		controller.metrics.registerWorkloadInfo(registry)
+		controller.workloadSecrets.Store(workload1, []string{"secret/data/bar", "secret/data/foo"})
+		controller.workloadSecrets.Store(workload2, []string{"secret/data/foo"})
+
+		controller.runReloader(context.Background(), nil)
+		require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP reloader_workload_info Secrets used by the workloads tracked by the reloader, always 1.
+# TYPE reloader_workload_info gauge
+reloader_workload_info{kind="Deployment",name="test1",namespace="default",secret_path="secret/data/bar"} 1
+reloader_workload_info{kind="Deployment",name="test1",namespace="default",secret_path="secret/data/foo"} 1
+reloader_workload_info{kind="StatefulSet",name="test2",namespace="other",secret_path="secret/data/foo"} 1
+`), "reloader_workload_info"))
+
+		// The series of workloads and secrets that are not tracked anymore are removed
+		controller.workloadSecrets.Store(workload1, []string{"secret/data/foo"})
+		controller.workloadSecrets.Delete(workload2)
+		controller.runReloader(context.Background(), nil)
+		require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP reloader_workload_info Secrets used by the workloads tracked by the reloader, always 1.
+# TYPE reloader_workload_info gauge
+reloader_workload_info{kind="Deployment",name="test1",namespace="default",secret_path="secret/data/foo"} 1
+`), "reloader_workload_info"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		controller := newTestController()
+		controller.metrics = newMetrics(registry, DefaultMetricsPrefix)
+		controller.workloadSecrets.Store(workload1, []string{"secret/data/foo"})
+
+		controller.runReloader(context.Background(), nil)
+		count, err := testutil.GatherAndCount(registry, "reloader_workload_info")
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
+
+func TestLastCycleTimestampMetric(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	controller := newTestController()
+	controller.metrics = newMetrics(registry, DefaultMetricsPrefix)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakeClock(now)
+	controller.clock = fakeClock
+
+	controller.runReloader(context.Background(), nil)
+	assert.Equal(t, float64(now.Unix()), testutil.ToFloat64(controller.metrics.lastCycleTimestamp))
+
+	// The gauge advances every cycle, even without workloads to reload
+	fakeClock.Step(time.Minute)
+	controller.runReloader(context.Background(), nil)
+	assert.Equal(t, float64(now.Add(time.Minute).Unix()), testutil.ToFloat64(controller.metrics.lastCycleTimestamp))
+}
+
+func TestReloaderMetrics(t *testing.T) {
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	controller := newTestController(newTestDeployment("test", nil))
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/foo"})
+	controller.workloadSecrets.Store(workload{name: "other", namespace: "default", kind: StatefulSetKind}, []string{"secret/data/foo"})
+
+	controller.runReloader(context.Background(), nil)
+	assert.Equal(t, float64(2), testutil.ToFloat64(controller.metrics.trackedWorkloads))
+
+	errs := controller.reloadWorkloads(context.Background(), map[workload][]secretChange{
+		testWorkload: {{path: "secret/data/foo", oldVersion: 1, newVersion: 2}},
+		// Workloads that fail to be reloaded are not counted
+		{name: "missing", namespace: "default", kind: DeploymentKind}: {{path: "secret/data/foo", oldVersion: 1, newVersion: 2}},
+	}, controller.logger)
+	require.Len(t, errs, 1)
+	assert.Equal(t, float64(1), testutil.ToFloat64(controller.metrics.workloadsReloaded.WithLabelValues("default", DeploymentKind)))
+
+	controller.handleSecretError(assert.AnError, "secret/data/foo", controller.logger)
+	controller.handleSecretError(ErrSecretNotFound{}, "secret/data/bar", controller.logger)
+	assert.Equal(t, float64(2), testutil.ToFloat64(controller.metrics.vaultReadErrors))
+
+	// Ignored missing secrets are not errors
+	controller.vaultConfig.IgnoreMissingSecrets = true
+	controller.handleSecretError(ErrSecretNotFound{}, "secret/data/bar", controller.logger)
+	assert.Equal(t, float64(2), testutil.ToFloat64(controller.metrics.vaultReadErrors))
+}
+
+func TestMetricsPrefix(t *testing.T) {
+	option, err := WithMetricsPrefix("myorg_vsr")
+	require.NoError(t, err)
+	controller := newTestController()
+	option(controller)
+	assert.Equal(t, "myorg_vsr", controller.metricsPrefix)
+
+	registry := prometheus.NewRegistry()
+	m := newMetrics(registry, controller.metricsPrefix)
+	m.registerWorkloadInfo(registry)
+	m.vaultSealed.Set(1)
+	m.updateWorkloadInfo(map[workload][]string{
+		{name: "test", namespace: "default", kind: DeploymentKind}: {"secret/data/foo"},
+	})
+
+	for _, name := range []string{"myorg_vsr_vault_sealed", "myorg_vsr_workload_info"} {
+		count, err := testutil.GatherAndCount(registry, name)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count, name)
+	}
+	count, err := testutil.GatherAndCount(registry, "reloader_vault_sealed", "reloader_workload_info")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	for _, prefix := range []string{"", "1reloader", "my-org", "myorg:vsr"} {
+		_, err := WithMetricsPrefix(prefix)
+		assert.Error(t, err, prefix)
+	}
+}
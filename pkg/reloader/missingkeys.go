@@ -0,0 +1,73 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// WithMissingKeyDetection enables validating that the keys workloads reference of a secret
+// (e.g. "vault:secret/data/app#key") still exist in it, warning about the missing ones, and reloading
+// the workloads whose referenced keys disappeared, even if the version of the secret didn't change.
+func WithMissingKeyDetection(enabled bool) Option {
+	return func(c *Controller) {
+		c.missingKeyDetection = enabled
+	}
+}
+
+// keyHashesNeeded reports whether the hashes of the keys of secrets are read and kept.
+func (c *Controller) keyHashesNeeded() bool {
+	return c.subkeyAwareReload || c.missingKeyDetection || c.changeDetection == ChangeDetectionWorkloadHash
+}
+
+// checkReferencedKeys warns about the keys the workloads reference that are missing from the secret, either
+// on its first check or once they disappear, returning the workloads whose referenced keys disappeared since
+// the last check.
+func (c *Controller) checkReferencedKeys(workloads []workload, secretPath string, secretKeys map[workload]map[string][]string, storedKeyHashes, keyHashes map[string]string, logger *slog.Logger) map[workload]bool {
+	if !c.missingKeyDetection || keyHashes == nil {
+		return nil
+	}
+
+	keysDisappeared := make(map[workload]bool)
+	for _, workload := range workloads {
+		missing, disappeared := missingReferencedKeys(secretKeys[workload][secretPath], storedKeyHashes, keyHashes)
+		switch {
+		case len(disappeared) > 0:
+			logger.Warn(fmt.Sprintf("Keys %v referenced by %s %s/%s disappeared from secret %s, reloading it", disappeared, workload.kind, workload.namespace, workload.name, secretPath))
+			keysDisappeared[workload] = true
+		case len(missing) > 0 && storedKeyHashes == nil:
+			logger.Warn(fmt.Sprintf("Keys %v referenced by %s %s/%s are missing from secret %s", missing, workload.kind, workload.namespace, workload.name, secretPath))
+		}
+	}
+
+	return keysDisappeared
+}
+
+// missingReferencedKeys returns the referenced keys missing from the secret,
+// and the ones of them that were present at the last check.
+func missingReferencedKeys(keys []string, storedKeyHashes, keyHashes map[string]string) (missing, disappeared []string) {
+	for _, key := range keys {
+		if _, ok := keyHashes[key]; ok {
+			continue
+		}
+		missing = append(missing, key)
+		if _, stored := storedKeyHashes[key]; stored {
+			disappeared = append(disappeared, key)
+		}
+	}
+
+	return missing, disappeared
+}
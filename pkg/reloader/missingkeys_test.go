@@ -0,0 +1,79 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSecretVersionsMissingKeys(t *testing.T) {
+	controller := newTestController()
+	WithMissingKeyDetection(true)(controller)
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	keyUser := workload{name: "user", namespace: "default", kind: DeploymentKind}
+	keyHost := workload{name: "host", namespace: "default", kind: DeploymentKind}
+	whole := workload{name: "whole", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.SetSecretKeys(keyUser, map[string][]string{"secret/data/mysql": {"user"}})
+	controller.workloadSecrets.SetSecretKeys(keyHost, map[string][]string{"secret/data/mysql": {"host"}})
+	secretWorkloads := map[string][]workload{"secret/data/mysql": {keyUser, keyHost, whole}}
+
+	// A key missing on the first check is only warned about
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{}, data: map[string]map[string]interface{}{}}
+	vaultClient.setData("secret/data/mysql", 1, map[string]interface{}{"user": "app", "password": "secret1"})
+	workloadsToReload, errs := controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, logger)
+	require.Empty(t, errs)
+	assert.Empty(t, workloadsToReload)
+	assert.Contains(t, logs.String(), "Keys [host] referenced by Deployment default/host are missing from secret secret/data/mysql")
+
+	// A referenced key disappearing without a new version reloads the workload referencing it
+	logs.Reset()
+	vaultClient.setData("secret/data/mysql", 1, map[string]interface{}{"password": "secret1"})
+	workloadsToReload, errs = controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, logger)
+	require.Empty(t, errs)
+	assert.Equal(t, map[workload][]secretChange{
+		keyUser: {{path: "secret/data/mysql", oldVersion: 1, newVersion: 1}},
+	}, workloadsToReload)
+	assert.Contains(t, logs.String(), "Keys [user] referenced by Deployment default/user disappeared from secret secret/data/mysql, reloading it")
+	assert.NotContains(t, logs.String(), "host")
+
+	// Without subkey-aware reloading, a new version still reloads all workloads
+	vaultClient.setData("secret/data/mysql", 2, map[string]interface{}{"password": "secret2"})
+	workloadsToReload, errs = controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, logger)
+	require.Empty(t, errs)
+	assert.Len(t, workloadsToReload, 3)
+}
+
+func TestMissingReferencedKeys(t *testing.T) {
+	missing, disappeared := missingReferencedKeys(
+		[]string{"user", "password", "host"},
+		map[string]string{"user": "a", "host": "b"},
+		map[string]string{"user": "a"},
+	)
+	assert.Equal(t, []string{"password", "host"}, missing)
+	assert.Equal(t, []string{"host"}, disappeared)
+
+	// Nothing disappeared without keys stored before
+	missing, disappeared = missingReferencedKeys([]string{"user"}, nil, map[string]string{})
+	assert.Equal(t, []string{"user"}, missing)
+	assert.Empty(t, disappeared)
+}
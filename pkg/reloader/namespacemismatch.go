@@ -0,0 +1,73 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+const (
+	// VaultNamespaceMismatchFollow reads the secrets of workloads requesting another Vault namespace
+	// than the Reloader's from that namespace
+	VaultNamespaceMismatchFollow = "follow"
+	// VaultNamespaceMismatchSkip doesn't track the secrets of workloads requesting another Vault namespace
+	// than the Reloader's, e.g. if its Vault role can't read them
+	VaultNamespaceMismatchSkip = "skip"
+)
+
+// WithVaultNamespaceMismatch sets how workloads requesting another Vault namespace than the Reloader's
+// with the vault-namespace annotation are handled, either VaultNamespaceMismatchFollow (the default)
+// or VaultNamespaceMismatchSkip.
+func WithVaultNamespaceMismatch(mode string) (Option, error) {
+	switch mode {
+	case VaultNamespaceMismatchFollow, VaultNamespaceMismatchSkip:
+	default:
+		return nil, fmt.Errorf("unknown Vault namespace mismatch handling %q, must be %q or %q", mode, VaultNamespaceMismatchFollow, VaultNamespaceMismatchSkip)
+	}
+
+	return func(c *Controller) {
+		c.vaultNamespaceMismatch = mode
+	}, nil
+}
+
+// reloaderVaultNamespace returns the Vault namespace of the Reloader, as configured with VAULT_NAMESPACE.
+func (c *Controller) reloaderVaultNamespace() string {
+	if c.vaultConfig != nil && c.vaultConfig.Namespace != "" {
+		return c.vaultConfig.Namespace
+	}
+
+	return getVaultConfigFromEnv().Namespace
+}
+
+// checkVaultNamespaceMismatch reports whether the secrets of the workload can be tracked, counting and logging
+// if it requests another Vault namespace than the Reloader's. With VaultNamespaceMismatchSkip, they can't.
+func (c *Controller) checkVaultNamespaceMismatch(workload workload, target vaultTarget, logger *slog.Logger) bool {
+	namespace := c.reloaderVaultNamespace()
+	if target.namespace == "" || target.namespace == namespace {
+		return true
+	}
+
+	c.metrics.vaultNamespaceMismatches.WithLabelValues(workload.namespace, workload.kind).Inc()
+	if c.vaultNamespaceMismatch == VaultNamespaceMismatchSkip {
+		logger.Warn(fmt.Sprintf("%s %s/%s requests Vault namespace %q instead of %q, its secrets are not tracked",
+			workload.kind, workload.namespace, workload.name, target.namespace, namespace))
+		return false
+	}
+
+	logger.Debug(fmt.Sprintf("%s %s/%s requests Vault namespace %q instead of %q, reading its secrets from it",
+		workload.kind, workload.namespace, workload.name, target.namespace, namespace))
+	return true
+}
@@ -0,0 +1,77 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/bank-vaults/secrets-webhook/pkg/common"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCollectWorkloadSecretsVaultNamespaceMismatch(t *testing.T) {
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	newDeployment := func(vaultNamespace string) *corev1.PodTemplateSpec {
+		deployment := newTestDeployment("test", map[string]string{
+			SecretReloadAnnotationName:      "true",
+			common.VaultNamespaceAnnotation: vaultNamespace,
+		})
+		deployment.Spec.Template.Spec.Containers = []corev1.Container{
+			{Name: "app", Env: []corev1.EnvVar{{Name: "PASSWORD", Value: "vault:secret/data/app#password"}}},
+		}
+		return &deployment.Spec.Template
+	}
+
+	t.Run("follow", func(t *testing.T) {
+		controller := newTestController()
+		controller.vaultConfig.Namespace = "admin"
+
+		controller.collectWorkloadSecrets(testWorkload, nil, *newDeployment("team-a"))
+		assert.Equal(t, map[workload][]string{
+			testWorkload: {"|team-a|secret/data/app"},
+		}, controller.workloadSecrets.GetWorkloadSecretsMap())
+		assert.Equal(t, 1.0, testutil.ToFloat64(controller.metrics.vaultNamespaceMismatches.WithLabelValues("default", DeploymentKind)))
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		controller := newTestController()
+		controller.vaultConfig.Namespace = "admin"
+		option, err := WithVaultNamespaceMismatch(VaultNamespaceMismatchSkip)
+		require.NoError(t, err)
+		option(controller)
+		var logs bytes.Buffer
+		controller.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+		// A workload collected before requesting another namespace stops being tracked
+		controller.collectWorkloadSecrets(testWorkload, nil, *newDeployment("admin"))
+		require.Len(t, controller.workloadSecrets.GetWorkloadSecretsMap(), 1)
+		assert.Equal(t, 0.0, testutil.ToFloat64(controller.metrics.vaultNamespaceMismatches.WithLabelValues("default", DeploymentKind)))
+
+		controller.collectWorkloadSecrets(testWorkload, nil, *newDeployment("team-a"))
+		assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+		assert.Equal(t, 1.0, testutil.ToFloat64(controller.metrics.vaultNamespaceMismatches.WithLabelValues("default", DeploymentKind)))
+		assert.Contains(t, logs.String(), `Deployment default/test requests Vault namespace \"team-a\" instead of \"admin\", its secrets are not tracked`)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := WithVaultNamespaceMismatch("ignore")
+		assert.Error(t, err)
+	})
+}
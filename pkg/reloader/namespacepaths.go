@@ -0,0 +1,79 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	namespacePlaceholder = "{namespace}"
+	pathPlaceholder      = "{path}"
+)
+
+// WithNamespacePathTemplate sets the template relative secret paths (without a "/", e.g. "app" in
+// "vault:app#password") are resolved with, replacing "{namespace}" with the namespace of the workload
+// and "{path}" with the relative path, e.g. "secret/data/{namespace}/{path}" for per-namespace secrets.
+// Relative paths are kept as they are without a template.
+func WithNamespacePathTemplate(template string) (Option, error) {
+	if template != "" {
+		if !strings.Contains(template, pathPlaceholder) {
+			return nil, fmt.Errorf("invalid namespace path template %q, must contain %s", template, pathPlaceholder)
+		}
+		if normalizeSecretPath(template) != template {
+			return nil, fmt.Errorf("invalid namespace path template %q, must not contain empty segments", template)
+		}
+	}
+
+	return func(c *Controller) {
+		c.namespacePathTemplate = template
+	}, nil
+}
+
+// resolveSecretPath returns the secret path a relative secret path of a workload in the namespace refers to,
+// according to the namespace path template, or the secret path itself if it's not relative.
+func (c *Controller) resolveSecretPath(namespace, secretPath string) string {
+	if c.namespacePathTemplate == "" || strings.Contains(secretPath, "/") {
+		return secretPath
+	}
+
+	return strings.NewReplacer(namespacePlaceholder, namespace, pathPlaceholder, secretPath).Replace(c.namespacePathTemplate)
+}
+
+// resolveSecretPaths resolves the relative secret paths of a workload in the namespace in place.
+func (c *Controller) resolveSecretPaths(namespace string, secretPaths []string) []string {
+	for i, secretPath := range secretPaths {
+		secretPaths[i] = c.resolveSecretPath(namespace, secretPath)
+	}
+
+	return secretPaths
+}
+
+// resolveSecretKeys returns the keys referenced of each secret of a workload in the namespace,
+// by their resolved secret path.
+func (c *Controller) resolveSecretKeys(namespace string, secretKeys map[string][]string) map[string][]string {
+	if c.namespacePathTemplate == "" {
+		return secretKeys
+	}
+
+	resolved := make(map[string][]string, len(secretKeys))
+	for secretPath, keys := range secretKeys {
+		resolvedPath := c.resolveSecretPath(namespace, secretPath)
+		resolved[resolvedPath] = append(resolved[resolvedPath], keys...)
+	}
+
+	return resolved
+}
@@ -0,0 +1,76 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// namespaceFilter holds the namespaces whose workloads are collected, the zero value allows every namespace.
+type namespaceFilter struct {
+	watched  map[string]bool
+	excluded map[string]bool
+}
+
+// allows reports whether the workloads of the namespace are collected.
+func (f namespaceFilter) allows(namespace string) bool {
+	if f.watched != nil && !f.watched[namespace] {
+		return false
+	}
+
+	return !f.excluded[namespace]
+}
+
+// WithNamespaces restricts the workloads collected by the controller to the ones in the watched namespaces,
+// and not in the excluded ones. No watched namespaces, the default, watches every namespace.
+func WithNamespaces(watched, excluded []string) (Option, error) {
+	watchedSet, err := namespaceSet(watched)
+	if err != nil {
+		return nil, err
+	}
+	excludedSet, err := namespaceSet(excluded)
+	if err != nil {
+		return nil, err
+	}
+	for namespace := range excludedSet {
+		if watchedSet[namespace] {
+			return nil, fmt.Errorf("namespace %q is both watched and excluded", namespace)
+		}
+	}
+
+	return func(c *Controller) {
+		c.namespaceFilter = namespaceFilter{watched: watchedSet, excluded: excludedSet}
+	}, nil
+}
+
+// namespaceSet validates the namespace names, returning nil if there are none.
+func namespaceSet(namespaces []string) (map[string]bool, error) {
+	if len(namespaces) == 0 {
+		return nil, nil
+	}
+
+	set := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid namespace %q: %s", namespace, strings.Join(errs, ", "))
+		}
+		set[namespace] = true
+	}
+
+	return set, nil
+}
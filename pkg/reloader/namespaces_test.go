@@ -0,0 +1,74 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithNamespaces(t *testing.T) {
+	tests := []struct {
+		name      string
+		watched   []string
+		excluded  []string
+		collected []string
+	}{
+		{name: "all namespaces", collected: []string{"default", "team-a", "kube-system"}},
+		{name: "watched", watched: []string{"team-a", "default"}, collected: []string{"default", "team-a"}},
+		{name: "excluded", excluded: []string{"kube-system"}, collected: []string{"default", "team-a"}},
+		{name: "watched and excluded", watched: []string{"team-a"}, excluded: []string{"kube-system"}, collected: []string{"team-a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, err := WithNamespaces(tt.watched, tt.excluded)
+			require.NoError(t, err)
+			controller := newTestController()
+			option(controller)
+
+			var collected []string
+			for _, namespace := range []string{"default", "team-a", "kube-system"} {
+				deployment := newTestDeployment("test", map[string]string{
+					SecretReloadAnnotationName:                                "true",
+					"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/app",
+				})
+				deployment.Namespace = namespace
+				controller.handleObject(deployment)
+
+				testWorkload := workload{name: "test", namespace: namespace, kind: DeploymentKind}
+				if _, ok := controller.workloadSecrets.GetWorkloadSecretsMap()[testWorkload]; ok {
+					collected = append(collected, namespace)
+				}
+
+				// Deleting a workload of a namespace that's not watched is ignored as well
+				controller.handleObjectDelete(deployment)
+				assert.NotContains(t, controller.workloadSecrets.GetWorkloadSecretsMap(), testWorkload)
+			}
+			assert.Equal(t, tt.collected, collected)
+		})
+	}
+
+	for _, namespaces := range [][2][]string{
+		{{"Invalid_Namespace"}, nil},
+		{nil, {""}},
+		{{"team-a"}, {"team-a"}},
+	} {
+		_, err := WithNamespaces(namespaces[0], namespaces[1])
+		assert.Error(t, err, namespaces)
+	}
+}
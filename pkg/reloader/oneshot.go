@@ -0,0 +1,179 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// RunOnce waits for the informer caches to sync, runs a single reloader cycle for the secrets of all
+// workloads and returns, to run the reloader as a batch job (e.g. from CI or a CronJob). Unless secret
+// versions are persisted to a ConfigMap they are only kept in memory, so the versions workloads were last
+// reloaded at (recorded in their secret-versions annotation) are used as the stored versions, only reloading the
+// workloads behind. It returns an error if any secret could not be checked or any workload could not be reloaded.
+func (c *Controller) RunOnce(ctx context.Context) error {
+	defer utilruntime.HandleCrash()
+
+	c.logger.Info("Running vault-secrets-reloader once")
+	if err := c.waitForCacheSync(ctx); err != nil {
+		return err
+	}
+
+	// The event handlers may not have processed all cached workloads yet, so they are collected here
+	accessors, err := c.listWorkloadAccessors()
+	if err != nil {
+		return fmt.Errorf("failed to list workloads: %w", err)
+	}
+	for _, accessor := range accessors {
+		c.processWorkload(accessor)
+	}
+	if err := c.loadSecretVersions(ctx); err != nil {
+		c.logger.Warn(err.Error())
+	}
+	c.seedSecretVersions(accessors)
+
+	summary := c.runReloader(ctx, c.workloadSecrets.GetSecretWorkloadsMap())
+	c.flush()
+	if len(summary.Errors) > 0 {
+		return fmt.Errorf("reloader cycle failed: %s", strings.Join(summary.Errors, "; "))
+	}
+
+	return nil
+}
+
+// listWorkloadAccessors returns the accessors of all cached workloads.
+func (c *Controller) listWorkloadAccessors() ([]WorkloadAccessor, error) {
+	var accessors []WorkloadAccessor
+	deployments, err := c.deploymentsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments {
+		accessors = append(accessors, &deploymentAccessor{deployment})
+	}
+
+	daemonSets, err := c.daemonSetsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, daemonSet := range daemonSets {
+		accessors = append(accessors, &daemonSetAccessor{daemonSet})
+	}
+
+	statefulSets, err := c.statefulSetsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, statefulSet := range statefulSets {
+		accessors = append(accessors, &statefulSetAccessor{statefulSet})
+	}
+
+	cronJobs, err := c.cronJobsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, cronJob := range cronJobs {
+		accessors = append(accessors, &cronJobAccessor{cronJob})
+	}
+
+	jobs, err := c.jobsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		accessors = append(accessors, &jobAccessor{job})
+	}
+
+	return accessors, nil
+}
+
+// seedSecretVersions stores the lowest version of each secret the collected workloads were last reloaded at,
+// so if any workload is behind, the change is detected in the first cycle. The version each workload was reloaded
+// at is kept too, so only the workloads behind are reloaded (see skipUpToDateWorkloads). Secrets no workload was
+// reloaded for are only recorded in the first cycle, like on startup.
+func (c *Controller) seedSecretVersions(accessors []WorkloadAccessor) {
+	workloadSecrets := c.workloadSecrets.GetWorkloadSecretsMap()
+
+	c.secretVersionsMu.Lock()
+	defer c.secretVersionsMu.Unlock()
+	for _, accessor := range accessors {
+		seededWorkload := workloadFromAccessor(accessor)
+		if _, ok := workloadSecrets[seededWorkload]; !ok {
+			continue
+		}
+		versionsJSON := accessor.GetPodTemplate().GetAnnotations()[SecretVersionsAnnotationName]
+		if versionsJSON == "" {
+			continue
+		}
+
+		var versions map[string]int
+		if err := json.Unmarshal([]byte(versionsJSON), &versions); err != nil {
+			c.logger.Warn(fmt.Errorf("invalid %s annotation of %s %s/%s: %w", SecretVersionsAnnotationName, accessor.Kind(), accessor.GetNamespace(), accessor.GetName(), err).Error())
+			continue
+		}
+		for secretPath, version := range versions {
+			if stored, ok := c.secretVersions[secretPath]; !ok || version < stored {
+				c.secretVersions[secretPath] = version
+			}
+		}
+		if c.seededVersions == nil {
+			c.seededVersions = make(map[workload]map[string]int)
+		}
+		c.seededVersions[seededWorkload] = versions
+	}
+}
+
+// skipUpToDateWorkloads removes the changes of the secrets whose current version the workloads were already
+// reloaded at, according to the versions seeded from their secret-versions annotation, along with the workloads
+// left without changes. The changes of the other workloads are counted from the version they were reloaded at.
+// The seeded versions are only used in the first cycle.
+func (c *Controller) skipUpToDateWorkloads(workloadsToReload map[workload][]secretChange, logger *slog.Logger) {
+	c.secretVersionsMu.Lock()
+	seededVersions := c.seededVersions
+	c.seededVersions = nil
+	c.secretVersionsMu.Unlock()
+	if len(seededVersions) == 0 {
+		return
+	}
+
+	for changedWorkload, changes := range workloadsToReload {
+		versions, ok := seededVersions[changedWorkload]
+		if !ok {
+			continue
+		}
+		changes = slices.DeleteFunc(changes, func(change secretChange) bool {
+			return versions[change.path] == change.newVersion
+		})
+		for i, change := range changes {
+			if version, ok := versions[change.path]; ok {
+				changes[i].oldVersion = version
+			}
+		}
+		if len(changes) == 0 {
+			logger.Info(fmt.Sprintf("Workload %s was already reloaded at the current versions of its secrets, skipping reload", changedWorkload))
+			delete(workloadsToReload, changedWorkload)
+			continue
+		}
+		workloadsToReload[changedWorkload] = changes
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newOneShotTestController returns a controller with informers of the clientset started and a Vault client
+// for a fake Vault server, serving version 3 of secret/data/foo. Reactors must be added to the clientset before,
+// as the informers use it concurrently.
+func newOneShotTestController(ctx context.Context, t *testing.T, kubeClient *fake.Clientset) *Controller {
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/health", "/v1/sys/seal-status":
+			_, _ = io.WriteString(w, `{"initialized": true, "sealed": false}`)
+		case "/v1/secret/data/foo":
+			_, _ = io.WriteString(w, `{"data": {"data": {"password": "secret"}, "metadata": {"version": 3}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(vaultServer.Close)
+	vaultClient, err := vaultapi.NewClient(&vaultapi.Config{Address: vaultServer.URL})
+	require.NoError(t, err)
+
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	controller := NewController(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		kubeClient,
+		informerFactory.Apps().V1().Deployments(),
+		informerFactory.Apps().V1().DaemonSets(),
+		informerFactory.Apps().V1().StatefulSets(),
+		informerFactory.Batch().V1().CronJobs(),
+		informerFactory.Batch().V1().Jobs(),
+		WithMetricsRegisterer(prometheus.NewRegistry()),
+	)
+	controller.vaultClient = vaultClient
+	controller.vaultConfig = &VaultConfig{}
+	informerFactory.Start(ctx.Done())
+
+	return controller
+}
+
+func newOneShotTestTemplate(versions string) *corev1.PodTemplateSpec {
+	return &corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				SecretReloadAnnotationName:   "true",
+				SecretVersionsAnnotationName: versions,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "app",
+				Env:  []corev1.EnvVar{{Name: "PASSWORD", Value: "vault:secret/data/foo#password"}},
+			}},
+		},
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	behind := newTestDeployment("behind", nil)
+	behind.Spec.Template = *newOneShotTestTemplate(`{"secret/data/foo": 2}`)
+	upToDate := newTestDeployment("up-to-date", nil)
+	upToDate.Spec.Template = *newOneShotTestTemplate(`{"secret/data/foo": 3}`)
+	kubeClient := fake.NewSimpleClientset(behind, upToDate)
+	controller := newOneShotTestController(ctx, t, kubeClient)
+
+	// Only the workload reloaded at an older version is reloaded in the single cycle
+	require.NoError(t, controller.RunOnce(ctx))
+	summary, ok := controller.cycleHistory.latest()
+	require.True(t, ok)
+	assert.Equal(t, 1, summary.SecretsChecked)
+	assert.Equal(t, 1, summary.WorkloadsReloaded)
+	deployment, err := kubeClient.AppsV1().Deployments("default").Get(ctx, "behind", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", deployment.Spec.Template.Annotations[ReloadCountAnnotationName])
+	assert.Equal(t, `{"secret/data/foo":3}`, deployment.Spec.Template.Annotations[SecretVersionsAnnotationName])
+	deployment, err = kubeClient.AppsV1().Deployments("default").Get(ctx, "up-to-date", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, deployment.Spec.Template.Annotations, ReloadCountAnnotationName)
+}
+
+func TestRunOnceUpToDate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	upToDate := newTestDeployment("up-to-date", nil)
+	upToDate.Spec.Template = *newOneShotTestTemplate(`{"secret/data/foo": 3}`)
+	kubeClient := fake.NewSimpleClientset(upToDate)
+	controller := newOneShotTestController(ctx, t, kubeClient)
+
+	// Workloads already reloaded at the current versions are not reloaded again
+	require.NoError(t, controller.RunOnce(ctx))
+	summary, ok := controller.cycleHistory.latest()
+	require.True(t, ok)
+	assert.Equal(t, 0, summary.WorkloadsReloaded)
+	for _, action := range kubeClient.Actions() {
+		assert.NotEqual(t, "update", action.GetVerb())
+	}
+}
+
+func TestRunOnceReloadFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	behind := newTestDeployment("behind", nil)
+	behind.Spec.Template = *newOneShotTestTemplate(`{"secret/data/foo": 2}`)
+	kubeClient := fake.NewSimpleClientset(behind)
+	kubeClient.PrependReactor("update", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, assert.AnError
+	})
+	controller := newOneShotTestController(ctx, t, kubeClient)
+
+	// A failed reload fails the run
+	err := controller.RunOnce(ctx)
+	assert.ErrorContains(t, err, "failed reloading workload")
+}
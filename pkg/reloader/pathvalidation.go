@@ -0,0 +1,148 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// vaultMountsPath is the path the mount of a secret path is looked up at, readable with any capability on the path
+const vaultMountsPath = "sys/internal/ui/mounts/"
+
+// WithSecretPathValidation enables validating the collected secret paths against the mounts of Vault on startup,
+// reporting the paths of KV v2 mounts that miss the "data" segment after the mount (e.g. "secret/foo" instead
+// of "secret/data/foo") at once.
+func WithSecretPathValidation(enabled bool) Option {
+	return func(c *Controller) {
+		c.secretPathValidation = enabled
+	}
+}
+
+// misconfiguredSecretPath is a secret path of a KV v2 mount that misses the "data" segment
+type misconfiguredSecretPath struct {
+	path      string
+	suggested string
+	workloads []workload
+}
+
+// secretMount is the mount of secret paths, as reported by Vault
+type secretMount struct {
+	path      string
+	kvVersion string
+}
+
+// runSecretPathValidation validates the secret paths collected on startup, logging a report of the misconfigured ones.
+func (c *Controller) runSecretPathValidation(ctx context.Context) {
+	validationLogger := c.logger.With(slog.String("worker", "path-validation"))
+
+	vaultClient, err := c.getVaultClient(ctx)
+	if err != nil {
+		validationLogger.Error(fmt.Errorf("failed to initialize Vault client: %w", err).Error())
+		return
+	}
+
+	var reader vaultSecretReader = vaultClient.Logical()
+	if c.vaultConfig.PathPrefix != "" {
+		reader = &prefixedSecretReader{reader: reader, prefix: c.vaultConfig.PathPrefix}
+	}
+	c.reportMisconfiguredSecretPaths(c.validateSecretPaths(ctx, reader, c.workloadSecrets.GetSecretWorkloadsMap(), validationLogger), validationLogger)
+}
+
+// validateSecretPaths returns the secret paths of KV v2 mounts that miss the "data" segment after the mount,
+// sorted by path. Paths whose mount can't be looked up are skipped.
+func (c *Controller) validateSecretPaths(ctx context.Context, vaultClient vaultSecretReader, secretWorkloads map[string][]workload, logger *slog.Logger) []misconfiguredSecretPath {
+	var mounts []secretMount
+	var misconfigured []misconfiguredSecretPath
+	for secretPath, workloads := range secretWorkloads {
+		index := slices.IndexFunc(mounts, func(mount secretMount) bool { return strings.HasPrefix(secretPath, mount.path) })
+		if index < 0 {
+			mount, err := lookupSecretMount(ctx, vaultClient, secretPath)
+			if err != nil {
+				logger.Debug(fmt.Errorf("failed to look up the mount of secret %s: %w", secretPath, err).Error())
+				continue
+			}
+			mounts = append(mounts, mount)
+			index = len(mounts) - 1
+		}
+
+		mount := mounts[index]
+		rest := strings.TrimPrefix(secretPath, mount.path)
+		if mount.kvVersion != "2" || strings.HasPrefix(rest, "data/") {
+			continue
+		}
+		workloads = slices.Clone(workloads)
+		slices.SortFunc(workloads, compareWorkloads)
+		misconfigured = append(misconfigured, misconfiguredSecretPath{
+			path:      secretPath,
+			suggested: mount.path + "data/" + strings.TrimPrefix(rest, "metadata/"),
+			workloads: workloads,
+		})
+	}
+
+	slices.SortFunc(misconfigured, func(a, b misconfiguredSecretPath) int {
+		return strings.Compare(a.path, b.path)
+	})
+
+	return misconfigured
+}
+
+// lookupSecretMount returns the mount of the secret path.
+func lookupSecretMount(ctx context.Context, vaultClient vaultSecretReader, secretPath string) (secretMount, error) {
+	secret, err := vaultClient.ReadWithContext(ctx, vaultMountsPath+secretPath)
+	if err != nil {
+		return secretMount{}, err
+	}
+	if secret == nil {
+		return secretMount{}, fmt.Errorf("no mount found")
+	}
+
+	mountPath, _ := secret.Data["path"].(string)
+	if mountPath == "" {
+		return secretMount{}, fmt.Errorf("no mount path in the response")
+	}
+	mount := secretMount{path: strings.TrimSuffix(mountPath, "/") + "/"}
+	if mountType, _ := secret.Data["type"].(string); mountType == "kv" {
+		mount.kvVersion = "1"
+		if options, ok := secret.Data["options"].(map[string]interface{}); ok {
+			if version, ok := options["version"].(string); ok && version != "" {
+				mount.kvVersion = version
+			}
+		}
+	}
+
+	return mount, nil
+}
+
+// reportMisconfiguredSecretPaths logs the misconfigured secret paths in a single report.
+func (c *Controller) reportMisconfiguredSecretPaths(misconfigured []misconfiguredSecretPath, logger *slog.Logger) {
+	if len(misconfigured) == 0 {
+		logger.Info("No misconfigured secret paths found")
+		return
+	}
+
+	entries := make([]string, 0, len(misconfigured))
+	for _, path := range misconfigured {
+		workloads := make([]string, 0, len(path.workloads))
+		for _, workload := range path.workloads {
+			workloads = append(workloads, fmt.Sprintf("%s %s/%s", workload.kind, workload.namespace, workload.name))
+		}
+		entries = append(entries, fmt.Sprintf("%s (should be %s, used by %s)", path.path, path.suggested, strings.Join(workloads, ", ")))
+	}
+	logger.Warn(fmt.Sprintf("Found %d secret paths of KV v2 mounts missing the data segment: %s", len(misconfigured), strings.Join(entries, "; ")))
+}
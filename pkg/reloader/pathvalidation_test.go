@@ -0,0 +1,81 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// mountsVaultClientMock answers mount lookups of secret paths from the given mounts
+type mountsVaultClientMock struct {
+	mounts  map[string]map[string]interface{}
+	lookups int
+}
+
+func (c *mountsVaultClientMock) ReadWithContext(_ context.Context, path string) (*vaultapi.Secret, error) {
+	c.lookups++
+	secretPath := strings.TrimPrefix(path, vaultMountsPath)
+	for mountPath, mount := range c.mounts {
+		if strings.HasPrefix(secretPath, mountPath) {
+			data := map[string]interface{}{"path": mountPath}
+			for key, value := range mount {
+				data[key] = value
+			}
+			return &vaultapi.Secret{Data: data}, nil
+		}
+	}
+
+	return nil, assert.AnError
+}
+
+func TestValidateSecretPaths(t *testing.T) {
+	controller := newTestController()
+	vaultClient := &mountsVaultClientMock{mounts: map[string]map[string]interface{}{
+		"secret/": {"type": "kv", "options": map[string]interface{}{"version": "2"}},
+		"kv1/":    {"type": "kv", "options": map[string]interface{}{"version": "1"}},
+		"pki/":    {"type": "pki"},
+	}}
+	app := workload{name: "app", namespace: "default", kind: DeploymentKind}
+	db := workload{name: "db", namespace: "default", kind: StatefulSetKind}
+	secretWorkloads := map[string][]workload{
+		"secret/data/app":     {app},
+		"secret/app":          {db, app},
+		"secret/metadata/db":  {db},
+		"kv1/app":             {app},
+		"pki/issue/app":       {app},
+		"unmounted/data/test": {db},
+	}
+
+	misconfigured := controller.validateSecretPaths(context.Background(), vaultClient, secretWorkloads, controller.logger)
+	assert.Equal(t, []misconfiguredSecretPath{
+		{path: "secret/app", suggested: "secret/data/app", workloads: []workload{app, db}},
+		{path: "secret/metadata/db", suggested: "secret/data/db", workloads: []workload{db}},
+	}, misconfigured)
+	// The mount of each path is only looked up once, besides the paths without a mount
+	assert.Equal(t, 4, vaultClient.lookups)
+
+	var logs bytes.Buffer
+	controller.reportMisconfiguredSecretPaths(misconfigured, slog.New(slog.NewTextHandler(&logs, nil)))
+	assert.Contains(t, logs.String(), "Found 2 secret paths of KV v2 mounts missing the data segment: "+
+		"secret/app (should be secret/data/app, used by Deployment default/app, StatefulSet default/db); "+
+		"secret/metadata/db (should be secret/data/db, used by StatefulSet default/db)")
+}
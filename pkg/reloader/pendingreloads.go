@@ -0,0 +1,114 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const (
+	// PendingReloadsDrop drops the reloads triggered but not run yet on shutdown, logging the workloads
+	PendingReloadsDrop = "drop"
+	// PendingReloadsRun runs the reloads triggered but not run yet once on shutdown, within the shutdown flush timeout
+	PendingReloadsRun = "run"
+)
+
+// WithPendingReloadsOnShutdown sets how the reloads triggered but not run yet when the controller shuts down are
+// handled, either PendingReloadsDrop (the default) or PendingReloadsRun. These are the coalesced reloads waiting for
+// the end of their window, and the changes of pinned versions not reloaded yet. Reloads deferred until the reload
+// window opens are not run outside of it.
+func WithPendingReloadsOnShutdown(mode string) (Option, error) {
+	switch mode {
+	case PendingReloadsDrop, PendingReloadsRun:
+	default:
+		return nil, fmt.Errorf("unknown pending reloads mode %q, must be %q or %q", mode, PendingReloadsDrop, PendingReloadsRun)
+	}
+
+	return func(c *Controller) {
+		c.runPendingReloadsOnShutdown = mode == PendingReloadsRun
+	}, nil
+}
+
+// waitForScheduledReloads waits for the goroutines of the coalesced reloads to return,
+// which they do once the context of the controller is cancelled.
+func (c *Controller) waitForScheduledReloads() {
+	if c.reloadCoalescer != nil {
+		c.reloadCoalescer.scheduled.Wait()
+	}
+}
+
+// takePendingReloads removes the reloads triggered but not run yet, and returns them
+// without the ones of the workloads that were deleted meanwhile.
+func (c *Controller) takePendingReloads() map[workload][]secretChange {
+	workloadsToReload := make(map[workload][]secretChange)
+	c.pinnedVersions.drain(workloadsToReload)
+	if c.reloadCoalescer != nil {
+		c.reloadCoalescer.mu.Lock()
+		for coalesced, changes := range c.reloadCoalescer.pending {
+			workloadsToReload[coalesced] = mergeSecretChanges(workloadsToReload[coalesced], changes)
+			delete(c.reloadCoalescer.pending, coalesced)
+		}
+		c.reloadCoalescer.mu.Unlock()
+	}
+
+	// Workloads deleted since their reload was triggered are not reloaded
+	workloadSecrets := c.workloadSecrets.GetWorkloadSecretsMap()
+	for pendingWorkload := range workloadsToReload {
+		if _, ok := workloadSecrets[pendingWorkload]; !ok {
+			delete(workloadsToReload, pendingWorkload)
+		}
+	}
+
+	return workloadsToReload
+}
+
+// handlePendingReloads runs or drops the reloads triggered but not run yet on shutdown,
+// as configured with WithPendingReloadsOnShutdown.
+func (c *Controller) handlePendingReloads(ctx context.Context) {
+	workloadsToReload := c.takePendingReloads()
+	if len(workloadsToReload) == 0 {
+		return
+	}
+
+	ctx = WithCorrelationID(ctx, string(uuid.NewUUID()))
+	logger := c.logger.With(
+		slog.String("worker", "reloader"),
+		slog.String("correlation_id", correlationIDFromContext(ctx)),
+	)
+
+	if !c.runPendingReloadsOnShutdown || c.reloadsPaused(ctx, logger) {
+		pendingWorkloads := make([]string, 0, len(workloadsToReload))
+		for pendingWorkload := range workloadsToReload {
+			pendingWorkloads = append(pendingWorkloads, fmt.Sprintf("%s %s/%s", pendingWorkload.kind, pendingWorkload.namespace, pendingWorkload.name))
+		}
+		slices.Sort(pendingWorkloads)
+		logger.Warn(fmt.Sprintf("Dropping the pending reloads of %d workloads on shutdown: %s", len(pendingWorkloads), strings.Join(pendingWorkloads, ", ")))
+		return
+	}
+
+	c.deferOutsideReloadWindow(workloadsToReload, logger)
+	if len(workloadsToReload) == 0 {
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Reloading %d pending workloads before shutting down", len(workloadsToReload)))
+	c.reloadWorkloads(ctx, workloadsToReload, logger)
+}
@@ -0,0 +1,77 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestShutdownWithPendingReloads(t *testing.T) {
+	for _, mode := range []string{PendingReloadsDrop, PendingReloadsRun} {
+		t.Run(mode, func(t *testing.T) {
+			controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+			var logs bytes.Buffer
+			controller.logger = slog.New(slog.NewTextHandler(&logs, nil))
+			controller.clock = testingclock.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+			WithReloadCoalescing(time.Minute)(controller)
+			option, err := WithPendingReloadsOnShutdown(mode)
+			require.NoError(t, err)
+			option(controller)
+
+			testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+			deletedWorkload := workload{name: "deleted", namespace: "default", kind: DeploymentKind}
+			controller.workloadSecrets.Store(testWorkload, []string{"secret/data/foo"})
+			controller.recordReload(testWorkload)
+			controller.recordReload(deletedWorkload)
+
+			// The reload is coalesced until the end of the window, which is not reached before shutdown
+			ctx, cancel := context.WithCancel(context.Background())
+			controller.coalesceReloads(ctx, map[workload][]secretChange{
+				testWorkload:    {{path: "secret/data/foo", oldVersion: 1, newVersion: 2}},
+				deletedWorkload: {{path: "secret/data/foo", oldVersion: 1, newVersion: 2}},
+			}, controller.logger)
+			cancel()
+			controller.waitForScheduledReloads()
+			controller.flush()
+
+			deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+			require.NoError(t, err)
+			if mode == PendingReloadsRun {
+				assert.Equal(t, "1", deployment.Spec.Template.Annotations[ReloadCountAnnotationName])
+				assert.Contains(t, logs.String(), "Reloading 1 pending workloads before shutting down")
+			} else {
+				assert.NotContains(t, deployment.Spec.Template.Annotations, ReloadCountAnnotationName)
+				assert.Contains(t, logs.String(), "Dropping the pending reloads of 1 workloads on shutdown: Deployment default/test")
+			}
+			controller.reloadCoalescer.mu.Lock()
+			defer controller.reloadCoalescer.mu.Unlock()
+			assert.Empty(t, controller.reloadCoalescer.pending)
+		})
+	}
+
+	t.Run("unknown mode", func(t *testing.T) {
+		_, err := WithPendingReloadsOnShutdown("later")
+		assert.Error(t, err)
+	})
+}
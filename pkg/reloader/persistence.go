@@ -0,0 +1,245 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+)
+
+// secretVersionsConfigMapKey is the key of the ConfigMap data the secret versions are kept in, as a JSON object
+// of secret paths to versions, as ConfigMap keys can't contain the slashes of secret paths
+const secretVersionsConfigMapKey = "secret-versions.json"
+
+// secretVersionsConfigMap is the ConfigMap the secret versions are persisted to across restarts
+type secretVersionsConfigMap struct {
+	namespace string
+	name      string
+
+	mu sync.Mutex
+	// saved are the versions saved (or loaded) last, so unchanged versions are not saved again
+	saved map[string]int
+	// unsavedCycles is the number of cycles the versions changed in since they were saved last
+	unsavedCycles int
+}
+
+// secretVersionsSaveBatching batches the saves of the secret versions, to reduce the writes of the ConfigMap
+type secretVersionsSaveBatching struct {
+	// minChanges is the number of changed secret versions saved right away
+	minChanges int
+	// maxCycles is the number of cycles fewer changes are kept unsaved for, 0 keeps them until shutdown
+	maxCycles int
+}
+
+// WithSecretVersionsConfigMap persists the versions of the checked secrets to the ConfigMap in the namespace,
+// loading them on startup, so changes of secrets made while the reloader was not running are detected.
+func WithSecretVersionsConfigMap(namespace, name string) (Option, error) {
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid namespace %q: %s", namespace, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid ConfigMap name %q: %s", name, strings.Join(errs, ", "))
+	}
+
+	return func(c *Controller) {
+		c.secretVersionsConfigMap = &secretVersionsConfigMap{namespace: namespace, name: name}
+		// Versions kept unsaved by batching are saved on shutdown
+		c.shutdownFlushes = append(c.shutdownFlushes, c.flushSecretVersions)
+	}, nil
+}
+
+// WithSecretVersionsSaveBatching only saves the secret versions persisted with WithSecretVersionsConfigMap once
+// the versions of at least minChanges secrets changed since they were saved last, or fewer versions changed in
+// maxCycles cycles, whichever comes first, and on shutdown. By default every change is saved right away,
+// zero maxCycles only saves fewer changes on shutdown.
+func WithSecretVersionsSaveBatching(minChanges, maxCycles int) (Option, error) {
+	if minChanges < 1 {
+		return nil, fmt.Errorf("invalid secret versions save threshold %d, must be positive", minChanges)
+	}
+	if maxCycles < 0 {
+		return nil, fmt.Errorf("invalid secret versions save max cycles %d, must not be negative", maxCycles)
+	}
+
+	return func(c *Controller) {
+		c.secretVersionsSaveBatching = secretVersionsSaveBatching{minChanges: minChanges, maxCycles: maxCycles}
+	}, nil
+}
+
+// loadSecretVersions stores the secret versions persisted to the ConfigMap, if any.
+// A missing ConfigMap is created on the first save.
+func (c *Controller) loadSecretVersions(ctx context.Context) error {
+	if c.secretVersionsConfigMap == nil {
+		return nil
+	}
+	persisted := c.secretVersionsConfigMap
+
+	configMap, err := c.kubeClient.CoreV1().ConfigMaps(persisted.namespace).Get(ctx, persisted.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		c.logger.Info(fmt.Sprintf("Secret versions ConfigMap %s/%s not found, starting without persisted versions", persisted.namespace, persisted.name))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret versions ConfigMap %s/%s: %w", persisted.namespace, persisted.name, err)
+	}
+
+	versionsJSON := configMap.Data[secretVersionsConfigMapKey]
+	if versionsJSON == "" {
+		return nil
+	}
+	var versions map[string]int
+	if err := json.Unmarshal([]byte(versionsJSON), &versions); err != nil {
+		return fmt.Errorf("invalid secret versions in ConfigMap %s/%s: %w", persisted.namespace, persisted.name, err)
+	}
+
+	c.secretVersionsMu.Lock()
+	for secretPath, version := range versions {
+		c.secretVersions[secretPath] = version
+	}
+	c.secretVersionsMu.Unlock()
+
+	persisted.mu.Lock()
+	persisted.saved = versions
+	persisted.mu.Unlock()
+
+	c.logger.Info(fmt.Sprintf("Loaded %d secret versions from ConfigMap %s/%s", len(versions), persisted.namespace, persisted.name))
+	return nil
+}
+
+// saveSecretVersions persists the secret versions to the ConfigMap at the end of a cycle, unless they didn't
+// change since they were saved last, or the changes are batched.
+func (c *Controller) saveSecretVersions(ctx context.Context, logger *slog.Logger) error {
+	if c.secretVersionsConfigMap == nil {
+		return nil
+	}
+	persisted := c.secretVersionsConfigMap
+	versions := c.secretVersionsSnapshot()
+
+	persisted.mu.Lock()
+	defer persisted.mu.Unlock()
+	changed := countChangedSecretVersions(persisted.saved, versions)
+	if changed == 0 {
+		persisted.unsavedCycles = 0
+		return nil
+	}
+
+	persisted.unsavedCycles++
+	batching := c.secretVersionsSaveBatching
+	if changed < max(batching.minChanges, 1) && (batching.maxCycles == 0 || persisted.unsavedCycles < batching.maxCycles) {
+		logger.Debug(fmt.Sprintf("Batching the save of %d changed secret versions", changed))
+		return nil
+	}
+
+	if err := c.writeSecretVersions(ctx, versions); err != nil {
+		return err
+	}
+	logger.Debug(fmt.Sprintf("Saved secret versions to ConfigMap %s/%s", persisted.namespace, persisted.name))
+	return nil
+}
+
+// flushSecretVersions saves the secret versions not saved yet because of batching.
+func (c *Controller) flushSecretVersions(ctx context.Context) error {
+	persisted := c.secretVersionsConfigMap
+	versions := c.secretVersionsSnapshot()
+
+	persisted.mu.Lock()
+	defer persisted.mu.Unlock()
+	if countChangedSecretVersions(persisted.saved, versions) == 0 {
+		return nil
+	}
+
+	return c.writeSecretVersions(ctx, versions)
+}
+
+// writeSecretVersions writes the secret versions to the ConfigMap, creating it if it doesn't exist,
+// must be called with the mutex of the ConfigMap held. If it fails, the versions in memory stay authoritative,
+// and are written again with the next save.
+func (c *Controller) writeSecretVersions(ctx context.Context, versions map[string]int) error {
+	persisted := c.secretVersionsConfigMap
+
+	versionsJSON, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+
+	// The ConfigMap is read and written again if it was changed, or created, by someone else in between
+	configMaps := c.kubeClient.CoreV1().ConfigMaps(persisted.namespace)
+	err = retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		configMap, err := configMaps.Get(ctx, persisted.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: persisted.name, Namespace: persisted.namespace},
+				Data:       map[string]string{secretVersionsConfigMapKey: string(versionsJSON)},
+			}
+			_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{FieldManager: c.fieldManager})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		configMap = configMap.DeepCopy()
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[secretVersionsConfigMapKey] = string(versionsJSON)
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{FieldManager: c.fieldManager})
+		return err
+	})
+	if err != nil {
+		c.metrics.secretVersionsSaveFailures.Inc()
+		return fmt.Errorf("failed to save secret versions to ConfigMap %s/%s: %w", persisted.namespace, persisted.name, err)
+	}
+
+	persisted.saved = versions
+	persisted.unsavedCycles = 0
+	return nil
+}
+
+// secretVersionsSnapshot returns a copy of the stored secret versions.
+func (c *Controller) secretVersionsSnapshot() map[string]int {
+	c.secretVersionsMu.Lock()
+	defer c.secretVersionsMu.Unlock()
+	return maps.Clone(c.secretVersions)
+}
+
+// countChangedSecretVersions returns the number of secrets whose version differs between saved and versions,
+// including the ones only in either of them.
+func countChangedSecretVersions(saved, versions map[string]int) int {
+	changed := 0
+	for secretPath, version := range versions {
+		if savedVersion, ok := saved[secretPath]; !ok || savedVersion != version {
+			changed++
+		}
+	}
+	for secretPath := range saved {
+		if _, ok := versions[secretPath]; !ok {
+			changed++
+		}
+	}
+	return changed
+}
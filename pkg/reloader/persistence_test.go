@@ -0,0 +1,269 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestLoadSecretVersions(t *testing.T) {
+	ctx := context.Background()
+	controller := newTestController(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret-versions", Namespace: "reloader"},
+		Data:       map[string]string{secretVersionsConfigMapKey: `{"secret/data/foo":1}`},
+	})
+	option, err := WithSecretVersionsConfigMap("reloader", "secret-versions")
+	require.NoError(t, err)
+	option(controller)
+
+	require.NoError(t, controller.loadSecretVersions(ctx))
+	assert.Equal(t, map[string]int{"secret/data/foo": 1}, controller.secretVersions)
+
+	// The secret changed while the reloader was not running
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 2}}
+	workloadsToReload, errs := controller.checkSecretVersions(ctx, vaultClient, map[string][]workload{"secret/data/foo": {testWorkload}}, controller.logger)
+	require.Empty(t, errs)
+	assert.Equal(t, map[workload][]secretChange{
+		testWorkload: {{path: "secret/data/foo", oldVersion: 1, newVersion: 2}},
+	}, workloadsToReload)
+}
+
+func TestSecretVersionsReloadAfterDowntime(t *testing.T) {
+	ctx := context.Background()
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+	})
+	kubeClient := fake.NewSimpleClientset(deployment)
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 1}}
+
+	// start starts a reloader with the persisted versions, and collects the workload
+	start := func() *Controller {
+		controller := newTestController()
+		controller.kubeClient = kubeClient
+		option, err := WithSecretVersionsConfigMap("reloader", "secret-versions")
+		require.NoError(t, err)
+		option(controller)
+		require.NoError(t, controller.loadSecretVersions(ctx))
+		current, err := kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		controller.handleObject(current)
+		return controller
+	}
+	cycle := func(controller *Controller) int {
+		summary := controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+		require.NoError(t, controller.saveSecretVersions(ctx, controller.logger))
+		return summary.WorkloadsReloaded
+	}
+
+	// The first reloader only records the version of the secret
+	controller := start()
+	assert.Equal(t, 0, cycle(controller))
+	controller.flush()
+
+	// The secret changes while the reloader is down
+	vaultClient.versions["secret/data/foo"] = 2
+
+	// The change is reloaded in the first cycle after the restart, and only then
+	controller = start()
+	assert.Equal(t, 1, cycle(controller))
+	assert.Equal(t, 0, cycle(controller))
+	controller.flush()
+
+	// Another restart without changes doesn't reload it again
+	controller = start()
+	assert.Equal(t, 0, cycle(controller))
+
+	reloaded, err := kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", reloaded.Spec.Template.Annotations[ReloadCountAnnotationName])
+}
+
+func TestLoadSecretVersionsMissingConfigMap(t *testing.T) {
+	controller := newTestController()
+	option, err := WithSecretVersionsConfigMap("reloader", "secret-versions")
+	require.NoError(t, err)
+	option(controller)
+
+	require.NoError(t, controller.loadSecretVersions(context.Background()))
+	assert.Empty(t, controller.secretVersions)
+}
+
+func TestSaveSecretVersions(t *testing.T) {
+	ctx := context.Background()
+	controller := newTestController()
+	option, err := WithSecretVersionsConfigMap("reloader", "secret-versions")
+	require.NoError(t, err)
+	option(controller)
+	kubeClient := controller.kubeClient.(*fake.Clientset)
+
+	// The ConfigMap is created on the first save
+	controller.secretVersions["secret/data/foo"] = 1
+	require.NoError(t, controller.saveSecretVersions(ctx, controller.logger))
+	configMap, err := kubeClient.CoreV1().ConfigMaps("reloader").Get(ctx, "secret-versions", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `{"secret/data/foo":1}`, configMap.Data[secretVersionsConfigMapKey])
+
+	// Unchanged versions are not saved again
+	kubeClient.ClearActions()
+	require.NoError(t, controller.saveSecretVersions(ctx, controller.logger))
+	assert.Empty(t, kubeClient.Actions())
+
+	controller.secretVersions["secret/data/foo"] = 2
+	require.NoError(t, controller.saveSecretVersions(ctx, controller.logger))
+	configMap, err = kubeClient.CoreV1().ConfigMaps("reloader").Get(ctx, "secret-versions", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `{"secret/data/foo":2}`, configMap.Data[secretVersionsConfigMapKey])
+}
+
+func TestSaveSecretVersionsConflict(t *testing.T) {
+	ctx := context.Background()
+	controller := newTestController(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret-versions", Namespace: "reloader"},
+		Data:       map[string]string{secretVersionsConfigMapKey: `{"secret/data/foo":1}`},
+	})
+	option, err := WithSecretVersionsConfigMap("reloader", "secret-versions")
+	require.NoError(t, err)
+	option(controller)
+	require.NoError(t, controller.loadSecretVersions(ctx))
+	kubeClient := controller.kubeClient.(*fake.Clientset)
+
+	// Someone else updates the ConfigMap between the first read and write
+	conflicts := 1
+	kubeClient.PrependReactor("update", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			return false, nil, nil
+		}
+		conflicts--
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "secret-versions", errors.New("the object has been modified"))
+	})
+
+	controller.secretVersions["secret/data/foo"] = 2
+	require.NoError(t, controller.saveSecretVersions(ctx, controller.logger))
+	configMap, err := kubeClient.CoreV1().ConfigMaps("reloader").Get(ctx, "secret-versions", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `{"secret/data/foo":2}`, configMap.Data[secretVersionsConfigMapKey])
+	assert.Zero(t, testutil.ToFloat64(controller.metrics.secretVersionsSaveFailures))
+}
+
+func TestSaveSecretVersionsFailure(t *testing.T) {
+	ctx := context.Background()
+	controller := newTestController()
+	option, err := WithSecretVersionsConfigMap("reloader", "secret-versions")
+	require.NoError(t, err)
+	option(controller)
+	kubeClient := controller.kubeClient.(*fake.Clientset)
+	unavailable := true
+	kubeClient.PrependReactor("create", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return unavailable, nil, apierrors.NewServiceUnavailable("unavailable")
+	})
+
+	// The versions in memory are kept, and saved again in the next cycle
+	controller.secretVersions["secret/data/foo"] = 1
+	assert.Error(t, controller.saveSecretVersions(ctx, controller.logger))
+	assert.Equal(t, float64(1), testutil.ToFloat64(controller.metrics.secretVersionsSaveFailures))
+	assert.Equal(t, map[string]int{"secret/data/foo": 1}, controller.secretVersions)
+
+	unavailable = false
+	require.NoError(t, controller.saveSecretVersions(ctx, controller.logger))
+	configMap, err := kubeClient.CoreV1().ConfigMaps("reloader").Get(ctx, "secret-versions", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `{"secret/data/foo":1}`, configMap.Data[secretVersionsConfigMapKey])
+}
+
+func TestSaveSecretVersionsBatching(t *testing.T) {
+	ctx := context.Background()
+	controller := newTestController()
+	option, err := WithSecretVersionsConfigMap("reloader", "secret-versions")
+	require.NoError(t, err)
+	option(controller)
+	option, err = WithSecretVersionsSaveBatching(2, 3)
+	require.NoError(t, err)
+	option(controller)
+	kubeClient := controller.kubeClient.(*fake.Clientset)
+	writes := func() int {
+		count := 0
+		for _, action := range kubeClient.Actions() {
+			if action.GetResource().Resource == "configmaps" && (action.GetVerb() == "create" || action.GetVerb() == "update") {
+				count++
+			}
+		}
+		return count
+	}
+	cycle := func(versions map[string]int) {
+		for secretPath, version := range versions {
+			controller.secretVersions[secretPath] = version
+		}
+		require.NoError(t, controller.saveSecretVersions(ctx, controller.logger))
+	}
+
+	// Changes of as many secrets as the threshold are saved right away
+	cycle(map[string]int{"secret/data/foo": 1, "secret/data/bar": 1})
+	assert.Equal(t, 1, writes())
+
+	// Cycles without changes don't write
+	for range 5 {
+		cycle(nil)
+	}
+	assert.Equal(t, 1, writes())
+
+	// Fewer changes are saved after the max cycles
+	cycle(map[string]int{"secret/data/foo": 2})
+	cycle(nil)
+	assert.Equal(t, 1, writes())
+	cycle(nil)
+	assert.Equal(t, 2, writes())
+
+	// Pending changes are saved on shutdown
+	cycle(map[string]int{"secret/data/bar": 2})
+	assert.Equal(t, 2, writes())
+	controller.flush()
+	assert.Equal(t, 3, writes())
+	configMap, err := kubeClient.CoreV1().ConfigMaps("reloader").Get(ctx, "secret-versions", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"secret/data/foo":2,"secret/data/bar":2}`, configMap.Data[secretVersionsConfigMapKey])
+
+	// Nothing is left to save on the next shutdown
+	controller.flush()
+	assert.Equal(t, 3, writes())
+}
+
+func TestWithSecretVersionsSaveBatching(t *testing.T) {
+	_, err := WithSecretVersionsSaveBatching(0, 3)
+	assert.Error(t, err)
+	_, err = WithSecretVersionsSaveBatching(1, -1)
+	assert.Error(t, err)
+}
+
+func TestWithSecretVersionsConfigMap(t *testing.T) {
+	_, err := WithSecretVersionsConfigMap("", "secret-versions")
+	assert.Error(t, err)
+	_, err = WithSecretVersionsConfigMap("reloader", "Secret_Versions")
+	assert.Error(t, err)
+}
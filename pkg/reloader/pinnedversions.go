@@ -0,0 +1,180 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// PinnedVersionsAnnotationName pins the versions of secrets a workload runs, as a JSON map of secret paths
+// to versions (e.g. {"secret/data/app": 3}) in the workload's own metadata. Editing it reloads the workload,
+// and changes of the pinned secrets in Vault are not detected.
+const PinnedVersionsAnnotationName = "alpha.vault.security.banzaicloud.io/pinned-versions"
+
+// pinnedVersions keeps the pinned versions of the collected workloads, and the changes of them to reload
+type pinnedVersions struct {
+	mu       sync.Mutex
+	versions map[workload]map[string]int
+	pending  map[workload][]secretChange
+	// ready is signaled when a change is added to the pending ones
+	ready chan struct{}
+}
+
+func newPinnedVersions() *pinnedVersions {
+	return &pinnedVersions{
+		versions: make(map[workload]map[string]int),
+		pending:  make(map[workload][]secretChange),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// update stores the pinned versions of the workload, queueing the changed ones to reload it.
+// The versions of a workload collected for the first time, and unpinned secrets, are only stored.
+func (p *pinnedVersions) update(pinnedWorkload workload, versions map[string]int) []secretChange {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stored, known := p.versions[pinnedWorkload]
+	p.versions[pinnedWorkload] = versions
+	if !known {
+		return nil
+	}
+
+	var changes []secretChange
+	for secretPath, version := range versions {
+		if storedVersion := stored[secretPath]; storedVersion != version {
+			changes = append(changes, secretChange{path: secretPath, oldVersion: storedVersion, newVersion: version})
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(changes, func(a, b secretChange) int {
+		return strings.Compare(a.path, b.path)
+	})
+	p.pending[pinnedWorkload] = mergeSecretChanges(p.pending[pinnedWorkload], changes)
+	select {
+	case p.ready <- struct{}{}:
+	default:
+	}
+
+	return changes
+}
+
+// delete forgets the pinned versions of the workload, and its pending changes.
+func (p *pinnedVersions) delete(deleted workload) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.versions, deleted)
+	delete(p.pending, deleted)
+}
+
+// drain adds the pending changes to workloadsToReload, and clears them.
+func (p *pinnedVersions) drain(workloadsToReload map[workload][]secretChange) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for pinnedWorkload, changes := range p.pending {
+		workloadsToReload[pinnedWorkload] = mergeSecretChanges(workloadsToReload[pinnedWorkload], changes)
+		delete(p.pending, pinnedWorkload)
+	}
+}
+
+// parsePinnedVersions parses the pinned versions annotation of a workload.
+func parsePinnedVersions(annotations map[string]string) (map[string]int, error) {
+	value, ok := annotations[PinnedVersionsAnnotationName]
+	if !ok {
+		return nil, nil
+	}
+
+	var versions map[string]int
+	if err := json.Unmarshal([]byte(value), &versions); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", PinnedVersionsAnnotationName, err)
+	}
+	for secretPath, version := range versions {
+		if version <= 0 {
+			return nil, fmt.Errorf("invalid %s annotation: version of secret %s must be positive", PinnedVersionsAnnotationName, secretPath)
+		}
+	}
+
+	return versions, nil
+}
+
+// dropPinnedSecretPaths updates the pinned versions of the workload, returning the secret paths that are not pinned.
+// An invalid annotation is logged, and pins no secrets.
+func (c *Controller) dropPinnedSecretPaths(workload workload, workloadAnnotations map[string]string, secretPaths []string, logger *slog.Logger) []string {
+	versions, err := parsePinnedVersions(workloadAnnotations)
+	if err != nil {
+		logger.Warn(fmt.Errorf("failed to parse the pinned versions of %s %s/%s: %w", workload.kind, workload.namespace, workload.name, err).Error())
+	}
+
+	if changes := c.pinnedVersions.update(workload, versions); len(changes) > 0 {
+		logger.Info(fmt.Sprintf("Pinned versions of %s %s/%s changed, queueing it for reload", workload.kind, workload.namespace, workload.name), secretChangesAttr(changes))
+	}
+	if len(versions) == 0 {
+		return secretPaths
+	}
+
+	return slices.DeleteFunc(secretPaths, func(secretPath string) bool {
+		_, pinned := versions[secretPath]
+		return pinned
+	})
+}
+
+// runPinnedVersionReloader reloads the workloads whose pinned versions changed as soon as they change,
+// until the context is cancelled.
+func (c *Controller) runPinnedVersionReloader(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.pinnedVersions.ready:
+		}
+
+		c.reloadPinnedVersions(ctx)
+	}
+}
+
+// reloadPinnedVersions reloads the workloads whose pinned versions changed. While reloads are paused,
+// the changes are kept for the next reloader cycle.
+func (c *Controller) reloadPinnedVersions(ctx context.Context) {
+	ctx = WithCorrelationID(ctx, string(uuid.NewUUID()))
+	logger := c.logger.With(
+		slog.String("worker", "reloader"),
+		slog.String("correlation_id", correlationIDFromContext(ctx)),
+	)
+
+	if c.reloadsPaused(ctx, logger) {
+		return
+	}
+
+	workloadsToReload := make(map[workload][]secretChange)
+	c.pinnedVersions.drain(workloadsToReload)
+	c.deferOutsideReloadWindow(workloadsToReload, logger)
+	if len(workloadsToReload) == 0 {
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Reloading %d workloads with changed pinned versions", len(workloadsToReload)))
+	c.reloadWorkloads(ctx, workloadsToReload, logger)
+}
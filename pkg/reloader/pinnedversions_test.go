@@ -0,0 +1,98 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHandleObjectPinnedVersions(t *testing.T) {
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo,secret/data/bar",
+	})
+	deployment.Annotations = map[string]string{PinnedVersionsAnnotationName: `{"secret/data/foo": 1}`}
+	controller := newTestController(deployment)
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+
+	// The pinned secret is not checked in Vault, and the first pinned versions are only stored
+	controller.handleObject(deployment)
+	assert.Equal(t, []string{"secret/data/bar"}, controller.workloadSecrets.GetWorkloadSecretsMap()[testWorkload])
+	assert.Empty(t, controller.pinnedVersions.pending)
+
+	// Collecting the workload again without editing the pinned versions doesn't reload it
+	controller.handleObject(deployment.DeepCopy())
+	assert.Empty(t, controller.pinnedVersions.pending)
+
+	edited := deployment.DeepCopy()
+	edited.Annotations[PinnedVersionsAnnotationName] = `{"secret/data/foo": 2}`
+	controller.handleObject(edited)
+	assert.Equal(t, map[workload][]secretChange{
+		testWorkload: {{path: "secret/data/foo", oldVersion: 1, newVersion: 2}},
+	}, controller.pinnedVersions.pending)
+
+	controller.reloadPinnedVersions(context.Background())
+	assert.Empty(t, controller.pinnedVersions.pending)
+	reloaded, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", reloaded.Spec.Template.Annotations[ReloadCountAnnotationName])
+	assert.Equal(t, `{"secret/data/foo":2}`, reloaded.Spec.Template.Annotations[SecretVersionsAnnotationName])
+
+	// Unpinning the secret checks it in Vault again, without reloading the workload
+	unpinned := edited.DeepCopy()
+	delete(unpinned.Annotations, PinnedVersionsAnnotationName)
+	controller.handleObject(unpinned)
+	assert.Equal(t, []string{"secret/data/bar", "secret/data/foo"}, controller.workloadSecrets.GetWorkloadSecretsMap()[testWorkload])
+	assert.Empty(t, controller.pinnedVersions.pending)
+}
+
+func TestHandleObjectPinnedVersionsPaused(t *testing.T) {
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+	})
+	deployment.Annotations = map[string]string{PinnedVersionsAnnotationName: `{"secret/data/foo": 1}`}
+	controller := newTestController(deployment)
+	controller.handleObject(deployment)
+	controller.runtimeFlags.Store(&runtimeFlags{paused: true})
+
+	edited := deployment.DeepCopy()
+	edited.Annotations[PinnedVersionsAnnotationName] = `{"secret/data/foo": 2}`
+	controller.handleObject(edited)
+
+	// The change is kept while reloads are paused
+	controller.reloadPinnedVersions(context.Background())
+	assert.Len(t, controller.pinnedVersions.pending, 1)
+}
+
+func TestParsePinnedVersions(t *testing.T) {
+	versions, err := parsePinnedVersions(map[string]string{PinnedVersionsAnnotationName: `{"secret/data/foo": 3, "secret/data/bar": 1}`})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"secret/data/foo": 3, "secret/data/bar": 1}, versions)
+
+	versions, err = parsePinnedVersions(nil)
+	require.NoError(t, err)
+	assert.Nil(t, versions)
+
+	for _, value := range []string{"", "secret/data/foo", `{"secret/data/foo": "3"}`, `{"secret/data/foo": 0}`} {
+		_, err := parsePinnedVersions(map[string]string{PinnedVersionsAnnotationName: value})
+		assert.Error(t, err, value)
+	}
+}
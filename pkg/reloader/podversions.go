@@ -0,0 +1,82 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// errPodsUpToDate is returned by reloadWorkload when the reload is skipped, as all pods of the workload
+// already run the new versions of the changed secrets
+var errPodsUpToDate = errors.New("all pods already run the new secret versions")
+
+// WithPodVersionCheck enables checking the secret versions the running pods of a workload were created with
+// (from the SecretVersionsAnnotationName annotation of an earlier reload) before reloading it, skipping
+// the reload if all of them already run the new versions of the changed secrets, e.g. as they were
+// restarted out-of-band.
+func WithPodVersionCheck(enabled bool) Option {
+	return func(c *Controller) {
+		c.podVersionCheck = enabled
+	}
+}
+
+// podsRunSecretVersions reports whether the workload has running pods, and all of them run at least
+// the new versions of the changed secrets. Changes that didn't increase the version of a secret
+// (e.g. a referenced key disappeared) are never considered to be run already.
+func (c *Controller) podsRunSecretVersions(ctx context.Context, accessor WorkloadAccessor, changes []secretChange) (bool, error) {
+	// The pods of batch workloads are not replaced by reloads, and may not be selectable (e.g. of CronJobs)
+	if len(changes) == 0 || isBatchKind(accessor.Kind()) {
+		return false, nil
+	}
+	for _, change := range changes {
+		if change.newVersion <= change.oldVersion {
+			return false, nil
+		}
+	}
+
+	pods, err := c.listWorkloadPods(ctx, accessor)
+	if err != nil {
+		return false, err
+	}
+
+	running := 0
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		running++
+
+		versionsJSON, ok := pod.Annotations[SecretVersionsAnnotationName]
+		if !ok {
+			return false, nil
+		}
+		var versions map[string]int
+		if err := json.Unmarshal([]byte(versionsJSON), &versions); err != nil {
+			return false, fmt.Errorf("invalid %s annotation of pod %s: %w", SecretVersionsAnnotationName, pod.Name, err)
+		}
+		for _, change := range changes {
+			if versions[change.path] < change.newVersion {
+				return false, nil
+			}
+		}
+	}
+
+	return running > 0, nil
+}
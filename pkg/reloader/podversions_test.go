@@ -0,0 +1,131 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestVersionedPod(name, secretVersions string, phase corev1.PodPhase) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "test"}},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+	if secretVersions != "" {
+		pod.Annotations = map[string]string{SecretVersionsAnnotationName: secretVersions}
+	}
+
+	return pod
+}
+
+func TestReloadWorkloadsPodVersionCheck(t *testing.T) {
+	deployment := newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"})
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	changes := []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 2}}
+
+	t.Run("pods already updated", func(t *testing.T) {
+		controller := newTestController(
+			deployment,
+			newTestVersionedPod("test-1", `{"secret/data/foo":2,"secret/data/bar":1}`, corev1.PodRunning),
+			newTestVersionedPod("test-2", `{"secret/data/foo":3}`, corev1.PodRunning),
+			// Finished pods don't run the old version anymore
+			newTestVersionedPod("test-3", `{"secret/data/foo":1}`, corev1.PodSucceeded),
+		)
+		WithPodVersionCheck(true)(controller)
+
+		workloadsToReload := map[workload][]secretChange{testWorkload: changes}
+		errs := controller.reloadWorkloads(context.Background(), workloadsToReload, controller.logger)
+		require.Empty(t, errs)
+		assert.Empty(t, workloadsToReload)
+
+		current, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.NotContains(t, current.Spec.Template.Annotations, ReloadCountAnnotationName)
+	})
+
+	t.Run("pod running the old version", func(t *testing.T) {
+		controller := newTestController(
+			deployment,
+			newTestVersionedPod("test-1", `{"secret/data/foo":2}`, corev1.PodRunning),
+			newTestVersionedPod("test-2", `{"secret/data/foo":1}`, corev1.PodRunning),
+		)
+		WithPodVersionCheck(true)(controller)
+
+		workloadsToReload := map[workload][]secretChange{testWorkload: changes}
+		errs := controller.reloadWorkloads(context.Background(), workloadsToReload, controller.logger)
+		require.Empty(t, errs)
+		assert.Len(t, workloadsToReload, 1)
+
+		current, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "1", current.Spec.Template.Annotations[ReloadCountAnnotationName])
+	})
+}
+
+func TestPodsRunSecretVersions(t *testing.T) {
+	deployment := newTestDeployment("test", nil)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+	changes := []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 2}}
+
+	tests := []struct {
+		name    string
+		pods    []*corev1.Pod
+		changes []secretChange
+		want    bool
+	}{
+		{name: "no pods", changes: changes},
+		{
+			name:    "pod without versions",
+			pods:    []*corev1.Pod{newTestVersionedPod("test-1", "", corev1.PodRunning)},
+			changes: changes,
+		},
+		{
+			name:    "pod missing the changed secret",
+			pods:    []*corev1.Pod{newTestVersionedPod("test-1", `{"secret/data/bar":5}`, corev1.PodRunning)},
+			changes: changes,
+		},
+		{
+			name:    "same version",
+			pods:    []*corev1.Pod{newTestVersionedPod("test-1", `{"secret/data/foo":1}`, corev1.PodRunning)},
+			changes: []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 1}},
+		},
+		{
+			name:    "pending pod updated",
+			pods:    []*corev1.Pod{newTestVersionedPod("test-1", `{"secret/data/foo":2}`, corev1.PodPending)},
+			changes: changes,
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := newTestController()
+			for _, pod := range tt.pods {
+				_, err := controller.kubeClient.CoreV1().Pods("default").Create(context.Background(), pod, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			upToDate, err := controller.podsRunSecretVersions(context.Background(), &deploymentAccessor{deployment}, tt.changes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, upToDate)
+		})
+	}
+}
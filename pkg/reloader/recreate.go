@@ -0,0 +1,59 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// AllowRecreateReloadAnnotationName is the pod template annotation of Deployments with the Recreate strategy
+// allowing to reload them, even though all of their pods are terminated before new ones are created.
+const AllowRecreateReloadAnnotationName = "secrets-reloader.security.bank-vaults.io/allow-recreate-reload"
+
+// errRecreateNotAllowed is returned by reloadWorkload when the reload is skipped, as the workload
+// is a Deployment with the Recreate strategy that didn't opt in to be reloaded
+var errRecreateNotAllowed = errors.New("reloading Deployments with the Recreate strategy is not allowed")
+
+// WithRecreateReloadOptIn requires Deployments with the Recreate strategy to opt in to be reloaded
+// with the AllowRecreateReloadAnnotationName annotation, as reloading them causes downtime.
+// Without it, they are reloaded with a warning.
+func WithRecreateReloadOptIn(enabled bool) Option {
+	return func(c *Controller) {
+		c.recreateReloadOptIn = enabled
+	}
+}
+
+// checkRecreateStrategy warns about reloading a Deployment with the Recreate strategy, or returns
+// errRecreateNotAllowed if it didn't opt in while that's required.
+func (c *Controller) checkRecreateStrategy(accessor WorkloadAccessor, logger *slog.Logger) error {
+	deployment, ok := accessor.(*deploymentAccessor)
+	if !ok || deployment.Spec.Strategy.Type != appsv1.RecreateDeploymentStrategyType {
+		return nil
+	}
+	if accessor.GetPodTemplate().Annotations[AllowRecreateReloadAnnotationName] == "true" {
+		return nil
+	}
+	if c.recreateReloadOptIn {
+		return errRecreateNotAllowed
+	}
+
+	logger.Warn(fmt.Sprintf("Deployment %s/%s uses the Recreate strategy, all of its pods are terminated before new ones are created, "+
+		"annotate its pod template with %s: \"true\" to acknowledge it", deployment.Namespace, deployment.Name, AllowRecreateReloadAnnotationName))
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReloadWorkloadsRecreateStrategy(t *testing.T) {
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	changes := []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 2}}
+	newRecreateDeployment := func(annotations map[string]string) *appsv1.Deployment {
+		deployment := newTestDeployment("test", annotations)
+		deployment.Spec.Strategy.Type = appsv1.RecreateDeploymentStrategyType
+		return deployment
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		optIn       bool
+		reloaded    bool
+		warning     string
+	}{
+		{
+			name:     "warned",
+			reloaded: true,
+			warning:  "Deployment default/test uses the Recreate strategy",
+		},
+		{
+			name:        "acknowledged",
+			annotations: map[string]string{AllowRecreateReloadAnnotationName: "true"},
+			reloaded:    true,
+		},
+		{
+			name:    "opt-in required",
+			optIn:   true,
+			warning: "skipping reload until its pod template is annotated with " + AllowRecreateReloadAnnotationName,
+		},
+		{
+			name:        "opted in",
+			annotations: map[string]string{AllowRecreateReloadAnnotationName: "true"},
+			optIn:       true,
+			reloaded:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := newTestController(newRecreateDeployment(tt.annotations))
+			WithRecreateReloadOptIn(tt.optIn)(controller)
+			var logs bytes.Buffer
+			controller.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+			workloadsToReload := map[workload][]secretChange{testWorkload: changes}
+			errs := controller.reloadWorkloads(context.Background(), workloadsToReload, controller.logger)
+			require.Empty(t, errs)
+
+			current, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+			require.NoError(t, err)
+			if tt.reloaded {
+				assert.Len(t, workloadsToReload, 1)
+				assert.Equal(t, "1", current.Spec.Template.Annotations[ReloadCountAnnotationName])
+			} else {
+				assert.Empty(t, workloadsToReload)
+				assert.NotContains(t, current.Spec.Template.Annotations, ReloadCountAnnotationName)
+			}
+			if tt.warning != "" {
+				assert.Contains(t, logs.String(), tt.warning)
+			} else {
+				assert.NotContains(t, logs.String(), "level=WARN")
+			}
+		})
+	}
+
+	// Deployments with the RollingUpdate strategy are reloaded without a warning
+	controller := newTestController(newTestDeployment("test", nil))
+	WithRecreateReloadOptIn(true)(controller)
+	var logs bytes.Buffer
+	controller.logger = slog.New(slog.NewTextHandler(&logs, nil))
+	errs := controller.reloadWorkloads(context.Background(), map[workload][]secretChange{testWorkload: changes}, controller.logger)
+	require.Empty(t, errs)
+	assert.NotContains(t, logs.String(), "Recreate")
+}
+
+func TestReloadRecreateStrategyAfterOptIn(t *testing.T) {
+	ctx := context.Background()
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+	})
+	deployment.Spec.Strategy.Type = appsv1.RecreateDeploymentStrategyType
+	controller := newTestController(deployment)
+	WithRecreateReloadOptIn(true)(controller)
+	controller.handleObject(deployment)
+	controller.secretVersions["secret/data/foo"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 2}}
+	cycle := func() int {
+		return controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger).WorkloadsReloaded
+	}
+
+	// The change is kept pending while the Deployment didn't opt in
+	assert.Equal(t, 0, cycle())
+	assert.Equal(t, 0, cycle())
+
+	current, err := controller.kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	current.Spec.Template.Annotations[AllowRecreateReloadAnnotationName] = "true"
+	_, err = controller.kubeClient.AppsV1().Deployments("default").Update(ctx, current, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	// Once annotated, the Deployment is reloaded for the change in the next cycle, and only then
+	assert.Equal(t, 1, cycle())
+	assert.Equal(t, 0, cycle())
+	current, err = controller.kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", current.Spec.Template.Annotations[ReloadCountAnnotationName])
+	assert.Equal(t, "secret/data/foo", current.Spec.Template.Annotations[ReloadTriggerPathsAnnotationName])
+}
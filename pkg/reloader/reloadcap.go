@@ -0,0 +1,99 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// reloadCap limits the number of workloads reloaded in a cycle, keeping the changes of the workloads
+// over the limit pending until the next cycles.
+type reloadCap struct {
+	max int
+
+	mu sync.Mutex
+	// pending map[Workload]cappedReload, the reloads deferred to the next cycles
+	pending map[workload]cappedReload
+}
+
+// cappedReload is a reload deferred by the reload cap, with the time its first change was detected at
+type cappedReload struct {
+	changes []secretChange
+	since   time.Time
+}
+
+// WithMaxReloadsPerCycle limits the number of workloads reloaded in a cycle, deferring the reload of the rest
+// to the next cycles, the ones with the oldest pending changes first. Zero, the default, disables the limit.
+func WithMaxReloadsPerCycle(limit int) (Option, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("invalid max reloads per cycle %d, must not be negative", limit)
+	}
+
+	return func(c *Controller) {
+		if limit > 0 {
+			c.reloadCap = &reloadCap{max: limit, pending: make(map[workload]cappedReload)}
+		}
+	}, nil
+}
+
+// capReloads adds the reloads deferred in the previous cycles to workloadsToReload, and keeps only the
+// ones with the oldest pending changes up to the limit in it, deferring the rest to the next cycles.
+func (c *Controller) capReloads(workloadsToReload map[workload][]secretChange, logger *slog.Logger) {
+	if c.reloadCap == nil {
+		return
+	}
+
+	c.reloadCap.mu.Lock()
+	defer c.reloadCap.mu.Unlock()
+
+	now := c.clock.Now()
+	reloads := make(map[workload]cappedReload, len(workloadsToReload)+len(c.reloadCap.pending))
+	for workload, changes := range workloadsToReload {
+		reloads[workload] = cappedReload{changes: changes, since: now}
+	}
+	// Workloads deleted since their reload was deferred are not reloaded
+	workloadSecrets := c.workloadSecrets.GetWorkloadSecretsMap()
+	for workload, pending := range c.reloadCap.pending {
+		if _, ok := workloadSecrets[workload]; ok {
+			reloads[workload] = cappedReload{changes: mergeSecretChanges(pending.changes, reloads[workload].changes), since: pending.since}
+		}
+		delete(c.reloadCap.pending, workload)
+	}
+
+	workloads := make([]workload, 0, len(reloads))
+	for workload, reload := range reloads {
+		workloads = append(workloads, workload)
+		workloadsToReload[workload] = reload.changes
+	}
+	if len(workloads) <= c.reloadCap.max {
+		return
+	}
+
+	slices.SortFunc(workloads, func(a, b workload) int {
+		if c := reloads[a].since.Compare(reloads[b].since); c != 0 {
+			return c
+		}
+		return compareWorkloads(a, b)
+	})
+	for _, deferred := range workloads[c.reloadCap.max:] {
+		c.reloadCap.pending[deferred] = reloads[deferred]
+		delete(workloadsToReload, deferred)
+	}
+	logger.Info(fmt.Sprintf("Reloading %d of %d workloads in this cycle, deferring the rest to the next cycles", c.reloadCap.max, len(workloads)))
+}
@@ -0,0 +1,81 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestReloadChangedWorkloadsMaxReloadsPerCycle(t *testing.T) {
+	controller := newTestController(
+		newTestDeployment("a", nil),
+		newTestDeployment("b", nil),
+		newTestDeployment("c", nil),
+	)
+	fakeClock := testingclock.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	controller.clock = fakeClock
+	option, err := WithMaxReloadsPerCycle(2)
+	require.NoError(t, err)
+	option(controller)
+
+	for _, name := range []string{"a", "b"} {
+		controller.workloadSecrets.Store(workload{name: name, namespace: "default", kind: DeploymentKind}, []string{"secret/data/bar", "secret/data/foo"})
+	}
+	controller.workloadSecrets.Store(workload{name: "c", namespace: "default", kind: DeploymentKind}, []string{"secret/data/foo"})
+	controller.secretVersions["secret/data/foo"] = 1
+	controller.secretVersions["secret/data/bar"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 2, "secret/data/bar": 1}}
+	reloadCounts := func() map[string]string {
+		counts := make(map[string]string)
+		for _, name := range []string{"a", "b", "c"} {
+			deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), name, metav1.GetOptions{})
+			require.NoError(t, err)
+			counts[name] = deployment.Spec.Template.Annotations[ReloadCountAnnotationName]
+		}
+		return counts
+	}
+	reloadChangedWorkloads := func() CycleSummary {
+		return controller.reloadChangedWorkloads(context.Background(), vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+	}
+
+	// Only the first workloads up to the limit are reloaded
+	summary := reloadChangedWorkloads()
+	assert.Equal(t, 2, summary.WorkloadsReloaded)
+	assert.Equal(t, map[string]string{"a": "1", "b": "1", "c": ""}, reloadCounts())
+
+	// The deferred workload is reloaded first, before the ones with newer changes
+	fakeClock.Step(time.Minute)
+	vaultClient.setVersion("secret/data/bar", 2)
+	summary = reloadChangedWorkloads()
+	assert.Equal(t, 2, summary.WorkloadsReloaded)
+	assert.Equal(t, map[string]string{"a": "2", "b": "1", "c": "1"}, reloadCounts())
+
+	// Deferred workloads are reloaded without new changes
+	fakeClock.Step(time.Minute)
+	summary = reloadChangedWorkloads()
+	assert.Equal(t, 1, summary.WorkloadsReloaded)
+	assert.Equal(t, map[string]string{"a": "2", "b": "2", "c": "1"}, reloadCounts())
+	assert.Empty(t, controller.reloadCap.pending)
+
+	_, err = WithMaxReloadsPerCycle(-1)
+	assert.Error(t, err)
+}
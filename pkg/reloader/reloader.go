@@ -16,144 +16,580 @@ package reloader
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/util/retry"
 )
 
-func (c *Controller) runReloader(ctx context.Context) {
-	reloaderLogger := c.logger.With(slog.String("worker", "reloader"))
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context carrying an ID, e.g. the ID of the request that triggered
+// the reload cycle, that the logs and events of the cycle run with the context are correlated with.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+func correlationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// runReloader checks the given secrets for changes and reloads the workloads using them,
+// returning the summary of the cycle.
+func (c *Controller) runReloader(ctx context.Context, secretWorkloads map[string][]workload) (summary CycleSummary) {
+	// Correlate the logs and events of the cycle, generating an ID if it wasn't triggered with one
+	if correlationIDFromContext(ctx) == "" {
+		ctx = WithCorrelationID(ctx, string(uuid.NewUUID()))
+	}
+	reloaderLogger := c.logger.With(
+		slog.String("worker", "reloader"),
+		slog.String("correlation_id", correlationIDFromContext(ctx)),
+	)
 	reloaderLogger.Info("Reloader started")
 
-	if len(c.workloadSecrets.GetWorkloadSecretsMap()) == 0 {
+	// Keep a summary of the cycle for debugging
+	startedAt := c.clock.Now()
+	defer func() {
+		summary.Timestamp = startedAt
+		summary.CorrelationID = correlationIDFromContext(ctx)
+		c.cycleHistory.add(summary)
+		// Let monitoring detect a wedged loop, which still serves the health checks
+		c.metrics.lastCycleTimestamp.Set(float64(c.clock.Now().Unix()))
+	}()
+
+	workloadSecrets := c.workloadSecrets.GetWorkloadSecretsMap()
+	c.metrics.trackedWorkloads.Set(float64(len(workloadSecrets)))
+	c.metrics.updateWorkloadInfo(workloadSecrets)
+
+	if len(secretWorkloads) == 0 {
 		reloaderLogger.Info("No workloads to reload")
 		return
 	}
 
-	err := c.initVaultClient()
+	vaultClient, err := c.getVaultClient(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to initialize Vault client: %w", err)
+		reloaderLogger.Error(err.Error())
+		c.metrics.vaultSealed.Set(vaultSealedUnknown)
+		summary.Errors = append(summary.Errors, err.Error())
+		return
+	}
+
+	sealed, err := c.checkVaultSealed(vaultClient.Sys(), reloaderLogger)
 	if err != nil {
-		reloaderLogger.Error(fmt.Errorf("failed to initialize Vault client: %w", err).Error())
+		err = fmt.Errorf("failed to get Vault seal status: %w", err)
+		reloaderLogger.Error(err.Error())
+		summary.Errors = append(summary.Errors, err.Error())
 		return
 	}
+	if sealed {
+		return
+	}
+
+	summary = c.reloadChangedWorkloads(ctx, vaultClient.Logical(), secretWorkloads, reloaderLogger)
+
+	// Persist the checked versions, so they survive restarts
+	if err := c.saveSecretVersions(ctx, reloaderLogger); err != nil {
+		reloaderLogger.Warn(err.Error())
+	}
+
+	return summary
+}
+
+// reloadChangedWorkloads reloads the workloads using the given secrets that have changed since the last check,
+// returning the summary of the cycle.
+func (c *Controller) reloadChangedWorkloads(ctx context.Context, vaultClient vaultSecretReader, secretWorkloads map[string][]workload, logger *slog.Logger) CycleSummary {
+	summary := CycleSummary{}
+
+	// Changes are detected once resumed or scaling is finished, as the stored versions are not updated until then
+	if c.reloadsPaused(ctx, logger) {
+		return summary
+	}
+
+	// Compare the currently used secrets' version with the one stored in the secretVersions map
+	workloadsToReload, readErrs := c.checkSecretVersions(ctx, vaultClient, secretWorkloads, logger)
+	c.skipUpToDateWorkloads(workloadsToReload, logger)
+	summary.SecretsChecked = len(secretWorkloads)
+	summary.SecretsChanged = countChangedSecrets(workloadsToReload)
+	c.filterByReloadThreshold(workloadsToReload, logger)
+	// Changes of pinned versions kept while reloads were paused
+	c.pinnedVersions.drain(workloadsToReload)
+	c.deferOutsideReloadWindow(workloadsToReload, logger)
+	c.coalesceReloads(ctx, workloadsToReload, logger)
+	c.capReloads(workloadsToReload, logger)
+
+	reloadErrs := c.reloadWorkloads(ctx, workloadsToReload, logger)
+	summary.WorkloadsReloaded = len(workloadsToReload) - len(reloadErrs)
+	for _, err := range append(readErrs, reloadErrs...) {
+		summary.Errors = append(summary.Errors, err.Error())
+	}
+
+	// Remove secrets from the secretVersions map that are not used by any workload anymore
+	c.pruneSecretVersions(c.workloadSecrets.GetSecretWorkloadsMap(), c.workloadSecrets.GetWorkloadSecretsMap())
+
+	if len(workloadsToReload) == 0 {
+		logger.Info("No workloads to reload")
+	}
+
+	return summary
+}
+
+// countChangedSecrets returns the number of distinct secrets changed across the workloads.
+func countChangedSecrets(workloadsToReload map[workload][]secretChange) int {
+	changed := make(map[string]struct{})
+	for _, changes := range workloadsToReload {
+		for _, change := range changes {
+			changed[change.path] = struct{}{}
+		}
+	}
+
+	return len(changed)
+}
+
+// secretChange is a change of a secret's version detected in Vault
+type secretChange struct {
+	path       string
+	oldVersion int
+	newVersion int
+}
 
-	// Create a secretWorkloads map and compare the currently used secrets' version
-	// with the one stored in the secretVersions map, while creating a new secretVersions map
-	workloadsToReload := make(map[workload]bool)
-	newSecretVersions := make(map[string]int)
-	var wg sync.WaitGroup
+// checkSecretVersions gets the current version of the given secrets from Vault,
+// compares them with the ones stored in the secretVersions map, updates the map
+// and returns the workloads using secrets that have changed, along with the changes
+// and the errors of the secrets that could not be read.
+func (c *Controller) checkSecretVersions(ctx context.Context, vaultClient vaultSecretReader, secretWorkloads map[string][]workload, logger *slog.Logger) (map[workload][]secretChange, []error) {
+	workloadsToReload := make(map[workload][]secretChange)
+	unreadableSecrets := make(map[workload][]string)
+	var readErrs []error
+	var secretKeys, referencedKeys map[workload]map[string][]string
+	if c.subkeyAwareReload || c.missingKeyDetection {
+		referencedKeys = c.workloadSecrets.GetSecretKeys()
+	}
+	if c.subkeyAwareReload {
+		secretKeys = referencedKeys
+	}
+	configs := c.workloadSecrets.GetConfigs()
+	secretRoles := c.getSecretVaultRoles(secretWorkloads)
+	readSecrets := make(map[string]readSecret)
 	var mu sync.Mutex
-	for secretPath, workloads := range c.workloadSecrets.GetSecretWorkloadsMap() {
-		wg.Add(1)
-		go func(secretPath string, workloads []workload) {
-			defer wg.Done()
-			reloaderLogger.Debug(fmt.Sprintf("Checking secret: %s", secretPath))
-
-			// Get current secret version
-			currentVersion, err := getSecretVersionFromVault(c.vaultClient.Logical(), secretPath)
-			if err != nil {
-				c.handleSecretError(err, secretPath, reloaderLogger)
-				return
+	// Secrets are read by a bounded number of workers, not to flood Vault with reads
+	runWorkerPool(c.reloaderConcurrency, secretWorkloads, func(secretPath string, workloads []workload) {
+		logger.Debug(fmt.Sprintf("Checking secret: %s", secretPath))
+
+		// Get current secret version
+		currentVersion, keyHashes, err := c.readSecretVersionWithRole(ctx, vaultClient, secretRoles[secretPath], secretPath, logger)
+		var deletedErr ErrSecretDeleted
+		deleted := errors.As(err, &deletedErr)
+		if deleted {
+			// The deleted version is still the current one, its workloads are reloaded as it got deleted
+			currentVersion, err = deletedErr.version, nil
+		}
+		if err != nil {
+			c.handleSecretError(err, secretPath, logger)
+			mu.Lock()
+			readErrs = append(readErrs, fmt.Errorf("%s: %w", secretPath, err))
+			for _, workload := range workloads {
+				unreadableSecrets[workload] = append(unreadableSecrets[workload], secretPath)
 			}
+			mu.Unlock()
+			return
+		}
 
+		storedVersion, storedKeyHashes := c.swapSecretVersion(secretPath, currentVersion, keyHashes)
+		deletionChanged := c.swapSecretDeletion(secretPath, deleted) && storedVersion != 0
+		keysDisappeared := c.checkReferencedKeys(workloads, secretPath, referencedKeys, storedKeyHashes, keyHashes, logger)
+		if c.changeDetection == ChangeDetectionWorkloadHash {
+			// Compared per workload once all secrets are read
+			mu.Lock()
+			readSecrets[secretPath] = readSecret{oldVersion: storedVersion, version: currentVersion, keyHashes: keyHashes}
+			mu.Unlock()
+			return
+		}
+
+		// Compare secret versions
+		switch storedVersion {
+		case 0:
+			logger.Debug(fmt.Sprintf("Secret %s not found in secretVersions map, creating it", secretPath))
+		case currentVersion:
+			if deletionChanged {
+				logSecretDeletion(secretPath, currentVersion, deleted, logger)
+			} else {
+				logger.Debug(fmt.Sprintf("Secret %s did not change", secretPath))
+			}
+			mu.Lock()
+			for _, workload := range workloads {
+				if deletionChanged || keysDisappeared[workload] {
+					workloadsToReload[workload] = append(workloadsToReload[workload], secretChange{path: secretPath, oldVersion: storedVersion, newVersion: currentVersion})
+				}
+			}
+			mu.Unlock()
+		default:
+			logger.Debug(fmt.Sprintf("Secret version stored: %d current: %d", storedVersion, currentVersion))
+			change := secretChange{path: secretPath, oldVersion: storedVersion, newVersion: currentVersion}
 			mu.Lock()
-			defer mu.Unlock()
-
-			// Compare secret versions
-			switch c.secretVersions[secretPath] {
-			case 0:
-				reloaderLogger.Debug(fmt.Sprintf("Secret %s not found in secretVersions map, creating it", secretPath))
-			case currentVersion:
-				reloaderLogger.Debug(fmt.Sprintf("Secret %s did not change", secretPath))
-			default:
-				reloaderLogger.Debug(fmt.Sprintf("Secret version stored: %d current: %d", c.secretVersions[secretPath], currentVersion))
-				for _, workload := range workloads {
-					workloadsToReload[workload] = true
+			for _, workload := range workloads {
+				keys := secretKeys[workload][secretPath]
+				if !referencedKeysChanged(keys, storedKeyHashes, keyHashes) {
+					logger.Debug(fmt.Sprintf("None of the keys %v of secret %s used by %s changed", keys, secretPath, workload))
+					continue
+				}
+				minDelta := c.minVersionDelta
+				if override := configs[workload].minVersionDelta; override > 0 {
+					minDelta = override
 				}
+				workloadChange, ok := c.versionDeltaReached(workload, change, minDelta)
+				if !ok && !keysDisappeared[workload] {
+					logger.Debug(fmt.Sprintf("Version of secret %s used by %s didn't increase by the minimum delta %d yet", secretPath, workload, minDelta))
+					continue
+				}
+				workloadsToReload[workload] = append(workloadsToReload[workload], workloadChange)
 			}
+			mu.Unlock()
+		}
+	})
 
-			newSecretVersions[secretPath] = currentVersion
-		}(secretPath, workloads)
+	if c.changeDetection == ChangeDetectionWorkloadHash {
+		c.compareWorkloadHashes(secretWorkloads, readSecrets, secretKeys, workloadsToReload, logger)
 	}
-	// wait for secret version checking to complete
-	wg.Wait()
 
-	// Reloading workloads
-	wg = sync.WaitGroup{} // Reset the WaitGroup
-	for workloadToReload := range workloadsToReload {
-		wg.Add(1)
-		go func(workloadToReload workload) {
-			defer wg.Done()
-			reloaderLogger.Info(fmt.Sprintf("Reloading workload: %s", workloadToReload))
+	for workload, changes := range workloadsToReload {
+		slices.SortFunc(changes, func(a, b secretChange) int {
+			return strings.Compare(a.path, b.path)
+		})
 
-			err := c.reloadWorkload(ctx, workloadToReload)
-			if err != nil {
-				reloaderLogger.Error(fmt.Errorf("failed reloading workload: %s: %w", workloadToReload, err).Error())
-			}
-		}(workloadToReload)
+		// A secret that can't be read doesn't prevent reloading for the ones that changed
+		if unreadable := unreadableSecrets[workload]; len(unreadable) > 0 {
+			slices.Sort(unreadable)
+			logger.Warn(fmt.Sprintf("Some secrets of workload %s could not be checked, reloading it for the changed ones: %v", workload, unreadable))
+		}
 	}
-	// wait for workload reloading to complete
-	wg.Wait()
 
-	// Replace secretVersions map with the new one so we don't keep deleted secrets in the map
-	c.secretVersions = newSecretVersions
-	reloaderLogger.Debug(fmt.Sprintf("Updated secretVersions map: %#v", newSecretVersions))
+	return workloadsToReload, readErrs
+}
 
-	if len(workloadsToReload) == 0 {
-		reloaderLogger.Info("No workloads to reload")
+// reloadWorkloads reloads the given workloads, returning the errors of the ones that failed.
+// The workloads depending on them are added to workloadsToReload, if enabled.
+func (c *Controller) reloadWorkloads(ctx context.Context, workloadsToReload map[workload][]secretChange, logger *slog.Logger) []error {
+	c.addDependentWorkloads(workloadsToReload, logger)
+	c.skipReadonlyReloads(workloadsToReload, logger)
+	c.breakReloadLoops(workloadsToReload, logger)
+
+	var errs []error
+	var results []reloadResult
+	var skipped []workload
+	var mu sync.Mutex
+	kindSlots := c.newKindReloadSlots()
+	runWorkerPool(c.reloaderConcurrency, workloadsToReload, func(workloadToReload workload, changes []secretChange) {
+		if slots, ok := kindSlots[workloadToReload.kind]; ok {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+		}
+		logger.Info(fmt.Sprintf("Reloading workload: %s", workloadToReload), secretChangesAttr(changes))
+
+		err := c.reloadWorkload(ctx, workloadToReload, changes)
+		if errors.Is(err, errPodsUpToDate) {
+			logger.Info(fmt.Sprintf("All pods of workload %s already run the new secret versions, skipping reload", workloadToReload))
+			mu.Lock()
+			skipped = append(skipped, workloadToReload)
+			mu.Unlock()
+			return
+		}
+		if errors.Is(err, errRecreateNotAllowed) {
+			logger.Warn(fmt.Sprintf("Deployment %s/%s uses the Recreate strategy, skipping reload until its pod template is annotated with %s: \"true\"",
+				workloadToReload.namespace, workloadToReload.name, AllowRecreateReloadAnnotationName))
+			// The changes were already stored, so they are kept pending, and reloaded in a later cycle once allowed
+			c.pendingReloadsMu.Lock()
+			c.pendingReloads[workloadToReload] = mergeSecretChanges(c.pendingReloads[workloadToReload], changes)
+			c.pendingReloadsMu.Unlock()
+			mu.Lock()
+			skipped = append(skipped, workloadToReload)
+			mu.Unlock()
+			return
+		}
+		if err != nil {
+			reloadErr := fmt.Errorf("failed reloading workload: %s: %w", workloadToReload, err)
+			logger.Error(reloadErr.Error())
+			mu.Lock()
+			errs = append(errs, reloadErr)
+			mu.Unlock()
+		} else {
+			c.recordReload(workloadToReload)
+		}
+
+		c.emitReloadEvent(ctx, workloadToReload, changes, err)
+		mu.Lock()
+		results = append(results, reloadResult{workload: workloadToReload, changes: changes, err: err})
+		mu.Unlock()
+	})
+	for _, workload := range skipped {
+		// Not counted as reloaded
+		delete(workloadsToReload, workload)
 	}
+	c.recordKubernetesEvents(results)
+
+	return errs
 }
 
-func (c *Controller) reloadWorkload(ctx context.Context, workload workload) error {
-	// Reload object based on its type
-	switch workload.kind {
-	case DeploymentKind:
-		deployment, err := c.kubeClient.AppsV1().Deployments(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
-		if err != nil {
-			return err
+// secretChangesAttr returns the old and new versions of the changed secrets as a log attribute.
+func secretChangesAttr(changes []secretChange) slog.Attr {
+	versions := make([]any, 0, len(changes))
+	for _, change := range changes {
+		versions = append(versions, slog.Group(change.path,
+			slog.Int("old", change.oldVersion),
+			slog.Int("new", change.newVersion),
+		))
+	}
+
+	return slog.Group("versions", versions...)
+}
+
+// secretChangePaths returns the sorted, distinct paths of the changed secrets.
+func secretChangePaths(changes []secretChange) []string {
+	paths := make([]string, 0, len(changes))
+	for _, change := range changes {
+		paths = append(paths, change.path)
+	}
+	slices.Sort(paths)
+
+	return slices.Compact(paths)
+}
+
+// workloadSecretVersions returns the versions of the secrets of the workload the reload is for:
+// the versions the changes were detected at, and the stored versions of its other secrets.
+func (c *Controller) workloadSecretVersions(workload workload, changes []secretChange) map[string]int {
+	versions := make(map[string]int)
+
+	c.secretVersionsMu.Lock()
+	for _, secretPath := range c.workloadSecrets.GetWorkloadSecretsMap()[workload] {
+		if version, ok := c.secretVersions[secretPath]; ok {
+			versions[secretPath] = version
 		}
+	}
+	c.secretVersionsMu.Unlock()
 
-		incrementReloadCountAnnotation(&deployment.Spec.Template)
+	for _, change := range changes {
+		versions[change.path] = change.newVersion
+	}
 
-		_, err = c.kubeClient.AppsV1().Deployments(workload.namespace).Update(ctx, deployment, metav1.UpdateOptions{})
-		if err != nil {
-			return err
+	return versions
+}
+
+// swapSecretVersion stores the current version of a secret and the hashes of its keys' values,
+// and returns the previously stored ones.
+func (c *Controller) swapSecretVersion(secretPath string, version int, keyHashes map[string]string) (int, map[string]string) {
+	c.secretVersionsMu.Lock()
+	defer c.secretVersionsMu.Unlock()
+
+	storedVersion := c.secretVersions[secretPath]
+	c.secretVersions[secretPath] = version
+
+	storedKeyHashes := c.secretKeyHashes[secretPath]
+	if keyHashes != nil {
+		c.secretKeyHashes[secretPath] = keyHashes
+	} else {
+		delete(c.secretKeyHashes, secretPath)
+	}
+
+	return storedVersion, storedKeyHashes
+}
+
+// swapSecretDeletion stores whether the latest version of a secret is deleted,
+// reporting whether it got deleted or undeleted since it was last checked.
+func (c *Controller) swapSecretDeletion(secretPath string, deleted bool) bool {
+	c.secretVersionsMu.Lock()
+	defer c.secretVersionsMu.Unlock()
+
+	storedDeleted := c.deletedSecrets[secretPath]
+	if deleted {
+		if c.deletedSecrets == nil {
+			c.deletedSecrets = make(map[string]bool)
 		}
+		c.deletedSecrets[secretPath] = true
+	} else {
+		delete(c.deletedSecrets, secretPath)
+	}
 
-	case DaemonSetKind:
-		daemonSet, err := c.kubeClient.AppsV1().DaemonSets(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
-		if err != nil {
-			return err
+	return storedDeleted != deleted
+}
+
+func logSecretDeletion(secretPath string, version int, deleted bool, logger *slog.Logger) {
+	if deleted {
+		logger.Info(fmt.Sprintf("Latest version %d of secret %s was deleted", version, secretPath))
+	} else {
+		logger.Info(fmt.Sprintf("Latest version %d of secret %s was undeleted", version, secretPath))
+	}
+}
+
+// referencedKeysChanged reports whether the value of any of the referenced keys of a secret changed,
+// a secret without referenced keys or hashes to compare is considered changed as a whole.
+func referencedKeysChanged(keys []string, storedKeyHashes, keyHashes map[string]string) bool {
+	if len(keys) == 0 || storedKeyHashes == nil || keyHashes == nil {
+		return true
+	}
+
+	for _, key := range keys {
+		storedHash, stored := storedKeyHashes[key]
+		hash, ok := keyHashes[key]
+		if stored != ok || storedHash != hash {
+			return true
 		}
+	}
+
+	return false
+}
+
+// pruneSecretVersions removes secrets from the secretVersions map that are not used by any workload,
+// so we don't keep deleted secrets in the map.
+func (c *Controller) pruneSecretVersions(secretWorkloads map[string][]workload, workloadSecrets map[workload][]string) {
+	c.secretVersionsMu.Lock()
+	defer c.secretVersionsMu.Unlock()
+
+	c.pruneWorkloadSecretHashes(workloadSecrets)
+	c.pruneVersionBaselines(workloadSecrets)
+
+	for secretPath := range c.secretVersions {
+		if _, ok := secretWorkloads[secretPath]; !ok {
+			delete(c.secretVersions, secretPath)
+			delete(c.secretKeyHashes, secretPath)
+			delete(c.deletedSecrets, secretPath)
+		}
+	}
+	c.logger.Debug(fmt.Sprintf("Updated secretVersions map: %#v", c.secretVersions))
+}
+
+// reloadWorkload triggers a new rollout of the workload, recording the paths of the changed secrets that triggered it.
+func (c *Controller) reloadWorkload(ctx context.Context, workload workload, changes []secretChange) error {
+	// The workload is read and updated again if another controller updated it in between
+	var accessor WorkloadAccessor
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		accessor, err = c.updateWorkload(ctx, workload, changes)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	c.metrics.workloadsReloaded.WithLabelValues(workload.namespace, workload.kind).Inc()
+	c.scheduleReloadVerification(ctx, workload, accessor)
+
+	// The pods of batch workloads run to completion, only their next runs pick up the new secrets
+	if c.reloadViaPodDelete && !isBatchKind(accessor.Kind()) {
+		return c.evictWorkloadPods(ctx, accessor)
+	}
+
+	return nil
+}
+
+// updateWorkload reads the workload and updates its pod template for the changed secrets, returning it updated.
+func (c *Controller) updateWorkload(ctx context.Context, workload workload, changes []secretChange) (WorkloadAccessor, error) {
+	accessor, err := getWorkloadAccessor(ctx, c.kubeClient, workload)
+	if err != nil {
+		return nil, err
+	}
 
-		incrementReloadCountAnnotation(&daemonSet.Spec.Template)
+	if err := c.checkRecreateStrategy(accessor, c.logger); err != nil {
+		return nil, err
+	}
 
-		_, err = c.kubeClient.AppsV1().DaemonSets(workload.namespace).Update(ctx, daemonSet, metav1.UpdateOptions{})
+	if c.podVersionCheck {
+		upToDate, err := c.podsRunSecretVersions(ctx, accessor, changes)
 		if err != nil {
-			return err
+			// Reload anyway, the check only prevents unnecessary reloads
+			c.logger.Warn(fmt.Errorf("failed to check the secret versions of the pods of %s: %w", workload, err).Error())
+		} else if upToDate {
+			return nil, errPodsUpToDate
 		}
+	}
 
-	case StatefulSetKind:
-		statefulSet, err := c.kubeClient.AppsV1().StatefulSets(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+	for _, annotation := range c.strippedAnnotations {
+		delete(accessor.GetPodTemplate().Annotations, annotation)
+	}
+	c.incrementReloadCount(accessor)
+	accessor.SetPodTemplateAnnotation(LastReloadTimestampAnnotationName, c.clock.Now().UTC().Format(time.RFC3339))
+	if paths := secretChangePaths(changes); len(paths) > 0 {
+		accessor.SetPodTemplateAnnotation(ReloadTriggerPathsAnnotationName, strings.Join(paths, ","))
+	} else {
+		delete(accessor.GetPodTemplate().Annotations, ReloadTriggerPathsAnnotationName)
+	}
+	if versions := c.workloadSecretVersions(workload, changes); len(versions) > 0 {
+		versionsJSON, err := json.Marshal(versions)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		accessor.SetPodTemplateAnnotation(SecretVersionsAnnotationName, string(versionsJSON))
+	} else {
+		delete(accessor.GetPodTemplate().Annotations, SecretVersionsAnnotationName)
+	}
+	if c.reloadGenerationLabel {
+		accessor.SetPodTemplateLabel(ReloadGenerationLabelName, accessor.GetPodTemplate().Annotations[c.reloadCountAnnotationForKind(accessor.Kind())])
+	}
+
+	if c.serverSideApply {
+		err = c.applyWorkload(ctx, accessor)
+	} else {
+		err = accessor.Update(ctx, c.kubeClient, metav1.UpdateOptions{FieldManager: c.fieldManager})
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		incrementReloadCountAnnotation(&statefulSet.Spec.Template)
+	return accessor, nil
+}
 
-		_, err = c.kubeClient.AppsV1().StatefulSets(workload.namespace).Update(ctx, statefulSet, metav1.UpdateOptions{})
-		if err != nil {
-			return err
+// evictWorkloadPods evicts the pods of the workload so they are recreated from its updated pod template.
+// Eviction is used instead of deleting the pods, so PodDisruptionBudgets are respected.
+func (c *Controller) evictWorkloadPods(ctx context.Context, accessor WorkloadAccessor) error {
+	pods, err := c.listWorkloadPods(ctx, accessor)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, pod := range pods {
+		err := c.kubeClient.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to evict pod %s: %w", pod.Name, err))
 		}
+	}
 
-	default:
-		return fmt.Errorf("unknown object type: %s", workload.kind)
+	return errors.Join(errs...)
+}
+
+// listWorkloadPods returns the pods selected by the pod selector of the workload.
+func (c *Controller) listWorkloadPods(ctx context.Context, accessor WorkloadAccessor) ([]corev1.Pod, error) {
+	// Never select every pod of the namespace
+	if accessor.GetSelector() == nil {
+		return nil, fmt.Errorf("workload has no pod selector")
+	}
+	selector, err := metav1.LabelSelectorAsSelector(accessor.GetSelector())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod selector: %w", err)
+	}
+	if selector.Empty() {
+		return nil, fmt.Errorf("workload has no pod selector")
 	}
 
-	return nil
+	pods, err := c.kubeClient.CoreV1().Pods(accessor.GetNamespace()).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	return pods.Items, nil
 }
 
 func (c *Controller) handleSecretError(err error, secretPath string, logger *slog.Logger) {
@@ -161,6 +597,7 @@ func (c *Controller) handleSecretError(err error, secretPath string, logger *slo
 	case ErrSecretNotFound:
 		if !c.vaultConfig.IgnoreMissingSecrets {
 			logger.Error(err.Error())
+			c.metrics.vaultReadErrors.Inc()
 		} else {
 			logger.Warn(fmt.Sprintf(
 				"Path not found: %s - We couldn't find a secret path. This is not an error since missing secrets can be ignored according to the configuration you've set (env: VAULT_IGNORE_MISSING_SECRETS).",
@@ -170,19 +607,36 @@ func (c *Controller) handleSecretError(err error, secretPath string, logger *slo
 
 	default:
 		logger.Error(fmt.Errorf("failed to get secret version: %w", err).Error())
+		c.metrics.vaultReadErrors.Inc()
 	}
 }
 
-func incrementReloadCountAnnotation(podTemplate *corev1.PodTemplateSpec) {
+// incrementReloadCount increments the reload count annotation of the workload's pod template,
+// reporting if its value had to be reset because it was invalid.
+func (c *Controller) incrementReloadCount(accessor WorkloadAccessor) {
+	err := incrementReloadCountAnnotation(accessor, c.reloadCountAnnotationForKind(accessor.Kind()))
+	if err != nil {
+		c.logger.Warn(fmt.Errorf("%s %s/%s: %w", accessor.Kind(), accessor.GetNamespace(), accessor.GetName(), err).Error())
+		c.metrics.invalidReloadCounts.WithLabelValues(accessor.GetNamespace(), accessor.Kind()).Inc()
+	}
+}
+
+func incrementReloadCountAnnotation(accessor WorkloadAccessor, annotationName string) error {
 	version := "1"
+	var err error
 
-	if reloadCount := podTemplate.GetAnnotations()[ReloadCountAnnotationName]; reloadCount != "" {
-		count, err := strconv.Atoi(reloadCount)
-		if err == nil {
+	if reloadCount := accessor.GetPodTemplate().GetAnnotations()[annotationName]; reloadCount != "" {
+		count, parseErr := strconv.Atoi(reloadCount)
+		if parseErr != nil || count < 0 {
+			// Reset corrupted values, so the annotation can be incremented again
+			err = fmt.Errorf("invalid reload count annotation value %q, resetting it to %s", reloadCount, version)
+		} else {
 			count++
 			version = strconv.Itoa(count)
 		}
 	}
 
-	podTemplate.GetAnnotations()[ReloadCountAnnotationName] = version
+	accessor.SetPodTemplateAnnotation(annotationName, version)
+
+	return err
 }
@@ -15,11 +15,31 @@
 package reloader
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func TestIncrementReloadCountAnnotation(t *testing.T) {
@@ -27,7 +47,15 @@ func TestIncrementReloadCountAnnotation(t *testing.T) {
 		name                string
 		annotations         map[string]string
 		expectedAnnotations map[string]string
+		expectedErr         bool
 	}{
+		{
+			name:        "no annotations should add annotation",
+			annotations: nil,
+			expectedAnnotations: map[string]string{
+				ReloadCountAnnotationName: "1",
+			},
+		},
 		{
 			name:        "no annotation should add annotation",
 			annotations: map[string]string{},
@@ -44,20 +72,607 @@ func TestIncrementReloadCountAnnotation(t *testing.T) {
 				ReloadCountAnnotationName: "2",
 			},
 		},
+		{
+			name: "corrupt annotation should be reset",
+			annotations: map[string]string{
+				ReloadCountAnnotationName: "garbage",
+			},
+			expectedAnnotations: map[string]string{
+				ReloadCountAnnotationName: "1",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "negative annotation should be reset",
+			annotations: map[string]string{
+				ReloadCountAnnotationName: "-3",
+			},
+			expectedAnnotations: map[string]string{
+				ReloadCountAnnotationName: "1",
+			},
+			expectedErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		ttp := tt
 		t.Run(ttp.name, func(t *testing.T) {
-			podTemplateSpec := &corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: ttp.annotations,
-				},
+			deployment := newTestDeployment("test", ttp.annotations)
+
+			err := incrementReloadCountAnnotation(&deploymentAccessor{deployment}, ReloadCountAnnotationName)
+			if ttp.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, ttp.expectedAnnotations, deployment.Spec.Template.Annotations)
+		})
+	}
+}
+
+func TestIncrementReloadCountInvalidMetric(t *testing.T) {
+	controller := newTestController()
+	deployment := newTestDeployment("test", map[string]string{ReloadCountAnnotationName: "garbage"})
+
+	controller.incrementReloadCount(&deploymentAccessor{deployment})
+
+	assert.Equal(t, "1", deployment.Spec.Template.Annotations[ReloadCountAnnotationName])
+	assert.InDelta(t, 1, testutil.ToFloat64(controller.metrics.invalidReloadCounts.WithLabelValues("default", DeploymentKind)), 0)
+}
+
+func TestRunReloaderCorrelationID(t *testing.T) {
+	controller := newTestController()
+	var logs bytes.Buffer
+	controller.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+
+	t.Run("from context", func(t *testing.T) {
+		logs.Reset()
+		controller.runReloader(WithCorrelationID(context.Background(), "request-1"), nil)
+
+		lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+		for _, line := range lines {
+			var record map[string]interface{}
+			require.NoError(t, json.Unmarshal(line, &record))
+			assert.Equal(t, "request-1", record["correlation_id"])
+		}
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		logs.Reset()
+		controller.runReloader(context.Background(), nil)
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(bytes.Split(logs.Bytes(), []byte("\n"))[0], &record))
+		assert.NotEmpty(t, record["correlation_id"])
+	})
+}
+
+func TestReloadWorkloadLastReloadTimestamp(t *testing.T) {
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	controller.clock = testingclock.NewFakeClock(now)
+
+	err := controller.reloadWorkload(context.Background(), workload{name: "test", namespace: "default", kind: DeploymentKind}, nil)
+	require.NoError(t, err)
+
+	deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	timestamp := deployment.Spec.Template.Annotations[LastReloadTimestampAnnotationName]
+	assert.Equal(t, "2024-05-01T10:30:00Z", timestamp)
+
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	require.NoError(t, err)
+	assert.True(t, now.Equal(parsed))
+}
+
+func TestReloadChangedWorkloadsAggregatesTriggerPaths(t *testing.T) {
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	var output bytes.Buffer
+	controller.eventOutput = newEventOutput(&output)
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/bar", "secret/data/baz", "secret/data/foo"})
+	controller.secretVersions["secret/data/foo"] = 1
+	controller.secretVersions["secret/data/bar"] = 1
+	controller.secretVersions["secret/data/baz"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 2, "secret/data/bar": 3, "secret/data/baz": 1}}
+
+	summary := controller.reloadChangedWorkloads(
+		context.Background(),
+		vaultClient,
+		controller.workloadSecrets.GetSecretWorkloadsMap(),
+		controller.logger,
+	)
+	assert.Equal(t, 2, summary.SecretsChanged)
+	assert.Equal(t, 1, summary.WorkloadsReloaded)
+
+	// The workload is reloaded once, listing all the secrets that triggered it
+	deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", deployment.Spec.Template.Annotations[ReloadCountAnnotationName])
+	assert.Equal(t, "secret/data/bar,secret/data/foo", deployment.Spec.Template.Annotations[ReloadTriggerPathsAnnotationName])
+
+	var event ReloadEvent
+	require.NoError(t, json.Unmarshal(output.Bytes(), &event))
+	assert.Equal(t, []string{"secret/data/bar", "secret/data/foo"}, event.Paths)
+
+	// A reload without changes doesn't keep the paths of the previous one
+	require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, nil))
+	deployment, err = controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, deployment.Spec.Template.Annotations, ReloadTriggerPathsAnnotationName)
+}
+
+func TestReloadChangedWorkloadsSecretVersions(t *testing.T) {
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/bar", "secret/data/baz", "secret/data/foo"})
+	controller.secretVersions["secret/data/foo"] = 1
+	controller.secretVersions["secret/data/bar"] = 4
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 2, "secret/data/bar": 4, "secret/data/baz": 7}}
+
+	summary := controller.reloadChangedWorkloads(
+		context.Background(),
+		vaultClient,
+		controller.workloadSecrets.GetSecretWorkloadsMap(),
+		controller.logger,
+	)
+	require.Equal(t, 1, summary.WorkloadsReloaded)
+
+	// The versions of all secrets of the workload are recorded, including the ones that didn't trigger the reload
+	deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "secret/data/foo", deployment.Spec.Template.Annotations[ReloadTriggerPathsAnnotationName])
+	assert.JSONEq(t,
+		`{"secret/data/bar": 4, "secret/data/baz": 7, "secret/data/foo": 2}`,
+		deployment.Spec.Template.Annotations[SecretVersionsAnnotationName],
+	)
+
+	// A workload without known secret versions doesn't keep the versions of the previous reload
+	controller.workloadSecrets.Delete(testWorkload)
+	require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, nil))
+	deployment, err = controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, deployment.Spec.Template.Annotations, SecretVersionsAnnotationName)
+}
+
+func TestReloadWorkloadGenerationLabel(t *testing.T) {
+	deployment := newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true", ReloadCountAnnotationName: "4"})
+	deployment.Spec.Template.Labels = map[string]string{"app": "test"}
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+
+	t.Run("disabled", func(t *testing.T) {
+		controller := newTestController(deployment.DeepCopy())
+
+		require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, nil))
+
+		reloaded, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"app": "test"}, reloaded.Spec.Template.Labels)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		controller := newTestController(deployment.DeepCopy())
+		WithReloadGenerationLabel(true)(controller)
+
+		require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, nil))
+		require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, nil))
+
+		reloaded, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"app": "test", ReloadGenerationLabelName: "6"}, reloaded.Spec.Template.Labels)
+		assert.Equal(t, "6", reloaded.Spec.Template.Annotations[ReloadCountAnnotationName])
+	})
+}
+
+func TestReloadWorkloadAnnotationManagement(t *testing.T) {
+	controller := newTestController(newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                  "true",
+		ReloadCountAnnotationName:                   "3",
+		"gitops.example.com/sync-hash":              "abc",
+		"kubectl.kubernetes.io/restartedAt":         "2024-01-01T00:00:00Z",
+		"secrets-reloader.example.com/reload-count": "2",
+	}))
+	option, err := WithReloadCountAnnotation("secrets-reloader.example.com/reload-count", false)
+	require.NoError(t, err)
+	option(controller)
+	WithStrippedAnnotations([]string{"gitops.example.com/sync-hash", "kubectl.kubernetes.io/restartedAt", "missing"})(controller)
+
+	err = controller.reloadWorkload(context.Background(), workload{name: "test", namespace: "default", kind: DeploymentKind}, nil)
+	require.NoError(t, err)
+
+	deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	annotations := deployment.Spec.Template.Annotations
+	assert.Equal(t, "3", annotations["secrets-reloader.example.com/reload-count"])
+	assert.Equal(t, "3", annotations[ReloadCountAnnotationName])
+	assert.NotContains(t, annotations, "gitops.example.com/sync-hash")
+	assert.NotContains(t, annotations, "kubectl.kubernetes.io/restartedAt")
+	assert.Equal(t, "true", annotations[SecretReloadAnnotationName])
+}
+
+func TestReloadWorkloadCronJob(t *testing.T) {
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{SecretReloadAnnotationName: "true"}}},
+		}}},
+	}
+	controller := newTestController(cronJob)
+	// The pods of CronJobs are not evicted, only the next Jobs pick up the new secrets
+	WithReloadViaPodDelete(true)(controller)
+
+	testWorkload := workload{name: "test", namespace: "default", kind: CronJobKind}
+	require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 2}}))
+
+	updated, err := controller.kubeClient.BatchV1().CronJobs("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", updated.Spec.JobTemplate.Spec.Template.Annotations[ReloadCountAnnotationName])
+	assert.Equal(t, "secret/data/foo", updated.Spec.JobTemplate.Spec.Template.Annotations[ReloadTriggerPathsAnnotationName])
+}
+
+func TestReloadWorkloadKindSuffixedReloadCount(t *testing.T) {
+	annotations := map[string]string{SecretReloadAnnotationName: "true", ReloadCountAnnotationName: "3"}
+	controller := newTestController(
+		newTestDeployment("test", annotations),
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: appsv1.StatefulSetSpec{
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: maps.Clone(annotations)}},
+			},
+		},
+	)
+	option, err := WithReloadCountAnnotation(ReloadCountAnnotationName, true)
+	require.NoError(t, err)
+	option(controller)
+	WithReloadGenerationLabel(true)(controller)
+
+	require.NoError(t, controller.reloadWorkload(context.Background(), workload{name: "test", namespace: "default", kind: DeploymentKind}, nil))
+	require.NoError(t, controller.reloadWorkload(context.Background(), workload{name: "test", namespace: "default", kind: StatefulSetKind}, nil))
+	require.NoError(t, controller.reloadWorkload(context.Background(), workload{name: "test", namespace: "default", kind: StatefulSetKind}, nil))
+
+	deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", deployment.Spec.Template.Annotations[ReloadCountAnnotationName+"-deployment"])
+	assert.Equal(t, "1", deployment.Spec.Template.Labels[ReloadGenerationLabelName])
+	// The unsuffixed count is left as it was
+	assert.Equal(t, "3", deployment.Spec.Template.Annotations[ReloadCountAnnotationName])
+
+	statefulSet, err := controller.kubeClient.AppsV1().StatefulSets("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "2", statefulSet.Spec.Template.Annotations[ReloadCountAnnotationName+"-statefulset"])
+	assert.NotContains(t, statefulSet.Spec.Template.Annotations, ReloadCountAnnotationName+"-deployment")
+
+	t.Run("invalid suffixed name", func(t *testing.T) {
+		_, err := WithReloadCountAnnotation("example.com/"+strings.Repeat("a", 55), true)
+		assert.Error(t, err)
+		_, err = WithReloadCountAnnotation("example.com/"+strings.Repeat("a", 55), false)
+		assert.NoError(t, err)
+	})
+}
+
+// TestReloadChangedWorkloadsConcurrentStoreMutations is meant to be run with -race
+func TestReloadChangedWorkloadsConcurrentStoreMutations(t *testing.T) {
+	const workloadCount = 10
+	const cycles = 20
+
+	var objects []runtime.Object
+	var workloads []workload
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{}}
+	for i := 0; i < workloadCount; i++ {
+		name := fmt.Sprintf("test-%d", i)
+		objects = append(objects, newTestDeployment(name, map[string]string{SecretReloadAnnotationName: "true"}))
+		workloads = append(workloads, workload{name: name, namespace: "default", kind: DeploymentKind})
+		vaultClient.setVersion(fmt.Sprintf("secret/data/%d", i), 1)
+	}
+	controller := newTestController(objects...)
+	for i, workload := range workloads {
+		controller.workloadSecrets.Store(workload, []string{fmt.Sprintf("secret/data/%d", i)})
+	}
+
+	// Mutate the store the way the collector does while the cycles are running
+	done := make(chan struct{})
+	mutatorDone := make(chan struct{})
+	go func() {
+		defer close(mutatorDone)
+		extra := workload{name: "extra", namespace: "default", kind: DeploymentKind}
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
 			}
 
-			incrementReloadCountAnnotation(podTemplateSpec)
+			workload := workloads[i%workloadCount]
+			controller.workloadSecrets.Store(workload, []string{fmt.Sprintf("secret/data/%d", i%workloadCount)})
+			controller.workloadSecrets.SetConfig(workload, workloadConfig{pollPeriod: time.Duration(i%2) * time.Minute})
+			controller.workloadSecrets.Store(extra, []string{"secret/data/extra"})
+			controller.workloadSecrets.Delete(extra)
+		}
+	}()
+
+	for cycle := 1; cycle <= cycles; cycle++ {
+		for i := 0; i < workloadCount; i++ {
+			vaultClient.setVersion(fmt.Sprintf("secret/data/%d", i), cycle)
+		}
+		controller.reloadChangedWorkloads(
+			context.Background(),
+			vaultClient,
+			controller.workloadSecrets.GetSecretWorkloadsMap(),
+			controller.logger,
+		)
+	}
+	close(done)
+	<-mutatorDone
+
+	for _, workload := range workloads {
+		deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), workload.name, metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(cycles-1), deployment.Spec.Template.Annotations[ReloadCountAnnotationName])
+	}
+	assert.NotContains(t, controller.secretVersions, "secret/data/extra")
+}
+
+func TestReloadWorkloadViaPodDelete(t *testing.T) {
+	deployment := newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"})
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+	newPod := func(name, namespace string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}}
+	}
+
+	controller := newTestController(
+		deployment,
+		newPod("test-1", "default", map[string]string{"app": "test"}),
+		newPod("test-2", "default", map[string]string{"app": "test"}),
+		newPod("other", "default", map[string]string{"app": "other"}),
+		newPod("test-3", "other", map[string]string{"app": "test"}),
+	)
+	WithReloadViaPodDelete(true)(controller)
+
+	err := controller.reloadWorkload(context.Background(), workload{name: "test", namespace: "default", kind: DeploymentKind}, nil)
+	require.NoError(t, err)
+
+	var evicted []string
+	for _, action := range controller.kubeClient.(*fake.Clientset).Actions() {
+		if action.GetSubresource() != "eviction" {
+			continue
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		assert.Equal(t, "default", eviction.Namespace)
+		evicted = append(evicted, eviction.Name)
+	}
+	assert.ElementsMatch(t, []string{"test-1", "test-2"}, evicted)
+
+	updated, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", updated.Spec.Template.Annotations[ReloadCountAnnotationName])
+}
+
+func TestReloadWorkloadViaPodDeleteWithoutSelector(t *testing.T) {
+	controller := newTestController(
+		newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}),
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"}},
+	)
+	WithReloadViaPodDelete(true)(controller)
+
+	err := controller.reloadWorkload(context.Background(), workload{name: "test", namespace: "default", kind: DeploymentKind}, nil)
+	assert.Error(t, err)
+
+	for _, action := range controller.kubeClient.(*fake.Clientset).Actions() {
+		assert.NotEqual(t, "eviction", action.GetSubresource())
+	}
+}
+
+func TestReloadWorkloadsVersionsLog(t *testing.T) {
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	controller.reloadWorkloads(context.Background(), map[workload][]secretChange{
+		{name: "test", namespace: "default", kind: DeploymentKind}: {
+			{path: "secret/data/bar", oldVersion: 3, newVersion: 5},
+			{path: "secret/data/foo", oldVersion: 1, newVersion: 2},
+		},
+	}, logger)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes.Split(logs.Bytes(), []byte("\n"))[0], &record))
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, map[string]interface{}{
+		"secret/data/bar": map[string]interface{}{"old": float64(3), "new": float64(5)},
+		"secret/data/foo": map[string]interface{}{"old": float64(1), "new": float64(2)},
+	}, record["versions"])
+}
+
+func TestCheckSecretVersionsUnreadableSecret(t *testing.T) {
+	controller := newTestController()
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	controller.secretVersions["secret/data/foo"] = 1
+	controller.secretVersions["kv2/data/bar"] = 1
+	vaultClient := &versionedVaultClientMock{
+		versions: map[string]int{"secret/data/foo": 2},
+		errs:     map[string]error{"kv2/data/bar": assert.AnError},
+	}
+
+	workloadsToReload, errs := controller.checkSecretVersions(context.Background(), vaultClient, map[string][]workload{
+		"secret/data/foo": {testWorkload},
+		"kv2/data/bar":    {testWorkload},
+	}, logger)
+
+	assert.Equal(t, map[workload][]secretChange{
+		testWorkload: {{path: "secret/data/foo", oldVersion: 1, newVersion: 2}},
+	}, workloadsToReload)
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], assert.AnError)
+	// The unreadable secret keeps its stored version, so its change is detected once it can be read
+	assert.Equal(t, 1, controller.secretVersions["kv2/data/bar"])
+	assert.Contains(t, logs.String(), `"level":"WARN"`)
+	assert.Contains(t, logs.String(), "kv2/data/bar")
+}
+
+func TestCheckSecretVersionsReloadOnDelete(t *testing.T) {
+	newSecret := func(deletionTime string) *vaultapi.Secret {
+		return &vaultapi.Secret{Data: map[string]interface{}{
+			"data": nil,
+			"metadata": map[string]interface{}{
+				"version":       json.Number("2"),
+				"deletion_time": deletionTime,
+				"destroyed":     false,
+			},
+		}}
+	}
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	secretWorkloads := map[string][]workload{"secret/data/foo": {testWorkload}}
+	change := secretChange{path: "secret/data/foo", oldVersion: 2, newVersion: 2}
+
+	for _, reloadOnDelete := range []bool{false, true} {
+		t.Run(strconv.FormatBool(reloadOnDelete), func(t *testing.T) {
+			controller := newTestController()
+			controller.vaultConfig.ReloadOnDelete = reloadOnDelete
+			controller.secretVersions["secret/data/foo"] = 2
+			vaultClient := &vaultClientMock{vaultSecret: newSecret("2025-01-01T00:00:00Z")}
+
+			workloadsToReload, errs := controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+			require.Empty(t, errs)
+			if !reloadOnDelete {
+				assert.Empty(t, workloadsToReload)
+				return
+			}
+			assert.Equal(t, map[workload][]secretChange{testWorkload: {change}}, workloadsToReload)
+
+			// Still deleted
+			workloadsToReload, errs = controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+			require.Empty(t, errs)
+			assert.Empty(t, workloadsToReload)
+
+			// Undeleted
+			vaultClient.vaultSecret = newSecret("")
+			workloadsToReload, errs = controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+			require.Empty(t, errs)
+			assert.Equal(t, map[workload][]secretChange{testWorkload: {change}}, workloadsToReload)
+			assert.Empty(t, controller.deletedSecrets)
+		})
+	}
+}
+
+func TestCheckSecretVersionsSubkeyAware(t *testing.T) {
+	controller := newTestController()
+	controller.subkeyAwareReload = true
+
+	keyUser := workload{name: "user", namespace: "default", kind: DeploymentKind}
+	keyPassword := workload{name: "password", namespace: "default", kind: DeploymentKind}
+	whole := workload{name: "whole", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.SetSecretKeys(keyUser, map[string][]string{"secret/data/mysql": {"user"}})
+	controller.workloadSecrets.SetSecretKeys(keyPassword, map[string][]string{"secret/data/mysql": {"password"}})
+	secretWorkloads := map[string][]workload{"secret/data/mysql": {keyUser, keyPassword, whole}}
+
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{}, data: map[string]map[string]interface{}{}}
+	vaultClient.setData("secret/data/mysql", 1, map[string]interface{}{"user": "app", "password": "secret1"})
+	workloadsToReload, errs := controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+	require.Empty(t, errs)
+	assert.Empty(t, workloadsToReload)
+
+	// Only the password changed, so the workload using the user key is not reloaded
+	vaultClient.setData("secret/data/mysql", 2, map[string]interface{}{"user": "app", "password": "secret2"})
+	workloadsToReload, errs = controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+	require.Empty(t, errs)
+	change := secretChange{path: "secret/data/mysql", oldVersion: 1, newVersion: 2}
+	assert.Equal(t, map[workload][]secretChange{
+		keyPassword: {change},
+		whole:       {change},
+	}, workloadsToReload)
+
+	// A new key that's not referenced only reloads the workload using the whole secret
+	vaultClient.setData("secret/data/mysql", 3, map[string]interface{}{"user": "app", "password": "secret2", "host": "mysql"})
+	workloadsToReload, errs = controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+	require.Empty(t, errs)
+	assert.Equal(t, map[workload][]secretChange{
+		whole: {{path: "secret/data/mysql", oldVersion: 2, newVersion: 3}},
+	}, workloadsToReload)
+
+	// Removing a referenced key is a change of it
+	vaultClient.setData("secret/data/mysql", 4, map[string]interface{}{"password": "secret2", "host": "mysql"})
+	workloadsToReload, errs = controller.checkSecretVersions(context.Background(), vaultClient, secretWorkloads, controller.logger)
+	require.Empty(t, errs)
+	change = secretChange{path: "secret/data/mysql", oldVersion: 3, newVersion: 4}
+	assert.Equal(t, map[workload][]secretChange{
+		keyUser: {change},
+		whole:   {change},
+	}, workloadsToReload)
+}
+
+func TestReloadWorkloadFieldManager(t *testing.T) {
+	for _, fieldManager := range []string{"", "custom-manager"} {
+		t.Run(fieldManager, func(t *testing.T) {
+			controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+			expected := DefaultFieldManager
+			if fieldManager != "" {
+				WithFieldManager(fieldManager)(controller)
+				expected = fieldManager
+			}
+
+			err := controller.reloadWorkload(context.Background(), workload{name: "test", namespace: "default", kind: DeploymentKind}, nil)
+			require.NoError(t, err)
+
+			var updates int
+			for _, action := range controller.kubeClient.(*fake.Clientset).Actions() {
+				if action.GetVerb() != "update" {
+					continue
+				}
+				updates++
+				assert.Equal(t, expected, action.(k8stesting.UpdateActionImpl).GetUpdateOptions().FieldManager)
+			}
+			assert.Equal(t, 1, updates)
+		})
+	}
+}
+
+func TestReloadWorkloadRetryOnConflict(t *testing.T) {
+	tests := []struct {
+		kind     string
+		resource string
+		object   runtime.Object
+	}{
+		{kind: DeploymentKind, resource: "deployments", object: newTestDeployment("test", nil)},
+		{kind: DaemonSetKind, resource: "daemonsets", object: &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}},
+		{kind: StatefulSetKind, resource: "statefulsets", object: &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			controller := newTestController(tt.object)
+			kubeClient := controller.kubeClient.(*fake.Clientset)
+			gvr := appsv1.SchemeGroupVersion.WithResource(tt.resource)
+
+			// Another controller updates the workload between the first read and update
+			updates := 0
+			kubeClient.PrependReactor("update", tt.resource, func(_ k8stesting.Action) (bool, runtime.Object, error) {
+				updates++
+				if updates > 1 {
+					return false, nil, nil
+				}
+				current, err := kubeClient.Tracker().Get(gvr, "default", "test")
+				require.NoError(t, err)
+				current.(metav1.Object).SetAnnotations(map[string]string{"other-controller": "true"})
+				require.NoError(t, kubeClient.Tracker().Update(gvr, current, "default"))
+				return true, nil, apierrors.NewConflict(gvr.GroupResource(), "test", assert.AnError)
+			})
+
+			err := controller.reloadWorkload(context.Background(), workload{name: "test", namespace: "default", kind: tt.kind}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, 2, updates)
 
-			assert.Equal(t, ttp.expectedAnnotations, podTemplateSpec.Annotations)
+			accessor, err := getWorkloadAccessor(context.Background(), controller.kubeClient, workload{name: "test", namespace: "default", kind: tt.kind})
+			require.NoError(t, err)
+			// The update is made on top of the change of the other controller, incrementing the reload count once
+			assert.Equal(t, "true", accessor.GetAnnotations()["other-controller"])
+			assert.Equal(t, "1", accessor.GetPodTemplate().Annotations[ReloadCountAnnotationName])
 		})
 	}
 }
@@ -0,0 +1,139 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultReloadLoopWindow is the window repeated reloads of a workload are counted in by default
+const DefaultReloadLoopWindow = 10 * time.Minute
+
+// reloadLoopBreaker stops reloading workloads that are reloaded over and over again for the same secret versions,
+// e.g. because an external controller keeps reverting their reloads.
+type reloadLoopBreaker struct {
+	threshold int
+	window    time.Duration
+
+	mu    sync.Mutex
+	loops map[workload]*reloadLoop
+}
+
+// reloadLoop holds the secret versions a workload was last reloaded for, and its repeated reloads since
+type reloadLoop struct {
+	versions map[string]int
+	repeats  []time.Time
+	tripped  bool
+}
+
+// WithReloadLoopDetection stops reloading a workload once it was reloaded more than threshold times within
+// the window without any of its secrets getting a new version, until one of them does. Zero, the default,
+// disables loop detection.
+func WithReloadLoopDetection(threshold int, window time.Duration) (Option, error) {
+	if threshold < 0 {
+		return nil, fmt.Errorf("invalid reload loop threshold %d, must not be negative", threshold)
+	}
+	if threshold > 0 && window <= 0 {
+		return nil, fmt.Errorf("invalid reload loop window %s, must be positive", window)
+	}
+
+	return func(c *Controller) {
+		if threshold > 0 {
+			c.reloadLoopBreaker = &reloadLoopBreaker{
+				threshold: threshold,
+				window:    window,
+				loops:     make(map[workload]*reloadLoop),
+			}
+		}
+	}, nil
+}
+
+// check records the reload of the workload, reporting whether it's allowed,
+// and whether the breaker tripped with it.
+func (b *reloadLoopBreaker) check(reloaded workload, changes []secretChange, now time.Time) (allowed bool, tripped bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	loop, ok := b.loops[reloaded]
+	if !ok {
+		loop = &reloadLoop{versions: make(map[string]int)}
+		b.loops[reloaded] = loop
+	}
+
+	// Any new secret version is a genuine change, which resets the breaker
+	repeated := len(changes) > 0
+	for _, change := range changes {
+		if version, ok := loop.versions[change.path]; !ok || version != change.newVersion {
+			repeated = false
+		}
+		loop.versions[change.path] = change.newVersion
+	}
+	if !repeated {
+		loop.repeats = nil
+		loop.tripped = false
+		return true, false
+	}
+	if loop.tripped {
+		return false, false
+	}
+
+	repeats := loop.repeats[:0]
+	for _, repeat := range loop.repeats {
+		if now.Sub(repeat) < b.window {
+			repeats = append(repeats, repeat)
+		}
+	}
+	loop.repeats = append(repeats, now)
+	if len(loop.repeats) > b.threshold {
+		loop.tripped = true
+		return false, true
+	}
+
+	return true, false
+}
+
+// forget drops the reloads recorded of the workload.
+func (b *reloadLoopBreaker) forget(forgotten workload) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.loops, forgotten)
+}
+
+// breakReloadLoops removes the workloads in a reload loop from workloadsToReload.
+func (c *Controller) breakReloadLoops(workloadsToReload map[workload][]secretChange, logger *slog.Logger) {
+	if c.reloadLoopBreaker == nil {
+		return
+	}
+
+	now := c.clock.Now()
+	for reloaded, changes := range workloadsToReload {
+		allowed, tripped := c.reloadLoopBreaker.check(reloaded, changes, now)
+		if tripped {
+			logger.Warn(fmt.Sprintf("Reload loop detected: workload %s was reloaded more than %d times within %s for the same secret versions, "+
+				"not reloading it until one of its secrets gets a new version. Check whether another controller reverts its reloads.",
+				reloaded, c.reloadLoopBreaker.threshold, c.reloadLoopBreaker.window), secretChangesAttr(changes))
+			c.metrics.reloadLoops.WithLabelValues(reloaded.namespace, reloaded.kind).Inc()
+		}
+		if !allowed {
+			if !tripped {
+				logger.Debug(fmt.Sprintf("Workload %s is in a reload loop, not reloading it", reloaded))
+			}
+			delete(workloadsToReload, reloaded)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestReloadLoopDetection(t *testing.T) {
+	ctx := context.Background()
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	controller.clock = fakeClock
+	option, err := WithReloadLoopDetection(2, time.Minute)
+	require.NoError(t, err)
+	option(controller)
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+
+	// An external controller reverts every reload, so the same change is reloaded again
+	reloadReverted := func(changes []secretChange) {
+		errs := controller.reloadWorkloads(ctx, map[workload][]secretChange{testWorkload: changes}, logger)
+		require.Empty(t, errs)
+
+		deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		delete(deployment.Spec.Template.Annotations, ReloadCountAnnotationName)
+		_, err = controller.kubeClient.AppsV1().Deployments("default").Update(ctx, deployment, metav1.UpdateOptions{})
+		require.NoError(t, err)
+		fakeClock.Step(time.Second)
+	}
+	reloads := func() float64 {
+		return testutil.ToFloat64(controller.metrics.workloadsReloaded.WithLabelValues("default", DeploymentKind))
+	}
+
+	change := []secretChange{{path: "secret/data/foo", oldVersion: 1, newVersion: 2}}
+	for range 5 {
+		reloadReverted(change)
+	}
+
+	// The first reload and the repeats up to the threshold are let through, then the breaker trips
+	assert.Equal(t, float64(3), reloads())
+	assert.Equal(t, float64(1), testutil.ToFloat64(controller.metrics.reloadLoops.WithLabelValues("default", DeploymentKind)))
+	assert.Contains(t, logs.String(), "Reload loop detected: workload")
+
+	// A new version of the secret resets the breaker
+	reloadReverted([]secretChange{{path: "secret/data/foo", oldVersion: 2, newVersion: 3}})
+	assert.Equal(t, float64(4), reloads())
+
+	// Repeats spread out over more than the window don't trip it
+	for range 4 {
+		fakeClock.Step(time.Minute)
+		reloadReverted([]secretChange{{path: "secret/data/foo", oldVersion: 2, newVersion: 3}})
+	}
+	assert.Equal(t, float64(8), reloads())
+	assert.Equal(t, float64(1), testutil.ToFloat64(controller.metrics.reloadLoops.WithLabelValues("default", DeploymentKind)))
+}
+
+func TestWithReloadLoopDetection(t *testing.T) {
+	controller := newTestController()
+	option, err := WithReloadLoopDetection(0, 0)
+	require.NoError(t, err)
+	option(controller)
+	assert.Nil(t, controller.reloadLoopBreaker)
+
+	_, err = WithReloadLoopDetection(-1, time.Minute)
+	assert.Error(t, err)
+	_, err = WithReloadLoopDetection(3, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,199 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// resyncQueue coalesces the workloads to re-collect on informer resyncs, in the order they were resynced
+type resyncQueue struct {
+	mu      sync.Mutex
+	pending map[workload]struct{}
+	order   []workload
+	// ready is signaled when a workload is added to the empty queue
+	ready chan struct{}
+}
+
+func newResyncQueue() *resyncQueue {
+	return &resyncQueue{
+		pending: make(map[workload]struct{}),
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// add queues the workload, unless it is already queued.
+func (q *resyncQueue) add(queued workload) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.pending[queued]; ok {
+		return
+	}
+
+	q.pending[queued] = struct{}{}
+	q.order = append(q.order, queued)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// remove drops the workload from the queue, e.g. when it was collected on an actual change.
+func (q *resyncQueue) remove(removed workload) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, removed)
+}
+
+// pop returns the next queued workload, if any.
+func (q *resyncQueue) pop() (workload, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.order) > 0 {
+		next := q.order[0]
+		q.order = q.order[1:]
+		// Removed workloads are skipped
+		if _, ok := q.pending[next]; ok {
+			delete(q.pending, next)
+			return next, true
+		}
+	}
+
+	return workload{}, false
+}
+
+// len returns the number of queued workloads.
+func (q *resyncQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// handleUpdate collects the secrets of an updated workload. With a resync collection interval set,
+// periodic resyncs, that don't change the workload, are queued to be re-collected one by one instead.
+func (c *Controller) handleUpdate(oldObj, newObj interface{}) {
+	if c.resyncQueue == nil {
+		c.handleObject(newObj)
+		return
+	}
+
+	accessor, ok := newWorkloadAccessor(newObj)
+	if !ok {
+		c.handleObject(newObj)
+		return
+	}
+
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil || oldMeta.GetResourceVersion() != accessor.GetResourceVersion() {
+		c.resyncQueue.remove(workloadFromAccessor(accessor))
+		c.handleObject(newObj)
+		return
+	}
+
+	c.resyncQueue.add(workloadFromAccessor(accessor))
+}
+
+// runResyncCollector re-collects the queued workloads, waiting resyncCollectionInterval between them.
+func (c *Controller) runResyncCollector(ctx context.Context) {
+	for {
+		next, ok := c.resyncQueue.pop()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.resyncQueue.ready:
+				continue
+			}
+		}
+
+		// The workload is collected as it is now, it may have changed since it was queued
+		accessor, err := c.getListedWorkloadAccessor(next)
+		switch {
+		case apierrors.IsNotFound(err):
+			// Deleted workloads are removed from the store by the delete handler
+		case err != nil:
+			c.logger.Error(fmt.Errorf("failed to re-collect workload %s: %w", next, err).Error())
+		default:
+			c.processWorkload(accessor)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(c.resyncCollectionInterval):
+		}
+	}
+}
+
+// getListedWorkloadAccessor returns an accessor for the workload from the informer caches.
+func (c *Controller) getListedWorkloadAccessor(listed workload) (WorkloadAccessor, error) {
+	switch listed.kind {
+	case DeploymentKind:
+		deployment, err := c.deploymentsLister.Deployments(listed.namespace).Get(listed.name)
+		if err != nil {
+			return nil, err
+		}
+		return &deploymentAccessor{deployment}, nil
+
+	case DaemonSetKind:
+		daemonSet, err := c.daemonSetsLister.DaemonSets(listed.namespace).Get(listed.name)
+		if err != nil {
+			return nil, err
+		}
+		return &daemonSetAccessor{daemonSet}, nil
+
+	case StatefulSetKind:
+		statefulSet, err := c.statefulSetsLister.StatefulSets(listed.namespace).Get(listed.name)
+		if err != nil {
+			return nil, err
+		}
+		return &statefulSetAccessor{statefulSet}, nil
+
+	case CronJobKind:
+		cronJob, err := c.cronJobsLister.CronJobs(listed.namespace).Get(listed.name)
+		if err != nil {
+			return nil, err
+		}
+		return &cronJobAccessor{cronJob}, nil
+
+	case JobKind:
+		job, err := c.jobsLister.Jobs(listed.namespace).Get(listed.name)
+		if err != nil {
+			return nil, err
+		}
+		return &jobAccessor{job}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown object type: %s", listed.kind)
+	}
+}
+
+// WithResyncCollectionInterval smooths out the re-collection of workloads on periodic informer resyncs,
+// re-collecting one workload per interval, so a resync of a large cluster doesn't cause a burst of collections.
+// Actual changes of workloads are collected immediately. Zero, the default, collects resyncs immediately.
+func WithResyncCollectionInterval(interval time.Duration) Option {
+	return func(c *Controller) {
+		if interval > 0 {
+			c.resyncCollectionInterval = interval
+			c.resyncQueue = newResyncQueue()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestResyncQueue(t *testing.T) {
+	queue := newResyncQueue()
+	workload1 := workload{name: "test1", namespace: "default", kind: DeploymentKind}
+	workload2 := workload{name: "test2", namespace: "default", kind: DeploymentKind}
+	workload3 := workload{name: "test3", namespace: "default", kind: DeploymentKind}
+
+	queue.add(workload1)
+	queue.add(workload2)
+	queue.add(workload1)
+	queue.add(workload3)
+	queue.remove(workload2)
+	assert.Equal(t, 2, queue.len())
+
+	next, ok := queue.pop()
+	assert.True(t, ok)
+	assert.Equal(t, workload1, next)
+	next, ok = queue.pop()
+	assert.True(t, ok)
+	assert.Equal(t, workload3, next)
+	_, ok = queue.pop()
+	assert.False(t, ok)
+}
+
+func TestResyncBurstIsSmoothedOut(t *testing.T) {
+	const workloadCount = 200
+	interval := time.Second
+
+	controller := newTestController()
+	fakeClock := testingclock.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	controller.clock = fakeClock
+	WithResyncCollectionInterval(interval)(controller)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	controller.deploymentsLister = appslisters.NewDeploymentLister(indexer)
+	deployments := make([]*appsv1.Deployment, 0, workloadCount)
+	for i := 0; i < workloadCount; i++ {
+		deployment := newTestDeployment(fmt.Sprintf("test%d", i), map[string]string{
+			SecretReloadAnnotationName:                                "true",
+			"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+		})
+		deployment.ResourceVersion = "1"
+		require.NoError(t, indexer.Add(deployment))
+		deployments = append(deployments, deployment)
+	}
+
+	// A resync delivers every workload as an update, twice as the resync period is shorter than the collection
+	for range 2 {
+		for _, deployment := range deployments {
+			controller.handleUpdate(deployment, deployment)
+		}
+	}
+	assert.Empty(t, controller.workloadSecrets.GetWorkloadSecretsMap())
+	assert.Equal(t, workloadCount, controller.resyncQueue.len())
+
+	// An actual change is collected right away
+	changed := deployments[0].DeepCopy()
+	changed.ResourceVersion = "2"
+	require.NoError(t, indexer.Update(changed))
+	controller.handleUpdate(deployments[0], changed)
+	assert.Len(t, controller.workloadSecrets.GetWorkloadSecretsMap(), 1)
+	assert.Equal(t, workloadCount-1, controller.resyncQueue.len())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go controller.runResyncCollector(ctx)
+
+	// Workloads are collected one per interval
+	for collected := 2; collected <= workloadCount; collected++ {
+		require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+		assert.Len(t, controller.workloadSecrets.GetWorkloadSecretsMap(), collected)
+		fakeClock.Step(interval)
+	}
+
+	assert.Len(t, controller.workloadSecrets.GetSecretWorkloadsMap()["secret/data/foo"], workloadCount)
+	assert.Zero(t, controller.resyncQueue.len())
+}
@@ -0,0 +1,74 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScalingInProgressAnnotationName is the default annotation of the scaling signal ConfigMap
+// that is set to "true" while the cluster is scaling.
+const ScalingInProgressAnnotationName = "secrets-reloader.security.bank-vaults.io/scaling-in-progress"
+
+// scalingSignal identifies the ConfigMap annotation that signals the cluster is scaling
+type scalingSignal struct {
+	namespace  string
+	name       string
+	annotation string
+}
+
+// parseScalingSignal parses a ConfigMap reference in namespace/name format.
+func parseScalingSignal(configMap, annotation string) (*scalingSignal, error) {
+	namespace, name, ok := strings.Cut(configMap, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid ConfigMap %q, must be in namespace/name format", configMap)
+	}
+	if annotation == "" {
+		annotation = ScalingInProgressAnnotationName
+	}
+
+	return &scalingSignal{namespace: namespace, name: name, annotation: annotation}, nil
+}
+
+// scalingInProgress reports whether reloads should be deferred because the cluster is scaling.
+// A missing or unreadable signal doesn't block reloads.
+func (c *Controller) scalingInProgress(ctx context.Context, logger *slog.Logger) bool {
+	if c.scalingSignal == nil {
+		return false
+	}
+
+	configMap, err := c.kubeClient.CoreV1().ConfigMaps(c.scalingSignal.namespace).Get(ctx, c.scalingSignal.name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Debug(fmt.Sprintf("Scaling signal ConfigMap %s/%s not found", c.scalingSignal.namespace, c.scalingSignal.name))
+		} else {
+			logger.Warn(fmt.Errorf("failed to read scaling signal ConfigMap: %w", err).Error())
+		}
+		return false
+	}
+
+	if configMap.Annotations[c.scalingSignal.annotation] != "true" {
+		return false
+	}
+
+	logger.Info("Cluster is scaling, deferring reloads until it's finished")
+	return true
+}
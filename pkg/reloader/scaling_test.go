@@ -0,0 +1,91 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseScalingSignal(t *testing.T) {
+	signal, err := parseScalingSignal("kube-system/cluster-autoscaler-status", "")
+	require.NoError(t, err)
+	assert.Equal(t, &scalingSignal{
+		namespace:  "kube-system",
+		name:       "cluster-autoscaler-status",
+		annotation: ScalingInProgressAnnotationName,
+	}, signal)
+
+	signal, err = parseScalingSignal("kube-system/scaling", "example.com/scaling")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/scaling", signal.annotation)
+
+	for _, configMap := range []string{"", "scaling", "/scaling", "kube-system/", "kube-system/scaling/extra"} {
+		_, err := parseScalingSignal(configMap, "")
+		assert.Error(t, err, configMap)
+	}
+}
+
+func TestReloadChangedWorkloadsScalingSignal(t *testing.T) {
+	ctx := context.Background()
+	signal := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "scaling",
+			Namespace:   "kube-system",
+			Annotations: map[string]string{ScalingInProgressAnnotationName: "true"},
+		},
+	}
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}), signal)
+	scalingSignalOption, err := WithScalingSignal("kube-system/scaling", "")
+	require.NoError(t, err)
+	scalingSignalOption(controller)
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/foo"})
+	controller.secretVersions["secret/data/foo"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 2}}
+
+	reloadCount := func() string {
+		deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		return deployment.Spec.Template.Annotations[ReloadCountAnnotationName]
+	}
+
+	// Scaling in progress, the reload is deferred
+	controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+	assert.Equal(t, "", reloadCount())
+	assert.Equal(t, 1, controller.secretVersions["secret/data/foo"])
+
+	// Scaling finished, the deferred change is reloaded
+	signal.Annotations[ScalingInProgressAnnotationName] = "false"
+	_, err = controller.kubeClient.CoreV1().ConfigMaps("kube-system").Update(ctx, signal, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+	assert.Equal(t, "1", reloadCount())
+	assert.Equal(t, 2, controller.secretVersions["secret/data/foo"])
+
+	// A missing signal doesn't block reloads
+	require.NoError(t, controller.kubeClient.CoreV1().ConfigMaps("kube-system").Delete(ctx, "scaling", metav1.DeleteOptions{}))
+	vaultClient.setVersion("secret/data/foo", 3)
+
+	controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+	assert.Equal(t, "2", reloadCount())
+}
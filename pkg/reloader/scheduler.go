@@ -0,0 +1,144 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithSettleDelay delays the first reloader cycle after the informer caches synced, so the secrets of
+// the workloads added by the initial burst of events are all collected before changes are checked.
+func WithSettleDelay(delay time.Duration) Option {
+	return func(c *Controller) {
+		c.settleDelay = delay
+	}
+}
+
+// pollScheduler keeps track of when the secrets, grouped by their poll period, were last checked.
+type pollScheduler struct {
+	lastChecked map[time.Duration]time.Time
+}
+
+func newPollScheduler() *pollScheduler {
+	return &pollScheduler{
+		lastChecked: make(map[time.Duration]time.Time),
+	}
+}
+
+// dueSecrets returns the secrets of the groups whose poll period elapsed since
+// they were last checked, and marks those groups as checked.
+func (s *pollScheduler) dueSecrets(groups map[time.Duration]map[string][]workload, now time.Time) map[string][]workload {
+	// Forget groups that don't exist anymore
+	for period := range s.lastChecked {
+		if _, ok := groups[period]; !ok {
+			delete(s.lastChecked, period)
+		}
+	}
+
+	dueSecrets := make(map[string][]workload)
+	for period, secretWorkloads := range groups {
+		if now.Sub(s.lastChecked[period]) < period {
+			continue
+		}
+
+		s.lastChecked[period] = now
+		for secretPath, workloads := range secretWorkloads {
+			dueSecrets[secretPath] = workloads
+		}
+	}
+
+	return dueSecrets
+}
+
+// nextCheck returns the time left until the next group of secrets is due,
+// or the default period if there are no secrets to check.
+func (s *pollScheduler) nextCheck(groups map[time.Duration]map[string][]workload, now time.Time, defaultPeriod time.Duration) time.Duration {
+	if len(groups) == 0 {
+		return defaultPeriod
+	}
+
+	var next time.Duration
+	first := true
+	for period := range groups {
+		untilDue := s.lastChecked[period].Add(period).Sub(now)
+		if first || untilDue < next {
+			next = untilDue
+			first = false
+		}
+	}
+
+	return max(next, 0)
+}
+
+// groupSecretsByPollPeriod groups the secrets by their effective poll period,
+// which is the shortest one among the workloads using them.
+func groupSecretsByPollPeriod(
+	secretWorkloads map[string][]workload,
+	configs map[workload]workloadConfig,
+	defaultPeriod time.Duration,
+) map[time.Duration]map[string][]workload {
+	groups := make(map[time.Duration]map[string][]workload)
+	for secretPath, workloads := range secretWorkloads {
+		var period time.Duration
+		for _, workload := range workloads {
+			workloadPeriod := configs[workload].pollPeriod
+			if workloadPeriod == 0 {
+				workloadPeriod = defaultPeriod
+			}
+			if period == 0 || workloadPeriod < period {
+				period = workloadPeriod
+			}
+		}
+
+		if groups[period] == nil {
+			groups[period] = make(map[string][]workload)
+		}
+		groups[period][secretPath] = workloads
+	}
+
+	return groups
+}
+
+// runReloaderScheduler runs the reloader every time a group of secrets is due to be checked,
+// until the context is cancelled.
+func (c *Controller) runReloaderScheduler(ctx context.Context, defaultPeriod time.Duration) {
+	scheduler := newPollScheduler()
+	secretGroups := func() map[time.Duration]map[string][]workload {
+		return groupSecretsByPollPeriod(
+			c.workloadSecrets.GetSecretWorkloadsMap(),
+			c.workloadSecrets.GetConfigs(),
+			defaultPeriod,
+		)
+	}
+
+	if c.settleDelay > 0 {
+		c.logger.Info(fmt.Sprintf("Waiting %s for the collected secrets to settle before the first reloader cycle", c.settleDelay))
+	}
+	wait := c.clock.After(c.settleDelay)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-wait:
+		}
+
+		c.runReloader(ctx, scheduler.dueSecrets(secretGroups(), c.clock.Now()))
+
+		wait = c.clock.After(scheduler.nextCheck(secretGroups(), c.clock.Now(), defaultPeriod))
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestGroupSecretsByPollPeriod(t *testing.T) {
+	fast := workload{name: "fast", namespace: "default", kind: DeploymentKind}
+	slow := workload{name: "slow", namespace: "default", kind: DeploymentKind}
+	other := workload{name: "other", namespace: "default", kind: DaemonSetKind}
+
+	secretWorkloads := map[string][]workload{
+		"secret/data/shared": {fast, other},
+		"secret/data/fast":   {fast},
+		"secret/data/slow":   {slow},
+		"secret/data/other":  {other},
+	}
+	configs := map[workload]workloadConfig{
+		fast: {pollPeriod: 10 * time.Second},
+		slow: {pollPeriod: 5 * time.Minute},
+	}
+
+	groups := groupSecretsByPollPeriod(secretWorkloads, configs, time.Minute)
+
+	assert.Equal(t, map[time.Duration]map[string][]workload{
+		// shared secret is checked with the shortest period of its workloads
+		10 * time.Second: {
+			"secret/data/shared": {fast, other},
+			"secret/data/fast":   {fast},
+		},
+		// overrides longer than the default period are honored
+		5 * time.Minute: {
+			"secret/data/slow": {slow},
+		},
+		time.Minute: {
+			"secret/data/other": {other},
+		},
+	}, groups)
+}
+
+func TestPollScheduler(t *testing.T) {
+	fast := workload{name: "fast", namespace: "default", kind: DeploymentKind}
+	other := workload{name: "other", namespace: "default", kind: DaemonSetKind}
+	groups := map[time.Duration]map[string][]workload{
+		10 * time.Second: {"secret/data/fast": {fast}},
+		time.Minute:      {"secret/data/other": {other}},
+	}
+
+	scheduler := newPollScheduler()
+	start := time.Now()
+
+	// every group is due on the first run
+	assert.Equal(t, map[string][]workload{
+		"secret/data/fast":  {fast},
+		"secret/data/other": {other},
+	}, scheduler.dueSecrets(groups, start))
+	assert.Equal(t, 10*time.Second, scheduler.nextCheck(groups, start, time.Minute))
+
+	// only the fast group is due until the default period elapses
+	for i := 1; i < 6; i++ {
+		now := start.Add(time.Duration(i) * 10 * time.Second)
+		assert.Equal(t, map[string][]workload{"secret/data/fast": {fast}}, scheduler.dueSecrets(groups, now))
+	}
+
+	now := start.Add(time.Minute)
+	assert.Equal(t, map[string][]workload{
+		"secret/data/fast":  {fast},
+		"secret/data/other": {other},
+	}, scheduler.dueSecrets(groups, now))
+
+	// nothing is due before the next period
+	assert.Empty(t, scheduler.dueSecrets(groups, now.Add(5*time.Second)))
+	assert.Equal(t, 5*time.Second, scheduler.nextCheck(groups, now.Add(5*time.Second), time.Minute))
+
+	// without groups, the default period is used
+	assert.Equal(t, time.Minute, scheduler.nextCheck(nil, now, time.Minute))
+
+	// groups with a longer period than the default one don't wake the scheduler earlier
+	slowGroups := map[time.Duration]map[string][]workload{5 * time.Minute: {"secret/data/other": {other}}}
+	scheduler = newPollScheduler()
+	scheduler.dueSecrets(slowGroups, now)
+	assert.Equal(t, 5*time.Minute, scheduler.nextCheck(slowGroups, now, time.Minute))
+}
+
+func TestRunReloaderSchedulerSettleDelay(t *testing.T) {
+	controller := newTestController()
+	WithSettleDelay(200 * time.Millisecond)(controller)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startedAt := time.Now()
+	go controller.runReloaderScheduler(ctx, time.Hour)
+
+	// The first cycle only runs once the settle delay elapsed
+	var summary CycleSummary
+	require.Eventually(t, func() bool {
+		var ok bool
+		summary, ok = controller.cycleHistory.latest()
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, summary.Timestamp.Sub(startedAt), 200*time.Millisecond)
+}
+
+func TestRunReloaderSchedulerPollPeriods(t *testing.T) {
+	controller := newTestController()
+	fakeClock := testingclock.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	controller.clock = fakeClock
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/health", "/v1/sys/seal-status":
+			_, _ = io.WriteString(w, `{"initialized": true, "sealed": false}`)
+		default:
+			_, _ = io.WriteString(w, `{"data": {"data": {"password": "secret"}, "metadata": {"version": 1}}}`)
+		}
+	}))
+	defer vaultServer.Close()
+	vaultClient, err := vaultapi.NewClient(&vaultapi.Config{Address: vaultServer.URL})
+	require.NoError(t, err)
+	controller.vaultClient = vaultClient
+
+	fast := workload{name: "fast", namespace: "default", kind: DeploymentKind}
+	slow := workload{name: "slow", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.Store(fast, []string{"secret/data/fast"})
+	controller.workloadSecrets.SetConfig(fast, workloadConfig{pollPeriod: 10 * time.Second})
+	controller.workloadSecrets.Store(slow, []string{"secret/data/slow"})
+	controller.workloadSecrets.SetConfig(slow, workloadConfig{pollPeriod: 25 * time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go controller.runReloaderScheduler(ctx, time.Hour)
+
+	// step advances the clock once the scheduler waits, and returns the number of secrets checked by the cycle it triggered
+	cycles := 0
+	step := func(d time.Duration) int {
+		require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+		fakeClock.Step(d)
+		cycles++
+		require.Eventually(t, func() bool { return len(controller.cycleHistory.list()) == cycles }, time.Second, time.Millisecond)
+		summary, _ := controller.cycleHistory.latest()
+		assert.Equal(t, fakeClock.Now(), summary.Timestamp)
+		return summary.SecretsChecked
+	}
+
+	// Both groups are checked in the first cycle, then each one when its own period elapsed
+	assert.Equal(t, 2, step(0))
+	assert.Equal(t, 1, step(10*time.Second))
+	assert.Equal(t, 1, step(10*time.Second))
+	assert.Equal(t, 1, step(5*time.Second))
+	assert.Equal(t, 1, step(5*time.Second))
+}
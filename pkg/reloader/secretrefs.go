@@ -0,0 +1,77 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// collectionSourceSecretReference is the collection source of secrets collected from the Kubernetes Secrets
+// referenced by the containers of workloads
+const collectionSourceSecretReference = "secret_reference"
+
+// referencedSecretNames returns the sorted names of the Kubernetes Secrets the containers and init containers
+// of the pod template reference with valueFrom.secretKeyRef or envFrom.secretRef.
+func referencedSecretNames(template corev1.PodTemplateSpec) []string {
+	containers := []corev1.Container{}
+	containers = append(containers, template.Spec.Containers...)
+	containers = append(containers, template.Spec.InitContainers...)
+
+	names := []string{}
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				names = append(names, env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil {
+				names = append(names, envFrom.SecretRef.Name)
+			}
+		}
+	}
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
+
+// collectSecretsFromSecretReferences collects the secrets of the vault-from-path annotation of the Kubernetes Secrets
+// referenced by the pod template, e.g. Secrets populated from Vault. The Secrets are used as a whole, as their keys
+// don't match the keys of the Vault secrets. Secrets that don't exist yet are skipped, until the workload is collected again.
+func (c *Controller) collectSecretsFromSecretReferences(namespace string, template corev1.PodTemplateSpec, logger *slog.Logger) []string {
+	vaultSecretPaths := []string{}
+	for _, name := range referencedSecretNames(template) {
+		secret, err := c.kubeClient.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			logger.Debug(fmt.Sprintf("Referenced Secret %s/%s not found, skipping it", namespace, name))
+			continue
+		}
+		if err != nil {
+			logger.Warn(fmt.Errorf("failed to get referenced Secret %s/%s, skipping it: %w", namespace, name, err).Error())
+			continue
+		}
+
+		vaultSecretPaths = append(vaultSecretPaths, c.collectSecretsFromAnnotations(secret.GetAnnotations())...)
+	}
+
+	return vaultSecretPaths
+}
@@ -0,0 +1,95 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"testing"
+
+	"github.com/bank-vaults/secrets-webhook/pkg/common"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCollectWorkloadSecretsSecretReferences(t *testing.T) {
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	populated := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:        "populated",
+		Namespace:   "default",
+		Annotations: map[string]string{common.VaultFromPathAnnotation: "secret/data/db,secret/data/api"},
+	}}
+	bulk := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:        "bulk",
+		Namespace:   "default",
+		Annotations: map[string]string{common.VaultFromPathAnnotation: "secret/data/bulk"},
+	}}
+	plain := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "default"}}
+	deployment := newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"})
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: "PASSWORD", Value: "vault:secret/data/db#password"},
+				{Name: "DB_USER", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "populated"},
+					Key:                  "user",
+				}}},
+				{Name: "PLAIN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "plain"},
+					Key:                  "value",
+				}}},
+			},
+			EnvFrom: []corev1.EnvFromSource{
+				{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}}},
+			},
+		},
+	}
+	deployment.Spec.Template.Spec.InitContainers = []corev1.Container{
+		{
+			Name:    "init",
+			EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "bulk"}}}},
+		},
+	}
+
+	controller := newTestController(populated, bulk, plain)
+	WithSubkeyAwareReload(true)(controller)
+	controller.handleObject(deployment)
+
+	assert.Equal(t, map[workload][]string{
+		testWorkload: {"secret/data/api", "secret/data/bulk", "secret/data/db"},
+	}, controller.workloadSecrets.GetWorkloadSecretsMap())
+	// The secret is also used as a whole through the referenced Secret
+	assert.Empty(t, controller.workloadSecrets.GetSecretKeys()[testWorkload])
+}
+
+func TestReferencedSecretNames(t *testing.T) {
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Env: []corev1.EnvVar{
+				{Name: "A", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "b"}}}},
+				{Name: "B", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "config"}}}},
+			},
+			EnvFrom: []corev1.EnvFromSource{
+				{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "a"}}},
+				{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "config"}}},
+			},
+		}},
+		InitContainers: []corev1.Container{{
+			EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "b"}}}},
+		}},
+	}}
+
+	assert.Equal(t, []string{"a", "b"}, referencedSecretNames(template))
+}
@@ -0,0 +1,92 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceAccountVaultRoleAnnotationName is the ServiceAccount annotation of the Vault role the secrets
+// of workloads running with the ServiceAccount are read with, if no role is configured with VAULT_ROLE.
+const ServiceAccountVaultRoleAnnotationName = "vault.security.banzaicloud.io/vault-role"
+
+// getServiceAccountVaultRole returns the Vault role set on the ServiceAccount of the pod template,
+// or an empty string if a role is configured explicitly, or the ServiceAccount has none.
+func (c *Controller) getServiceAccountVaultRole(namespace string, template corev1.PodTemplateSpec, logger *slog.Logger) string {
+	if c.vaultConfig.Role != "" {
+		return ""
+	}
+
+	serviceAccountName := template.Spec.ServiceAccountName
+	if serviceAccountName == "" {
+		serviceAccountName = "default"
+	}
+
+	serviceAccount, err := c.kubeClient.CoreV1().ServiceAccounts(namespace).Get(context.Background(), serviceAccountName, metav1.GetOptions{})
+	if err != nil {
+		logger.Warn(fmt.Errorf("failed to get ServiceAccount %s/%s, using the default Vault role: %w", namespace, serviceAccountName, err).Error())
+		return ""
+	}
+
+	return serviceAccount.GetAnnotations()[ServiceAccountVaultRoleAnnotationName]
+}
+
+// getSecretVaultRoles returns the Vault role each secret is read with, the first in order among
+// the roles of the workloads using it, secrets of workloads without a role are omitted.
+func (c *Controller) getSecretVaultRoles(secretWorkloads map[string][]workload) map[string]string {
+	configs := c.workloadSecrets.GetConfigs()
+	secretRoles := make(map[string]string)
+	for secretPath, workloads := range secretWorkloads {
+		var roles []string
+		for _, workload := range workloads {
+			if role := configs[workload].vaultRole; role != "" {
+				roles = append(roles, role)
+			}
+		}
+		if len(roles) > 0 {
+			secretRoles[secretPath] = slices.Min(roles)
+		}
+	}
+
+	return secretRoles
+}
+
+// readSecretVersionWithRole reads the secret version with a Vault client logged in with the role,
+// or with vaultClient if the role is empty. Secrets of another Vault than the default one
+// are read with a client of that Vault (see qualifySecretPath).
+func (c *Controller) readSecretVersionWithRole(ctx context.Context, vaultClient vaultSecretReader, role string, secretPath string, logger *slog.Logger) (int, map[string]string, error) {
+	target, secretPath := splitQualifiedSecretPath(secretPath)
+	if target != (vaultTarget{}) {
+		targetVaultClient, err := c.getTargetVaultClient(ctx, target, role)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to initialize Vault client for address %q and namespace %q: %w", target.addr, target.namespace, err)
+		}
+		vaultClient = targetVaultClient.Logical()
+	} else if role != "" {
+		roleVaultClient, err := c.getRoleVaultClient(ctx, role)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to initialize Vault client for role %s: %w", role, err)
+		}
+		vaultClient = roleVaultClient.Logical()
+	}
+
+	return c.readKVSecretVersion(ctx, vaultClient, target, secretPath, logger)
+}
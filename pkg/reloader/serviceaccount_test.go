@@ -0,0 +1,81 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHandleObjectServiceAccountVaultRole(t *testing.T) {
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "default",
+			Annotations: map[string]string{ServiceAccountVaultRoleAnnotationName: "app-role"},
+		},
+	}
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/foo",
+	})
+	deployment.Spec.Template.Spec.ServiceAccountName = "app"
+
+	t.Run("annotated ServiceAccount", func(t *testing.T) {
+		controller := newTestController(serviceAccount)
+		controller.handleObject(deployment)
+
+		assert.Equal(t, "app-role", controller.workloadSecrets.GetConfigs()[testWorkload].vaultRole)
+		assert.Equal(t, map[string]string{"secret/data/foo": "app-role"}, controller.getSecretVaultRoles(controller.workloadSecrets.GetSecretWorkloadsMap()))
+	})
+
+	t.Run("missing ServiceAccount", func(t *testing.T) {
+		controller := newTestController()
+		controller.handleObject(deployment)
+
+		assert.Equal(t, []string{"secret/data/foo"}, controller.workloadSecrets.GetWorkloadSecretsMap()[testWorkload])
+		assert.Empty(t, controller.workloadSecrets.GetConfigs())
+	})
+
+	t.Run("explicit role", func(t *testing.T) {
+		controller := newTestController(serviceAccount)
+		controller.vaultConfig.Role = "reloader"
+		controller.handleObject(deployment)
+
+		assert.Empty(t, controller.workloadSecrets.GetConfigs())
+	})
+}
+
+func TestGetSecretVaultRoles(t *testing.T) {
+	controller := newTestController()
+	workload1 := workload{name: "test1", namespace: "default", kind: DeploymentKind}
+	workload2 := workload{name: "test2", namespace: "default", kind: DeploymentKind}
+	workload3 := workload{name: "test3", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.SetConfig(workload1, workloadConfig{vaultRole: "role-b"})
+	controller.workloadSecrets.SetConfig(workload2, workloadConfig{vaultRole: "role-a"})
+
+	assert.Equal(t, map[string]string{
+		"secret/data/shared": "role-a",
+		"secret/data/foo":    "role-b",
+	}, controller.getSecretVaultRoles(map[string][]workload{
+		"secret/data/shared":  {workload1, workload2, workload3},
+		"secret/data/foo":     {workload1},
+		"secret/data/default": {workload3},
+	}))
+}
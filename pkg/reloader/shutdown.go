@@ -0,0 +1,50 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const shutdownFlushTimeout = 10 * time.Second
+
+// flush logs the summary of the last reloader cycle, handles the pending reloads and calls the shutdown flushes,
+// so the last data point is not lost when the reloader exits.
+func (c *Controller) flush() {
+	if summary, ok := c.cycleHistory.latest(); ok {
+		c.logger.Info("Final reloader cycle summary",
+			slog.Time("timestamp", summary.Timestamp),
+			slog.String("correlation_id", summary.CorrelationID),
+			slog.Int("secrets_checked", summary.SecretsChecked),
+			slog.Int("secrets_changed", summary.SecretsChanged),
+			slog.Int("workloads_reloaded", summary.WorkloadsReloaded),
+			slog.Any("errors", summary.Errors),
+		)
+	}
+
+	// The context of the controller is already cancelled at this point
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+	defer cancel()
+
+	c.handlePendingReloads(ctx)
+	for _, flush := range c.shutdownFlushes {
+		if err := flush(ctx); err != nil {
+			c.logger.Error(fmt.Errorf("failed to flush on shutdown: %w", err).Error())
+		}
+	}
+}
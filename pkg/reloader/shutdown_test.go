@@ -0,0 +1,103 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// syncBuffer is a buffer that can be written by the controller while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunFlushesOnShutdown(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	var logs syncBuffer
+
+	flushed := make(chan error, 2)
+	flush := func(ctx context.Context) error {
+		// The flush gets a live context even though the one of the controller is cancelled
+		flushed <- ctx.Err()
+		return assert.AnError
+	}
+	controller := NewController(
+		slog.New(slog.NewJSONHandler(&logs, nil)),
+		kubeClient,
+		informerFactory.Apps().V1().Deployments(),
+		informerFactory.Apps().V1().DaemonSets(),
+		informerFactory.Apps().V1().StatefulSets(),
+		informerFactory.Batch().V1().CronJobs(),
+		informerFactory.Batch().V1().Jobs(),
+		WithMetricsRegisterer(prometheus.NewRegistry()),
+		WithShutdownFlush(flush),
+		WithShutdownFlush(flush),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	informerFactory.Start(ctx.Done())
+	done := make(chan error)
+	go func() {
+		done <- controller.Run(ctx, time.Hour)
+	}()
+
+	// Wait for the first reloader cycle to finish
+	require.Eventually(t, func() bool {
+		_, ok := controller.cycleHistory.latest()
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, flushed)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not shut down")
+	}
+
+	require.Len(t, flushed, 2)
+	assert.NoError(t, <-flushed)
+	assert.NoError(t, <-flushed)
+	assert.Contains(t, logs.String(), `"msg":"Final reloader cycle summary"`)
+	assert.Contains(t, logs.String(), `"secrets_checked":0`)
+	// A failed flush doesn't prevent the others
+	assert.Contains(t, logs.String(), "failed to flush on shutdown")
+}
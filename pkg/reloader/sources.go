@@ -0,0 +1,87 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Collection sources of secret paths, besides collectionSourceAgentConfigMap
+const (
+	collectionSourceContainerEnv          = "container_env"
+	collectionSourceInitContainerEnv      = "init_container_env"
+	collectionSourcePodTemplateAnnotation = "pod_template_annotation"
+	collectionSourceWorkloadAnnotation    = "workload_annotation"
+)
+
+// secretSources map[secretPath][]source, the collection sources each secret path of a workload was found in
+type secretSources map[string][]string
+
+// add records the source of the secret paths, once per path.
+func (s secretSources) add(source string, secretPaths ...string) {
+	for _, secretPath := range secretPaths {
+		if !slices.Contains(s[secretPath], source) {
+			s[secretPath] = append(s[secretPath], source)
+		}
+	}
+}
+
+// paths returns the sorted secret paths, without duplicates.
+func (s secretSources) paths() []string {
+	return slices.Sorted(maps.Keys(s))
+}
+
+// collectSecretSources collects the secret paths of the env vars of the containers and init containers
+// of the pod template, and of its annotations, along with their source.
+func (c *Controller) collectSecretSources(template corev1.PodTemplateSpec) secretSources {
+	sources := secretSources{}
+	sources.add(collectionSourceContainerEnv, collectSecretsFromContainerEnvVars(template.Spec.Containers)...)
+	sources.add(collectionSourceInitContainerEnv, collectSecretsFromContainerEnvVars(template.Spec.InitContainers)...)
+	sources.add(collectionSourcePodTemplateAnnotation, c.collectSecretsFromAnnotations(template.GetAnnotations())...)
+
+	return sources
+}
+
+// logSecretSources logs the sources of the tracked secret paths of the workload at debug level,
+// following the secret paths as they were resolved and qualified (see resolveSecretPath and qualifySecretPath).
+func (c *Controller) logSecretSources(workload workload, target vaultTarget, sources secretSources, vaultSecretPaths []string, logger *slog.Logger) {
+	tracked := make(map[string][]string, len(vaultSecretPaths))
+	for secretPath, pathSources := range sources {
+		trackedPath := qualifySecretPath(target, c.resolveSecretPath(workload.namespace, secretPath))
+		for _, vaultSecretPath := range vaultSecretPaths {
+			// The secrets expanded from a wildcard path have the sources of the wildcard path
+			if vaultSecretPath != trackedPath && !(isWildcardSecretPath(trackedPath) && matchesWildcardSecretPath(trackedPath, vaultSecretPath)) {
+				continue
+			}
+			for _, source := range pathSources {
+				if !slices.Contains(tracked[vaultSecretPath], source) {
+					tracked[vaultSecretPath] = append(tracked[vaultSecretPath], source)
+				}
+			}
+		}
+	}
+
+	for _, secretPath := range vaultSecretPaths {
+		logger.Debug("Secret path collected",
+			slog.String("workload", workload.kind+" "+workload.namespace+"/"+workload.name),
+			slog.String("path", secretPath),
+			slog.Any("sources", tracked[secretPath]),
+		)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// reloadThreshold is the amount of a workload's secrets that need to change
+// within a cycle to reload it, either as a count or as a percentage.
+type reloadThreshold struct {
+	count   int
+	percent int
+}
+
+var defaultReloadThreshold = reloadThreshold{count: 1}
+
+// parseReloadThreshold parses a threshold in the format of "N" or "N%".
+func parseReloadThreshold(value string) (reloadThreshold, error) {
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.Atoi(percent)
+		if err != nil || p < 1 || p > 100 {
+			return reloadThreshold{}, fmt.Errorf("invalid reload threshold percentage %q, must be between 1%% and 100%%", value)
+		}
+		return reloadThreshold{percent: p}, nil
+	}
+
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 {
+		return reloadThreshold{}, fmt.Errorf("invalid reload threshold %q, must be a positive number or a percentage", value)
+	}
+
+	return reloadThreshold{count: count}, nil
+}
+
+// reached reports whether enough of the workload's secrets changed to reload it.
+func (t reloadThreshold) reached(changed, total int) bool {
+	if t.percent > 0 {
+		return changed*100 >= t.percent*total
+	}
+
+	return changed >= t.count
+}
+
+func (t reloadThreshold) String() string {
+	if t.percent > 0 {
+		return fmt.Sprintf("%d%%", t.percent)
+	}
+
+	return strconv.Itoa(t.count)
+}
+
+// filterByReloadThreshold removes the workloads from workloadsToReload that
+// don't have enough of their secrets changed to reach their reload threshold.
+func (c *Controller) filterByReloadThreshold(workloadsToReload map[workload][]secretChange, logger *slog.Logger) {
+	workloadSecrets := c.workloadSecrets.GetWorkloadSecretsMap()
+	configs := c.workloadSecrets.GetConfigs()
+
+	for workload, changes := range workloadsToReload {
+		threshold := c.reloadThreshold
+		if override := configs[workload].reloadThreshold; override != nil {
+			threshold = *override
+		}
+
+		total := len(workloadSecrets[workload])
+		if !threshold.reached(len(changes), total) {
+			logger.Info(fmt.Sprintf(
+				"Skipping reload of workload %s, %d of its %d secrets changed, threshold is %s",
+				workload, len(changes), total, threshold,
+			))
+			delete(workloadsToReload, workload)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReloadThreshold(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected reloadThreshold
+		err      bool
+	}{
+		{value: "1", expected: reloadThreshold{count: 1}},
+		{value: "3", expected: reloadThreshold{count: 3}},
+		{value: "50%", expected: reloadThreshold{percent: 50}},
+		{value: "100%", expected: reloadThreshold{percent: 100}},
+		{value: "0", err: true},
+		{value: "-1", err: true},
+		{value: "0%", err: true},
+		{value: "101%", err: true},
+		{value: "half", err: true},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.value, func(t *testing.T) {
+			threshold, err := parseReloadThreshold(ttp.value)
+			if ttp.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, ttp.expected, threshold)
+			assert.Equal(t, ttp.value, threshold.String())
+		})
+	}
+}
+
+func TestReloadThresholdReached(t *testing.T) {
+	assert.True(t, reloadThreshold{count: 1}.reached(1, 4))
+	assert.False(t, reloadThreshold{count: 2}.reached(1, 4))
+	assert.True(t, reloadThreshold{count: 2}.reached(2, 4))
+	assert.False(t, reloadThreshold{percent: 50}.reached(1, 4))
+	assert.True(t, reloadThreshold{percent: 50}.reached(2, 4))
+	assert.False(t, reloadThreshold{percent: 50}.reached(1, 3))
+	assert.True(t, reloadThreshold{percent: 100}.reached(3, 3))
+}
+
+func TestFilterByReloadThreshold(t *testing.T) {
+	controller := newTestController()
+	controller.reloadThreshold = reloadThreshold{count: 2}
+
+	defaultThreshold := workload{name: "default", namespace: "default", kind: DeploymentKind}
+	overridden := workload{name: "overridden", namespace: "default", kind: DeploymentKind}
+	notReached := workload{name: "not-reached", namespace: "default", kind: DeploymentKind}
+
+	controller.workloadSecrets.Store(defaultThreshold, []string{"secret/data/a", "secret/data/b", "secret/data/c"})
+	controller.workloadSecrets.Store(overridden, []string{"secret/data/a", "secret/data/b", "secret/data/c", "secret/data/d"})
+	controller.workloadSecrets.SetConfig(overridden, workloadConfig{reloadThreshold: &reloadThreshold{percent: 25}})
+	controller.workloadSecrets.Store(notReached, []string{"secret/data/a", "secret/data/c"})
+
+	changeA := secretChange{path: "secret/data/a", oldVersion: 1, newVersion: 2}
+	changeB := secretChange{path: "secret/data/b", oldVersion: 1, newVersion: 2}
+	workloadsToReload := map[workload][]secretChange{
+		defaultThreshold: {changeA, changeB},
+		overridden:       {changeA},
+		notReached:       {changeA},
+	}
+
+	controller.filterByReloadThreshold(workloadsToReload, controller.logger)
+
+	assert.Equal(t, map[workload][]secretChange{
+		defaultThreshold: {changeA, changeB},
+		overridden:       {changeA},
+	}, workloadsToReload)
+}
@@ -16,12 +16,18 @@ package reloader
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bank-vaults/vault-sdk/vault"
@@ -40,8 +46,37 @@ type VaultConfig struct {
 	TLSSecretNS          string
 	ClientTimeout        time.Duration
 	IgnoreMissingSecrets bool
+	ReadTimeout          time.Duration
+	PathPrefix           string
+	TokenReloginTTL      time.Duration
+	RoleID               string
+	SecretID             string
+	SecretIDFile         string
+	LoginMaxRetries      int
+	FallbackAuthMethod   string
+	FallbackPath         string
+	ReloadOnDelete       bool
 }
 
+// vaultAuth is an auth method of Vault and the path it's mounted at
+type vaultAuth struct {
+	method string
+	path   string
+}
+
+// AppRoleAuthMethod logs in to Vault with the role_id and secret_id of an AppRole, instead of
+// the token of the ServiceAccount of the Reloader
+const AppRoleAuthMethod = "approle"
+
+// defaultTokenReloginTTL is the remaining TTL of the Vault token below which the client logs in again by default
+const defaultTokenReloginTTL = 2 * time.Minute
+
+const (
+	// vaultLoginInitialBackoff is the time to wait before the first retry of a failed Vault login, doubled every retry
+	vaultLoginInitialBackoff = time.Second
+	vaultLoginMaxBackoff     = 30 * time.Second
+)
+
 func getVaultConfigFromEnv() *VaultConfig {
 	var vaultConfig VaultConfig
 
@@ -60,8 +95,16 @@ func getVaultConfigFromEnv() *VaultConfig {
 	vaultConfig.Path = os.Getenv("VAULT_PATH")
 	if vaultConfig.Path == "" {
 		vaultConfig.Path = "kubernetes"
+		if vaultConfig.AuthMethod == AppRoleAuthMethod {
+			vaultConfig.Path = "approle"
+		}
 	}
 
+	vaultConfig.RoleID = os.Getenv("VAULT_ROLE_ID")
+	vaultConfig.SecretID = os.Getenv("VAULT_SECRET_ID")
+	// Read on every login, so a rotated secret_id is picked up
+	vaultConfig.SecretIDFile = os.Getenv("VAULT_SECRET_ID_FILE")
+
 	vaultConfig.Namespace = os.Getenv("VAULT_NAMESPACE")
 	if vaultConfig.Namespace == "" {
 		vaultConfig.Namespace = "default"
@@ -83,35 +126,255 @@ func getVaultConfigFromEnv() *VaultConfig {
 
 	vaultConfig.IgnoreMissingSecrets, _ = strconv.ParseBool(os.Getenv("VAULT_IGNORE_MISSING_SECRETS"))
 
+	// Zero means reads are only limited by the client timeout
+	vaultConfig.ReadTimeout, _ = time.ParseDuration(os.Getenv("VAULT_READ_TIMEOUT"))
+
+	// Prepended to the paths of all secret reads, e.g. for an API gateway routing requests to Vault
+	vaultConfig.PathPrefix = strings.Trim(os.Getenv("VAULT_PATH_PREFIX"), "/")
+
+	// Zero disables logging in again before the token expires
+	vaultConfig.TokenReloginTTL = defaultTokenReloginTTL
+	if value, ok := os.LookupEnv("VAULT_TOKEN_RELOGIN_TTL"); ok {
+		vaultConfig.TokenReloginTTL, _ = time.ParseDuration(value)
+	}
+
+	// Zero only attempts to log in once per cycle
+	vaultConfig.LoginMaxRetries, _ = strconv.Atoi(os.Getenv("VAULT_LOGIN_MAX_RETRIES"))
+	vaultConfig.LoginMaxRetries = max(vaultConfig.LoginMaxRetries, 0)
+
+	// Tried if logging in with VAULT_AUTH_METHOD fails, e.g. "kubernetes" if both jwt and kubernetes auth are enabled
+	vaultConfig.FallbackAuthMethod = os.Getenv("VAULT_FALLBACK_AUTH_METHOD")
+	vaultConfig.FallbackPath = os.Getenv("VAULT_FALLBACK_PATH")
+	if vaultConfig.FallbackPath == "" {
+		vaultConfig.FallbackPath = "kubernetes"
+	}
+
+	// Deleting or undeleting the latest version of a KV v2 secret reloads its workloads
+	vaultConfig.ReloadOnDelete, _ = strconv.ParseBool(os.Getenv("VAULT_RELOAD_ON_DELETE"))
+
 	return &vaultConfig
 }
 
-func (c *Controller) initVaultClient() error {
+// getVaultClient returns a Vault client with a valid connection, (re)initializing it if needed.
+func (c *Controller) getVaultClient(ctx context.Context) (*vaultapi.Client, error) {
+	c.vaultClientMu.Lock()
+	defer c.vaultClientMu.Unlock()
+
+	err := c.initVaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.vaultClient, nil
+}
+
+// errVaultLoginInProgress is returned for the Vault clients while another goroutine waits to retry logging in
+var errVaultLoginInProgress = errors.New("logging in to Vault is being retried")
+
+// initVaultClient (re)initializes the default Vault client if it's not valid, must be called with vaultClientMu held.
+func (c *Controller) initVaultClient(ctx context.Context) error {
 	if c.vaultClient != nil {
 		_, err := c.vaultClient.Sys().Health()
-		if err == nil {
+		if err == nil && !c.vaultTokenExpiring(c.vaultClient.Auth().Token()) {
 			// Client is valid, no need to init
 			return nil
 		}
-		// log error and continue with (re)creating client
-		c.logger.Error("connection to Vault lost, recreating client")
+		if err != nil {
+			// log error and continue with (re)creating client
+			c.logger.Error("connection to Vault lost, recreating client")
+		}
+	}
+
+	// Callers don't wait for the login retries of another goroutine, so they are not blocked by its backoff
+	if c.vaultLoginRetrying {
+		return errVaultLoginInProgress
 	}
 
 	c.logger.Info("Initializing Vault client")
 
-	c.vaultConfig = getVaultConfigFromEnv()
+	if err := validateVaultPathPrefix(c.vaultConfig.PathPrefix); err != nil {
+		return fmt.Errorf("invalid VAULT_PATH_PREFIX: %w", err)
+	}
+	vaultClient, err := c.loginWithRetries(ctx, func() (*vaultapi.Client, error) {
+		return c.loginWithAuthFallback(c.vaultConfig.Role)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.vaultClient = vaultClient
+	// The clients of the other roles and Vaults are recreated along with the default one
+	c.roleVaultClients = nil
+	c.targetVaultClients = nil
+	c.logger.Info("Vault client initialized")
+	return nil
+}
+
+// loginWithRetries logs in to Vault, retrying up to VAULT_LOGIN_MAX_RETRIES times with an exponential backoff
+// if it fails, e.g. while Vault is still initializing or unsealed on startup, until the context is done.
+// It must be called with vaultClientMu held, which is released while waiting for the next attempt.
+func (c *Controller) loginWithRetries(ctx context.Context, login func() (*vaultapi.Client, error)) (*vaultapi.Client, error) {
+	backoff := vaultLoginInitialBackoff
+	for attempt := 0; ; attempt++ {
+		vaultClient, err := login()
+		if err == nil || attempt >= c.vaultConfig.LoginMaxRetries {
+			return vaultClient, err
+		}
+
+		c.logger.Warn(fmt.Errorf("failed to log in to Vault, retrying in %s (%d/%d): %w", backoff, attempt+1, c.vaultConfig.LoginMaxRetries, err).Error())
+		if err := c.waitForLoginRetry(ctx, backoff); err != nil {
+			return nil, err
+		}
+		backoff = min(backoff*2, vaultLoginMaxBackoff)
+	}
+}
+
+// waitForLoginRetry waits for the backoff or the context to be done, without holding vaultClientMu.
+func (c *Controller) waitForLoginRetry(ctx context.Context, backoff time.Duration) error {
+	c.vaultLoginRetrying = true
+	c.vaultClientMu.Unlock()
+	defer func() {
+		c.vaultClientMu.Lock()
+		c.vaultLoginRetrying = false
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.clock.After(backoff):
+		return nil
+	}
+}
+
+// loginWithAuthFallback logs in to Vault with the configured auth method, or with VAULT_FALLBACK_AUTH_METHOD
+// if that fails and a fallback is set, e.g. when the jwt auth method or its role doesn't exist. The auth method
+// that succeeded is used by the clients of the other roles and Vaults as well.
+func (c *Controller) loginWithAuthFallback(role string) (*vaultapi.Client, error) {
+	auth := vaultAuth{method: c.vaultConfig.AuthMethod, path: c.vaultConfig.Path}
+	fallback := vaultAuth{method: c.vaultConfig.FallbackAuthMethod, path: c.vaultConfig.FallbackPath}
+	vaultClient, err := c.newVaultClientWithAuth(vaultTarget{}, role, auth)
+	if err == nil || fallback.method == "" || fallback == auth {
+		if err == nil {
+			c.vaultAuth = auth
+			c.logger.Info(fmt.Sprintf("Logged in to Vault with the %s auth method at auth/%s", auth.method, auth.path))
+		}
+		return vaultClient, err
+	}
+
+	c.logger.Warn(fmt.Errorf("failed to log in to Vault with the %s auth method at auth/%s, falling back to the %s auth method at auth/%s: %w",
+		auth.method, auth.path, fallback.method, fallback.path, err).Error())
+	vaultClient, fallbackErr := c.newVaultClientWithAuth(vaultTarget{}, role, fallback)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("failed to log in to Vault with the %s auth method (%w) and the fallback %s auth method: %w", auth.method, err, fallback.method, fallbackErr)
+	}
+
+	c.vaultAuth = fallback
+	c.logger.Info(fmt.Sprintf("Logged in to Vault with the fallback %s auth method at auth/%s", fallback.method, fallback.path))
+	return vaultClient, nil
+}
+
+// vaultTokenLookup looks up the token of a Vault client
+type vaultTokenLookup interface {
+	LookupSelf() (*vaultapi.Secret, error)
+}
+
+// vaultTokenExpiring reports whether the token of the Vault client expires within the re-login TTL, e.g. as it
+// reached its max TTL and can't be renewed anymore, so the client logs in again before the token expires.
+// Tokens that are rejected as invalid are replaced as well, other tokens that can't be looked up or
+// don't expire are kept.
+func (c *Controller) vaultTokenExpiring(token vaultTokenLookup) bool {
+	if c.vaultConfig == nil || c.vaultConfig.TokenReloginTTL <= 0 {
+		return false
+	}
+
+	secret, err := token.LookupSelf()
+	var responseErr *vaultapi.ResponseError
+	if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusForbidden {
+		// The token already expired, e.g. as it wasn't renewed between two cycles
+		c.logger.Info("Vault token is not valid anymore, logging in again")
+		return true
+	}
+	if err != nil {
+		c.logger.Debug(fmt.Errorf("failed to look up Vault token: %w", err).Error())
+		return false
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil || ttl <= 0 || ttl > c.vaultConfig.TokenReloginTTL {
+		return false
+	}
+
+	c.logger.Info(fmt.Sprintf("Vault token expires in %s, logging in again", ttl))
+	return true
+}
+
+// getRoleVaultClient returns a Vault client logged in with the role, (re)initializing the default client if needed.
+func (c *Controller) getRoleVaultClient(ctx context.Context, role string) (*vaultapi.Client, error) {
+	c.vaultClientMu.Lock()
+	defer c.vaultClientMu.Unlock()
+
+	err := c.initVaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// AppRole logins don't depend on the role of the workload
+	if role == c.vaultConfig.Role || c.vaultConfig.AuthMethod == AppRoleAuthMethod {
+		return c.vaultClient, nil
+	}
+	if vaultClient, ok := c.roleVaultClients[role]; ok {
+		return vaultClient, nil
+	}
+
+	c.logger.Info(fmt.Sprintf("Initializing Vault client for role %s", role))
+	vaultClient, err := c.newVaultClient(role)
+	if err != nil {
+		return nil, err
+	}
+	if c.roleVaultClients == nil {
+		c.roleVaultClients = make(map[string]*vaultapi.Client)
+	}
+	c.roleVaultClients[role] = vaultClient
+
+	return vaultClient, nil
+}
+
+// newVaultClient returns a Vault client configured with c.vaultConfig, logged in with the role.
+func (c *Controller) newVaultClient(role string) (*vaultapi.Client, error) {
+	return c.newVaultClientForTarget(vaultTarget{}, role)
+}
+
+// newVaultClientForTarget returns a Vault client configured with c.vaultConfig, logged in with the role,
+// to the address and namespace of the target if set, with the auth method the default client logged in with.
+func (c *Controller) newVaultClientForTarget(target vaultTarget, role string) (*vaultapi.Client, error) {
+	auth := c.vaultAuth
+	if auth == (vaultAuth{}) {
+		auth = vaultAuth{method: c.vaultConfig.AuthMethod, path: c.vaultConfig.Path}
+	}
+
+	return c.newVaultClientWithAuth(target, role, auth)
+}
+
+// newVaultClientWithAuth returns a Vault client configured with c.vaultConfig, logged in with the role and auth method,
+// to the address and namespace of the target if set.
+func (c *Controller) newVaultClientWithAuth(target vaultTarget, role string, auth vaultAuth) (*vaultapi.Client, error) {
 	clientConfig := vaultapi.DefaultConfig()
 	if clientConfig.Error != nil {
-		return clientConfig.Error
+		return nil, clientConfig.Error
 	}
 
 	clientConfig.Address = c.vaultConfig.Addr
+	if target.addr != "" {
+		clientConfig.Address = target.addr
+	}
+	namespace := c.vaultConfig.Namespace
+	if target.namespace != "" {
+		namespace = target.namespace
+	}
 	clientConfig.Timeout = c.vaultConfig.ClientTimeout
 
 	tlsConfig := vaultapi.TLSConfig{Insecure: c.vaultConfig.SkipVerify}
 	err := clientConfig.ConfigureTLS(&tlsConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if c.vaultConfig.TLSSecret != "" {
@@ -121,7 +384,7 @@ func (c *Controller) initVaultClient() error {
 			metav1.GetOptions{},
 		)
 		if err != nil {
-			return fmt.Errorf("failed to read Vault TLS Secret: %s", err.Error())
+			return nil, fmt.Errorf("failed to read Vault TLS Secret: %s", err.Error())
 		}
 
 		clientTLSConfig := clientConfig.HttpClient.Transport.(*http.Transport).TLSClientConfig
@@ -130,34 +393,103 @@ func (c *Controller) initVaultClient() error {
 
 		ok := pool.AppendCertsFromPEM(tlsSecret.Data["ca.crt"])
 		if !ok {
-			return fmt.Errorf("error loading Vault CA PEM from TLS Secret: %s", tlsSecret.Name)
+			return nil, fmt.Errorf("error loading Vault CA PEM from TLS Secret: %s", tlsSecret.Name)
 		}
 
 		clientTLSConfig.RootCAs = pool
 	}
 
+	if auth.method == AppRoleAuthMethod {
+		return c.newAppRoleVaultClient(clientConfig, namespace, auth.path)
+	}
+
 	vaultClient, err := vault.NewClientFromConfig(
 		clientConfig,
-		vault.ClientRole(c.vaultConfig.Role),
-		vault.ClientAuthPath(c.vaultConfig.Path),
-		vault.ClientAuthMethod(c.vaultConfig.AuthMethod),
+		vault.ClientRole(role),
+		vault.ClientAuthPath(auth.path),
+		vault.ClientAuthMethod(auth.method),
 		vault.ClientLogger(&clientLogger{logger: c.logger}),
-		vault.VaultNamespace(c.vaultConfig.Namespace),
+		vault.VaultNamespace(namespace),
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	//
 	// Check connection to Vault
 	_, err = vaultClient.RawClient().Sys().Health()
 	if err != nil {
 		c.logger.Error("testing connection to Vault failed")
-		return err
+		return nil, err
 	}
 
-	c.vaultClient = vaultClient.RawClient()
-	c.logger.Info("Vault client initialized")
-	return nil
+	return vaultClient.RawClient(), nil
+}
+
+// newAppRoleVaultClient returns a Vault client of the namespace logged in with the AppRole credentials of c.vaultConfig
+// at the auth path. The token isn't renewed, the client logs in again once it's about to expire (see vaultTokenExpiring).
+func (c *Controller) newAppRoleVaultClient(clientConfig *vaultapi.Config, namespace, authPath string) (*vaultapi.Client, error) {
+	vaultClient, err := vaultapi.NewClient(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	if namespace != "" {
+		vaultClient.SetNamespace(namespace)
+	}
+
+	secretID := c.vaultConfig.SecretID
+	if c.vaultConfig.SecretIDFile != "" {
+		content, err := os.ReadFile(c.vaultConfig.SecretIDFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read VAULT_SECRET_ID_FILE: %w", err)
+		}
+		secretID = strings.TrimSpace(string(content))
+	}
+	if c.vaultConfig.RoleID == "" {
+		return nil, fmt.Errorf("VAULT_ROLE_ID must be set for the %s auth method", AppRoleAuthMethod)
+	}
+
+	data := map[string]interface{}{"role_id": c.vaultConfig.RoleID}
+	// The secret_id is optional, if the AppRole doesn't require it
+	if secretID != "" {
+		data["secret_id"] = secretID
+	}
+	secret, err := vaultClient.Logical().Write(fmt.Sprintf("auth/%s/login", authPath), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in to Vault with AppRole: %w", err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("failed to log in to Vault with AppRole: no token in the response")
+	}
+	vaultClient.SetToken(secret.Auth.ClientToken)
+	c.logger.Info(fmt.Sprintf("Logged in to Vault with AppRole, token TTL %s", time.Duration(secret.Auth.LeaseDuration)*time.Second))
+
+	return vaultClient, nil
+}
+
+type vaultSealStatusReader interface {
+	SealStatus() (*vaultapi.SealStatusResponse, error)
+}
+
+// vaultSealedUnknown is the value of the Vault sealed metric when the seal status couldn't be checked,
+// e.g. because the Vault client couldn't be initialized
+const vaultSealedUnknown = -1
+
+// checkVaultSealed reports whether Vault is sealed, in which case no secrets can be read.
+func (c *Controller) checkVaultSealed(vaultSys vaultSealStatusReader, logger *slog.Logger) (bool, error) {
+	sealStatus, err := vaultSys.SealStatus()
+	if err != nil {
+		c.metrics.vaultSealed.Set(vaultSealedUnknown)
+		return false, err
+	}
+
+	if sealStatus.Sealed {
+		c.metrics.vaultSealed.Set(1)
+		logger.Warn("Vault is sealed, secrets cannot be checked until it is unsealed")
+		return true, nil
+	}
+
+	c.metrics.vaultSealed.Set(0)
+	return false, nil
 }
 
 type ErrSecretNotFound struct {
@@ -168,22 +500,195 @@ func (e ErrSecretNotFound) Error() string {
 	return fmt.Sprintf("Vault secret path %s not found", e.secretPath)
 }
 
+// ErrSecretDeleted is returned for a KV v2 secret whose latest version is deleted or destroyed,
+// if reloading on deletion is enabled.
+type ErrSecretDeleted struct {
+	secretPath string
+	version    int
+}
+
+func (e ErrSecretDeleted) Error() string {
+	return fmt.Sprintf("latest version %d of Vault secret path %s is deleted", e.version, e.secretPath)
+}
+
 type vaultSecretReader interface {
-	Read(path string) (*vaultapi.Secret, error)
+	ReadWithContext(ctx context.Context, path string) (*vaultapi.Secret, error)
+}
+
+// prefixedSecretReader reads secrets with the prefix prepended to their paths,
+// so secrets keep being tracked and reported by their own path.
+type prefixedSecretReader struct {
+	reader vaultSecretReader
+	prefix string
+}
+
+func (r *prefixedSecretReader) ReadWithContext(ctx context.Context, path string) (*vaultapi.Secret, error) {
+	return r.reader.ReadWithContext(ctx, applyVaultPathPrefix(r.prefix, path))
+}
+
+// applyVaultPathPrefix prepends the prefix to the secret path as whole path segments.
+func applyVaultPathPrefix(prefix, secretPath string) string {
+	if prefix == "" {
+		return secretPath
+	}
+
+	return prefix + "/" + strings.TrimPrefix(secretPath, "/")
+}
+
+// validateVaultPathPrefix checks that the prefix only adds path segments in front of the mount of secrets,
+// so the "data" and "metadata" segments following the mount of KV v2 secrets remain the first ones of prefixed paths.
+func validateVaultPathPrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if strings.ContainsAny(prefix, "?#") {
+		return fmt.Errorf("path prefix %q must not contain a query or fragment", prefix)
+	}
+
+	for _, segment := range strings.Split(prefix, "/") {
+		switch segment {
+		case "", ".", "..":
+			return fmt.Errorf("path prefix %q must not contain empty or relative segments", prefix)
+		case "data", "metadata":
+			return fmt.Errorf("path prefix %q must not contain a %q segment, as it's reserved for KV v2 paths", prefix, segment)
+		}
+	}
+
+	return nil
+}
+
+// readSecretVersion gets the current version of a secret, limiting the read with the configured read timeout,
+// and reading it through the configured path prefix, if any.
+// With subkey-aware reloading or missing key detection, the hashes of the values of the secret's keys are also returned.
+func (c *Controller) readSecretVersion(ctx context.Context, vaultClient vaultSecretReader, secretPath string, logger *slog.Logger) (int, map[string]string, error) {
+	if c.vaultConfig.ReadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.vaultConfig.ReadTimeout)
+		defer cancel()
+	}
+
+	if c.vaultConfig.PathPrefix != "" {
+		vaultClient = &prefixedSecretReader{reader: vaultClient, prefix: c.vaultConfig.PathPrefix}
+	}
+
+	secret, err := readSecretFromVault(ctx, vaultClient, secretPath, logger)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	version, err := getSecretVersion(secret, secretPath)
+	if err == nil && version.deleted && c.vaultConfig.ReloadOnDelete {
+		return version.version, nil, ErrSecretDeleted{secretPath: secretPath, version: version.version}
+	}
+	if c.changeDetection == ChangeDetectionWorkloadHash {
+		// Unversioned secrets, e.g. KV v1, are compared by their data alone
+		if err != nil {
+			version.version = 0
+		}
+		return version.version, hashSecretKeys(secret), nil
+	}
+	if err != nil || !c.keyHashesNeeded() {
+		return version.version, nil, err
+	}
+
+	return version.version, hashSecretKeys(secret), nil
+}
+
+// secretVersion is the current version of a secret, and whether it's deleted in case of KV v2 secrets.
+type secretVersion struct {
+	version int
+	deleted bool
+}
+
+func getSecretVersionFromVault(ctx context.Context, vaultClient vaultSecretReader, secretPath string, logger *slog.Logger) (secretVersion, error) {
+	secret, err := readSecretFromVault(ctx, vaultClient, secretPath, logger)
+	if err != nil {
+		return secretVersion{}, err
+	}
+
+	return getSecretVersion(secret, secretPath)
 }
 
-func getSecretVersionFromVault(vaultClient vaultSecretReader, secretPath string) (int, error) {
-	secret, err := vaultClient.Read(secretPath)
+// readSecretFromVault reads a secret, logging the warnings Vault returned with it.
+func readSecretFromVault(ctx context.Context, vaultClient vaultSecretReader, secretPath string, logger *slog.Logger) (*vaultapi.Secret, error) {
+	secret, err := vaultClient.ReadWithContext(ctx, secretPath)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	if secret != nil {
-		secretVersion, err := secret.Data["metadata"].(map[string]interface{})["version"].(json.Number).Int64()
+	if secret != nil && len(secret.Warnings) > 0 {
+		logger.Debug(fmt.Sprintf("Vault returned warnings reading secret %s: %s", secretPath, strings.Join(secret.Warnings, "; ")))
+	}
+	// Vault may respond with warnings only, e.g. for deprecated paths
+	if secret == nil || len(secret.Data) == 0 {
+		return nil, ErrSecretNotFound{secretPath: secretPath}
+	}
+
+	return secret, nil
+}
+
+func getSecretVersion(secret *vaultapi.Secret, secretPath string) (secretVersion, error) {
+	if metadata, ok := secret.Data["metadata"].(map[string]interface{}); ok {
+		version, ok := metadata["version"].(json.Number)
+		if !ok {
+			return secretVersion{}, fmt.Errorf("secret path %s has no version in its metadata", secretPath)
+		}
+		kvVersion, err := version.Int64()
 		if err != nil {
-			return 0, err
+			return secretVersion{}, err
+		}
+		// Soft deleted versions have a deletion time, destroyed ones are flagged
+		deletionTime, _ := metadata["deletion_time"].(string)
+		destroyed, _ := metadata["destroyed"].(bool)
+		return secretVersion{version: int(kvVersion), deleted: deletionTime != "" || destroyed}, nil
+	}
+
+	// PKI certificates have no KV version, so they are tracked by their expiry
+	if certificate, ok := secret.Data["certificate"].(string); ok {
+		version, err := getCertificateVersion(certificate)
+		return secretVersion{version: version}, err
+	}
+
+	return secretVersion{}, fmt.Errorf("secret path %s has neither a KV version nor a PKI certificate", secretPath)
+}
+
+// hashSecretKeys returns the SHA-256 hashes of the values of a KV secret's keys,
+// so changes of specific keys can be detected without keeping the secret's values in memory.
+func hashSecretKeys(secret *vaultapi.Secret) map[string]string {
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		// Deleted KV v2 secrets have no data
+		if _, versioned := secret.Data["metadata"]; versioned {
+			return nil
 		}
-		return int(secretVersion), nil
+		// KV v1 secrets keep their keys at the top level
+		data = secret.Data
+	}
+
+	hashes := make(map[string]string, len(data))
+	for key, value := range data {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		hash := sha256.Sum256(encoded)
+		hashes[key] = hex.EncodeToString(hash[:])
+	}
+
+	return hashes
+}
+
+// getCertificateVersion returns the expiry of a PEM encoded certificate as a version,
+// which changes every time the certificate is re-issued.
+func getCertificateVersion(certificate string) (int, error) {
+	block, _ := pem.Decode([]byte(certificate))
+	if block == nil {
+		return 0, fmt.Errorf("failed to decode PEM certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse certificate: %w", err)
 	}
 
-	return 0, ErrSecretNotFound{secretPath: secretPath}
+	return int(cert.NotAfter.Unix()), nil
 }
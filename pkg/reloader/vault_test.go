@@ -15,17 +15,39 @@
 package reloader
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func TestGetVaultConfigFromEnv(t *testing.T) {
 	t.Run("default config", func(t *testing.T) {
+		// Unset for the test only, restored afterwards
+		t.Setenv("VAULT_ADDR", "")
 		os.Unsetenv("VAULT_ADDR")
 		defaults := VaultConfig{
 			Addr:                 "https://vault:8200",
@@ -38,6 +60,10 @@ func TestGetVaultConfigFromEnv(t *testing.T) {
 			TLSSecretNS:          "default",
 			ClientTimeout:        10 * time.Second,
 			IgnoreMissingSecrets: false,
+			ReadTimeout:          0,
+			PathPrefix:           "",
+			TokenReloginTTL:      2 * time.Minute,
+			FallbackPath:         "kubernetes",
 		}
 
 		vaultConfig := getVaultConfigFromEnv()
@@ -45,16 +71,23 @@ func TestGetVaultConfigFromEnv(t *testing.T) {
 	})
 
 	t.Run("custom config", func(t *testing.T) {
-		os.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
-		os.Setenv("VAULT_AUTH_METHOD", "kubernetes")
-		os.Setenv("VAULT_ROLE", "test")
-		os.Setenv("VAULT_PATH", "test")
-		os.Setenv("VAULT_NAMESPACE", "test")
-		os.Setenv("VAULT_SKIP_VERIFY", "true")
-		os.Setenv("VAULT_TLS_SECRET", "test")
-		os.Setenv("VAULT_TLS_SECRET_NS", "test")
-		os.Setenv("VAULT_CLIENT_TIMEOUT", "1m")
-		os.Setenv("VAULT_IGNORE_MISSING_SECRETS", "true")
+		t.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
+		t.Setenv("VAULT_AUTH_METHOD", "kubernetes")
+		t.Setenv("VAULT_ROLE", "test")
+		t.Setenv("VAULT_PATH", "test")
+		t.Setenv("VAULT_NAMESPACE", "test")
+		t.Setenv("VAULT_SKIP_VERIFY", "true")
+		t.Setenv("VAULT_TLS_SECRET", "test")
+		t.Setenv("VAULT_TLS_SECRET_NS", "test")
+		t.Setenv("VAULT_CLIENT_TIMEOUT", "1m")
+		t.Setenv("VAULT_IGNORE_MISSING_SECRETS", "true")
+		t.Setenv("VAULT_READ_TIMEOUT", "5s")
+		t.Setenv("VAULT_PATH_PREFIX", "/gateway/vault/")
+		t.Setenv("VAULT_TOKEN_RELOGIN_TTL", "30s")
+		t.Setenv("VAULT_LOGIN_MAX_RETRIES", "5")
+		t.Setenv("VAULT_FALLBACK_AUTH_METHOD", "jwt")
+		t.Setenv("VAULT_FALLBACK_PATH", "jwt")
+		t.Setenv("VAULT_RELOAD_ON_DELETE", "true")
 
 		defaults := VaultConfig{
 			Addr:                 "http://127.0.0.1:8200",
@@ -67,11 +100,74 @@ func TestGetVaultConfigFromEnv(t *testing.T) {
 			TLSSecretNS:          "test",
 			ClientTimeout:        1 * time.Minute,
 			IgnoreMissingSecrets: true,
+			ReadTimeout:          5 * time.Second,
+			PathPrefix:           "gateway/vault",
+			TokenReloginTTL:      30 * time.Second,
+			LoginMaxRetries:      5,
+			FallbackAuthMethod:   "jwt",
+			FallbackPath:         "jwt",
+			ReloadOnDelete:       true,
 		}
 
 		vaultConfig := getVaultConfigFromEnv()
 		assert.Equal(t, defaults, *vaultConfig)
 	})
+
+	t.Run("approle config", func(t *testing.T) {
+		t.Setenv("VAULT_AUTH_METHOD", "approle")
+		t.Setenv("VAULT_PATH", "")
+		t.Setenv("VAULT_ROLE_ID", "reloader-role-id")
+		t.Setenv("VAULT_SECRET_ID", "reloader-secret-id")
+		t.Setenv("VAULT_SECRET_ID_FILE", "/vault/approle/secret-id")
+
+		vaultConfig := getVaultConfigFromEnv()
+		assert.Equal(t, AppRoleAuthMethod, vaultConfig.AuthMethod)
+		// The AppRole auth method is mounted at approle by default
+		assert.Equal(t, "approle", vaultConfig.Path)
+		assert.Equal(t, "reloader-role-id", vaultConfig.RoleID)
+		assert.Equal(t, "reloader-secret-id", vaultConfig.SecretID)
+		assert.Equal(t, "/vault/approle/secret-id", vaultConfig.SecretIDFile)
+	})
+}
+
+func TestNewVaultClientAppRole(t *testing.T) {
+	var logins []map[string]interface{}
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/approle/login" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var login map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&login); err != nil || login["role_id"] != "role-id" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		logins = append(logins, login)
+		_, _ = io.WriteString(w, `{"auth": {"client_token": "approle-token", "lease_duration": 3600, "renewable": true}}`)
+	}))
+	defer vaultServer.Close()
+
+	controller := newTestController()
+	controller.vaultConfig = &VaultConfig{Addr: vaultServer.URL, AuthMethod: AppRoleAuthMethod, Path: "approle", RoleID: "role-id", SecretID: "secret-id"}
+
+	vaultClient, err := controller.newVaultClient("")
+	require.NoError(t, err)
+	assert.Equal(t, "approle-token", vaultClient.Token())
+
+	// The secret_id is read from the file on every login, if set
+	secretIDFile := filepath.Join(t.TempDir(), "secret-id")
+	require.NoError(t, os.WriteFile(secretIDFile, []byte("rotated-secret-id\n"), 0o600))
+	controller.vaultConfig.SecretIDFile = secretIDFile
+	_, err = controller.newVaultClient("")
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"role_id": "role-id", "secret_id": "secret-id"},
+		{"role_id": "role-id", "secret_id": "rotated-secret-id"},
+	}, logins)
+
+	controller.vaultConfig.RoleID = "unknown"
+	_, err = controller.newVaultClient("")
+	assert.ErrorContains(t, err, "failed to log in to Vault with AppRole")
 }
 
 type vaultClientMock struct {
@@ -79,18 +175,33 @@ type vaultClientMock struct {
 	vaultSecret *vaultapi.Secret
 }
 
-func (c *vaultClientMock) Read(path string) (*vaultapi.Secret, error) {
+func (c *vaultClientMock) ReadWithContext(_ context.Context, path string) (*vaultapi.Secret, error) {
 	_ = path
 	return c.vaultSecret, c.err
 }
 
+type slowVaultClientMock struct {
+	delay time.Duration
+}
+
+func (c *slowVaultClientMock) ReadWithContext(ctx context.Context, _ string) (*vaultapi.Secret, error) {
+	select {
+	case <-time.After(c.delay):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 func TestGetSecretVersionFromVault(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
 	t.Run("secret not found", func(t *testing.T) {
 		vaultClient := &vaultClientMock{
 			err: ErrSecretNotFound{},
 		}
 
-		_, err := getSecretVersionFromVault(vaultClient, "test")
+		_, err := getSecretVersionFromVault(context.Background(), vaultClient, "test", logger)
 		assert.Equal(t, ErrSecretNotFound{}, err)
 	})
 
@@ -99,7 +210,7 @@ func TestGetSecretVersionFromVault(t *testing.T) {
 			err: assert.AnError,
 		}
 
-		_, err := getSecretVersionFromVault(vaultClient, "test")
+		_, err := getSecretVersionFromVault(context.Background(), vaultClient, "test", logger)
 		assert.Equal(t, assert.AnError, err)
 	})
 
@@ -114,8 +225,508 @@ func TestGetSecretVersionFromVault(t *testing.T) {
 			},
 		}
 
-		version, err := getSecretVersionFromVault(vaultClient, "test")
+		version, err := getSecretVersionFromVault(context.Background(), vaultClient, "test", logger)
+		assert.NoError(t, err)
+		assert.Equal(t, secretVersion{version: 3}, version)
+	})
+
+	t.Run("deleted version", func(t *testing.T) {
+		vaultClient := &vaultClientMock{
+			vaultSecret: &vaultapi.Secret{
+				Data: map[string]interface{}{
+					"data": nil,
+					"metadata": map[string]interface{}{
+						"version":       json.Number("3"),
+						"deletion_time": "2025-01-01T00:00:00Z",
+						"destroyed":     false,
+					},
+				},
+			},
+		}
+
+		version, err := getSecretVersionFromVault(context.Background(), vaultClient, "test", logger)
 		assert.NoError(t, err)
+		assert.Equal(t, secretVersion{version: 3, deleted: true}, version)
+	})
+
+	t.Run("destroyed version", func(t *testing.T) {
+		vaultClient := &vaultClientMock{
+			vaultSecret: &vaultapi.Secret{
+				Data: map[string]interface{}{
+					"data": nil,
+					"metadata": map[string]interface{}{
+						"version":       json.Number("3"),
+						"deletion_time": "",
+						"destroyed":     true,
+					},
+				},
+			},
+		}
+
+		version, err := getSecretVersionFromVault(context.Background(), vaultClient, "test", logger)
+		assert.NoError(t, err)
+		assert.Equal(t, secretVersion{version: 3, deleted: true}, version)
+	})
+
+	t.Run("warnings only", func(t *testing.T) {
+		var logs bytes.Buffer
+		vaultClient := &vaultClientMock{
+			vaultSecret: &vaultapi.Secret{
+				Warnings: []string{"Invalid path for a versioned K/V secrets engine."},
+			},
+		}
+
+		_, err := getSecretVersionFromVault(context.Background(), vaultClient, "secret/test", slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		assert.Equal(t, ErrSecretNotFound{secretPath: "secret/test"}, err)
+		assert.Contains(t, logs.String(), "Invalid path for a versioned K/V secrets engine.")
+	})
+
+	t.Run("warnings with data", func(t *testing.T) {
+		var logs bytes.Buffer
+		vaultClient := &vaultClientMock{
+			vaultSecret: &vaultapi.Secret{
+				Data: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"version": json.Number("2"),
+					},
+				},
+				Warnings: []string{"Endpoint is deprecated.", "Use the new endpoint."},
+			},
+		}
+
+		version, err := getSecretVersionFromVault(context.Background(), vaultClient, "test", slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		assert.NoError(t, err)
+		assert.Equal(t, secretVersion{version: 2}, version)
+		assert.Contains(t, logs.String(), "Endpoint is deprecated.; Use the new endpoint.")
+	})
+
+	t.Run("metadata without version", func(t *testing.T) {
+		vaultClient := &vaultClientMock{
+			vaultSecret: &vaultapi.Secret{
+				Data: map[string]interface{}{
+					"metadata": map[string]interface{}{},
+				},
+			},
+		}
+
+		_, err := getSecretVersionFromVault(context.Background(), vaultClient, "test", logger)
+		assert.Error(t, err)
+	})
+
+	t.Run("PKI certificate", func(t *testing.T) {
+		notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		vaultClient := &vaultClientMock{
+			vaultSecret: &vaultapi.Secret{
+				Data: map[string]interface{}{
+					"certificate":   newTestCertificate(t, 1, notAfter),
+					"serial_number": "01",
+				},
+			},
+		}
+
+		version, err := getSecretVersionFromVault(context.Background(), vaultClient, "pki/cert/ca", logger)
+		assert.NoError(t, err)
+		assert.Equal(t, secretVersion{version: int(notAfter.Unix())}, version)
+	})
+
+	t.Run("re-issued PKI certificate", func(t *testing.T) {
+		vaultClient := &vaultClientMock{
+			vaultSecret: &vaultapi.Secret{
+				Data: map[string]interface{}{
+					"certificate": newTestCertificate(t, 1, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)),
+				},
+			},
+		}
+
+		version, err := getSecretVersionFromVault(context.Background(), vaultClient, "pki/cert/ca", logger)
+		assert.NoError(t, err)
+
+		vaultClient.vaultSecret.Data["certificate"] = newTestCertificate(t, 2, time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC))
+		newVersion, err := getSecretVersionFromVault(context.Background(), vaultClient, "pki/cert/ca", logger)
+		assert.NoError(t, err)
+		assert.NotEqual(t, version, newVersion)
+	})
+
+	t.Run("invalid PKI certificate", func(t *testing.T) {
+		vaultClient := &vaultClientMock{
+			vaultSecret: &vaultapi.Secret{
+				Data: map[string]interface{}{
+					"certificate": "not a certificate",
+				},
+			},
+		}
+
+		_, err := getSecretVersionFromVault(context.Background(), vaultClient, "pki/cert/ca", logger)
+		assert.Error(t, err)
+	})
+
+	t.Run("unversioned secret", func(t *testing.T) {
+		vaultClient := &vaultClientMock{
+			vaultSecret: &vaultapi.Secret{
+				Data: map[string]interface{}{
+					"key": "value",
+				},
+			},
+		}
+
+		_, err := getSecretVersionFromVault(context.Background(), vaultClient, "kv1/test", logger)
+		assert.Error(t, err)
+	})
+}
+
+func newTestCertificate(t *testing.T, serial int64, notAfter time.Time) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+type vaultSealStatusMock struct {
+	err    error
+	sealed bool
+}
+
+func (m *vaultSealStatusMock) SealStatus() (*vaultapi.SealStatusResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &vaultapi.SealStatusResponse{Sealed: m.sealed}, nil
+}
+
+func TestCheckVaultSealed(t *testing.T) {
+	controller := newTestController()
+
+	t.Run("sealed", func(t *testing.T) {
+		sealed, err := controller.checkVaultSealed(&vaultSealStatusMock{sealed: true}, controller.logger)
+		assert.NoError(t, err)
+		assert.True(t, sealed)
+		assert.InDelta(t, 1, testutil.ToFloat64(controller.metrics.vaultSealed), 0)
+	})
+
+	t.Run("unsealed", func(t *testing.T) {
+		sealed, err := controller.checkVaultSealed(&vaultSealStatusMock{}, controller.logger)
+		assert.NoError(t, err)
+		assert.False(t, sealed)
+		assert.InDelta(t, 0, testutil.ToFloat64(controller.metrics.vaultSealed), 0)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := controller.checkVaultSealed(&vaultSealStatusMock{err: assert.AnError}, controller.logger)
+		assert.Equal(t, assert.AnError, err)
+		assert.InDelta(t, vaultSealedUnknown, testutil.ToFloat64(controller.metrics.vaultSealed), 0)
+	})
+
+	t.Run("client init error", func(t *testing.T) {
+		controller.metrics.vaultSealed.Set(0)
+		controller.vaultConfig = &VaultConfig{PathPrefix: "tenant/../a"}
+
+		summary := controller.runReloader(context.Background(), map[string][]workload{
+			"secret/data/foo": {{name: "test", namespace: "default", kind: DeploymentKind}},
+		})
+		assert.Len(t, summary.Errors, 1)
+		assert.InDelta(t, vaultSealedUnknown, testutil.ToFloat64(controller.metrics.vaultSealed), 0)
+	})
+}
+
+type vaultTokenLookupMock struct {
+	err error
+	ttl int
+}
+
+func (m *vaultTokenLookupMock) LookupSelf() (*vaultapi.Secret, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &vaultapi.Secret{Data: map[string]interface{}{"ttl": json.Number(fmt.Sprint(m.ttl))}}, nil
+}
+
+func TestVaultTokenExpiring(t *testing.T) {
+	controller := newTestController()
+	controller.vaultConfig = &VaultConfig{TokenReloginTTL: time.Minute}
+	var logs bytes.Buffer
+	controller.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	// A short TTL left, e.g. as the token reached its max TTL, triggers logging in again
+	assert.True(t, controller.vaultTokenExpiring(&vaultTokenLookupMock{ttl: 30}))
+	assert.Contains(t, logs.String(), "Vault token expires in 30s, logging in again")
+
+	assert.False(t, controller.vaultTokenExpiring(&vaultTokenLookupMock{ttl: 3600}))
+	// Tokens that don't expire or can't be looked up are kept
+	assert.False(t, controller.vaultTokenExpiring(&vaultTokenLookupMock{ttl: 0}))
+	assert.False(t, controller.vaultTokenExpiring(&vaultTokenLookupMock{err: assert.AnError}))
+	// A token rejected by Vault already expired
+	assert.True(t, controller.vaultTokenExpiring(&vaultTokenLookupMock{err: &vaultapi.ResponseError{StatusCode: http.StatusForbidden}}))
+
+	controller.vaultConfig.TokenReloginTTL = 0
+	assert.False(t, controller.vaultTokenExpiring(&vaultTokenLookupMock{ttl: 30}))
+}
+
+func TestLoginWithRetries(t *testing.T) {
+	start := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	newLogin := func(failures int) (func() (*vaultapi.Client, error), *int) {
+		attempts := 0
+		return func() (*vaultapi.Client, error) {
+			attempts++
+			if attempts <= failures {
+				return nil, assert.AnError
+			}
+			return &vaultapi.Client{}, nil
+		}, &attempts
+	}
+	// loginWithRetries logs in with the client mutex held, like initVaultClient, stepping the clock through the backoffs
+	loginWithRetries := func(ctx context.Context, controller *Controller, fakeClock *testingclock.FakeClock, login func() (*vaultapi.Client, error)) (*vaultapi.Client, error) {
+		type result struct {
+			vaultClient *vaultapi.Client
+			err         error
+		}
+		done := make(chan result)
+		go func() {
+			controller.vaultClientMu.Lock()
+			defer controller.vaultClientMu.Unlock()
+			vaultClient, err := controller.loginWithRetries(ctx, login)
+			done <- result{vaultClient, err}
+		}()
+		for {
+			select {
+			case result := <-done:
+				return result.vaultClient, result.err
+			case <-time.After(time.Millisecond):
+				if fakeClock.HasWaiters() {
+					fakeClock.Step(vaultLoginMaxBackoff)
+				}
+			}
+		}
+	}
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		controller := newTestController()
+		controller.vaultConfig = &VaultConfig{LoginMaxRetries: 3}
+		fakeClock := testingclock.NewFakeClock(start)
+		controller.clock = fakeClock
+
+		login, attempts := newLogin(2)
+		vaultClient, err := loginWithRetries(context.Background(), controller, fakeClock, login)
+		require.NoError(t, err)
+		assert.NotNil(t, vaultClient)
+		assert.Equal(t, 3, *attempts)
+		assert.False(t, controller.vaultLoginRetrying)
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		controller := newTestController()
+		controller.vaultConfig = &VaultConfig{LoginMaxRetries: 6}
+		fakeClock := testingclock.NewFakeClock(start)
+		controller.clock = fakeClock
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		login, attempts := newLogin(10)
+		done := make(chan error)
+		go func() {
+			controller.vaultClientMu.Lock()
+			defer controller.vaultClientMu.Unlock()
+			_, err := controller.loginWithRetries(ctx, login)
+			done <- err
+		}()
+
+		// The backoff doubles with every retry, and is capped at 30s
+		for _, backoff := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second} {
+			require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+			// The client mutex is not held while waiting, so other goroutines fail right away instead of waiting
+			controller.vaultClientMu.Lock()
+			assert.ErrorIs(t, controller.initVaultClient(ctx), errVaultLoginInProgress)
+			controller.vaultClientMu.Unlock()
+
+			fakeClock.Step(backoff - time.Millisecond)
+			assert.True(t, fakeClock.HasWaiters())
+			fakeClock.Step(time.Millisecond)
+		}
+		assert.ErrorIs(t, <-done, assert.AnError)
+		assert.Equal(t, 7, *attempts)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		controller := newTestController()
+		controller.vaultConfig = &VaultConfig{LoginMaxRetries: 3}
+		fakeClock := testingclock.NewFakeClock(start)
+		controller.clock = fakeClock
+		ctx, cancel := context.WithCancel(context.Background())
+
+		login, attempts := newLogin(10)
+		done := make(chan error)
+		go func() {
+			controller.vaultClientMu.Lock()
+			defer controller.vaultClientMu.Unlock()
+			_, err := controller.loginWithRetries(ctx, login)
+			done <- err
+		}()
+
+		// Shutting down stops waiting for the next attempt
+		require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		assert.Equal(t, 1, *attempts)
+		assert.False(t, controller.vaultLoginRetrying)
+	})
+
+	t.Run("no retries", func(t *testing.T) {
+		controller := newTestController()
+		controller.vaultConfig = &VaultConfig{}
+
+		login, attempts := newLogin(1)
+		_, err := controller.loginWithRetries(context.Background(), login)
+		assert.Error(t, err)
+		assert.Equal(t, 1, *attempts)
+	})
+}
+
+func TestLoginWithAuthFallback(t *testing.T) {
+	var mu sync.Mutex
+	var logins []string
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/sys/health":
+			_, _ = io.WriteString(w, `{"initialized": true, "sealed": false}`)
+		case "/v1/auth/jwt/login":
+			logins = append(logins, "jwt")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"errors": ["role \"default\" could not be found"]}`)
+		case "/v1/auth/kubernetes/login":
+			logins = append(logins, "kubernetes")
+			_, _ = io.WriteString(w, `{"auth": {"client_token": "kubernetes-token", "lease_duration": 3600, "renewable": true}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vaultServer.Close()
+
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(jwtFile, []byte("service-account-token"), 0o600))
+	t.Setenv("KUBERNETES_SERVICE_ACCOUNT_TOKEN", jwtFile)
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("VAULT_TOKEN_PATH", filepath.Join(t.TempDir(), "vault-token"))
+	// The jwt login is retried until the client timeout
+	t.Setenv("VAULT_CLIENT_TIMEOUT", "1s")
+
+	newController := func(fallbackAuthMethod string) (*Controller, *bytes.Buffer) {
+		controller := newTestController()
+		controller.vaultConfig = &VaultConfig{
+			Addr:               vaultServer.URL,
+			AuthMethod:         "jwt",
+			Path:               "jwt",
+			ClientTimeout:      time.Second,
+			FallbackAuthMethod: fallbackAuthMethod,
+			FallbackPath:       "kubernetes",
+		}
+		var logs bytes.Buffer
+		controller.logger = slog.New(slog.NewTextHandler(&logs, nil))
+		return controller, &logs
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		controller, logs := newController("kubernetes")
+
+		vaultClient, err := controller.loginWithAuthFallback("")
+		require.NoError(t, err)
+		assert.Equal(t, "kubernetes-token", vaultClient.Token())
+		assert.Equal(t, vaultAuth{method: "kubernetes", path: "kubernetes"}, controller.vaultAuth)
+		assert.Contains(t, logs.String(), "falling back to the kubernetes auth method at auth/kubernetes")
+		assert.Contains(t, logs.String(), "Logged in to Vault with the fallback kubernetes auth method at auth/kubernetes")
+		mu.Lock()
+		assert.Equal(t, "kubernetes", logins[len(logins)-1])
+		mu.Unlock()
+	})
+
+	t.Run("no fallback", func(t *testing.T) {
+		controller, _ := newController("")
+
+		_, err := controller.loginWithAuthFallback("")
+		assert.Error(t, err)
+		assert.Equal(t, vaultAuth{}, controller.vaultAuth)
+	})
+}
+
+func TestReadSecretVersionTimeout(t *testing.T) {
+	controller := newTestController()
+	controller.vaultConfig.ReadTimeout = 10 * time.Millisecond
+
+	start := time.Now()
+	_, _, err := controller.readSecretVersion(context.Background(), &slowVaultClientMock{delay: time.Minute}, "test", controller.logger)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// reads faster than the timeout are not affected
+	controller.vaultConfig.ReadTimeout = time.Minute
+	_, _, err = controller.readSecretVersion(context.Background(), &slowVaultClientMock{delay: time.Millisecond}, "test", controller.logger)
+	assert.Equal(t, ErrSecretNotFound{secretPath: "test"}, err)
+}
+
+// pathRecordingVaultClientMock records the paths secrets are read from
+type pathRecordingVaultClientMock struct {
+	paths []string
+}
+
+func (c *pathRecordingVaultClientMock) ReadWithContext(_ context.Context, path string) (*vaultapi.Secret, error) {
+	c.paths = append(c.paths, path)
+	return &vaultapi.Secret{Data: map[string]interface{}{
+		"data":     map[string]interface{}{"password": "secret"},
+		"metadata": map[string]interface{}{"version": json.Number("3")},
+	}}, nil
+}
+
+func TestVaultPathPrefix(t *testing.T) {
+	t.Run("apply", func(t *testing.T) {
+		assert.Equal(t, "secret/data/app", applyVaultPathPrefix("", "secret/data/app"))
+		assert.Equal(t, "gateway/vault/secret/data/app", applyVaultPathPrefix("gateway/vault", "secret/data/app"))
+		assert.Equal(t, "gateway/secret/data/app", applyVaultPathPrefix("gateway", "/secret/data/app"))
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		for _, prefix := range []string{"", "gateway", "gateway/vault", "team-data"} {
+			assert.NoError(t, validateVaultPathPrefix(prefix), prefix)
+		}
+		for _, prefix := range []string{"data", "gateway/metadata", "gateway//vault", "gateway/..", "gateway?route=vault", "gateway#vault"} {
+			assert.Error(t, validateVaultPathPrefix(prefix), prefix)
+		}
+	})
+
+	t.Run("metadata path derivation", func(t *testing.T) {
+		// The first "data" segment of valid prefixed KV v2 paths is still the one following the mount
+		for _, prefix := range []string{"gateway", "gateway/vault", "team-data"} {
+			prefixed := applyVaultPathPrefix(prefix, "secret/data/app/data")
+			assert.Equal(t, prefix+"/secret/metadata/app/data", strings.Replace(prefixed, "/data/", "/metadata/", 1))
+		}
+	})
+
+	t.Run("read", func(t *testing.T) {
+		controller := newTestController()
+		controller.vaultConfig.PathPrefix = "gateway/vault"
+		vaultClient := &pathRecordingVaultClientMock{}
+
+		version, _, err := controller.readSecretVersion(context.Background(), vaultClient, "secret/data/app", controller.logger)
+		require.NoError(t, err)
 		assert.Equal(t, 3, version)
+		assert.Equal(t, []string{"gateway/vault/secret/data/app"}, vaultClient.paths)
+	})
+
+	t.Run("secrets are reported by their own path", func(t *testing.T) {
+		controller := newTestController()
+		controller.vaultConfig.PathPrefix = "gateway/vault"
+
+		_, _, err := controller.readSecretVersion(context.Background(), &vaultClientMock{}, "secret/data/app", controller.logger)
+		assert.Equal(t, ErrSecretNotFound{secretPath: "secret/data/app"}, err)
 	})
 }
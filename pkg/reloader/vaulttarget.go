@@ -0,0 +1,126 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bank-vaults/secrets-webhook/pkg/common"
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// vaultTargetSeparator separates the Vault address, namespace and path of the secrets of workloads
+// that override the Vault they are read from
+const vaultTargetSeparator = "|"
+
+// vaultTarget is the Vault address and namespace a workload reads its secrets from, overriding
+// VAULT_ADDR and VAULT_NAMESPACE if set
+type vaultTarget struct {
+	addr      string
+	namespace string
+}
+
+// vaultTargetClient identifies the Vault client of a target, logged in with a role
+type vaultTargetClient struct {
+	target vaultTarget
+	role   string
+}
+
+// getWorkloadVaultTarget returns the Vault address and namespace overrides set on the pod template with the
+// vault-addr and vault-namespace annotations of the webhook, or of their deprecated versions if not disabled.
+func (c *Controller) getWorkloadVaultTarget(annotations map[string]string) vaultTarget {
+	target := vaultTarget{
+		addr:      annotations[common.VaultAddrAnnotation],
+		namespace: annotations[common.VaultNamespaceAnnotation],
+	}
+	if !c.skipDeprecatedAnnotation {
+		if target.addr == "" {
+			target.addr = annotations[common.VaultAddrAnnotationDeprecated]
+		}
+		if target.namespace == "" {
+			target.namespace = annotations[common.VaultNamespaceAnnotationDeprecated]
+		}
+	}
+
+	return target
+}
+
+// qualifySecretPath returns the path the secret of the target is tracked by, e.g.
+// "https://vault-b:8200|team-a|secret/data/app", so the secrets of different Vaults are tracked apart.
+// Secrets of the default Vault are tracked by their path.
+func qualifySecretPath(target vaultTarget, secretPath string) string {
+	if target == (vaultTarget{}) {
+		return secretPath
+	}
+
+	return strings.Join([]string{target.addr, target.namespace, secretPath}, vaultTargetSeparator)
+}
+
+// splitQualifiedSecretPath returns the Vault target and path of a tracked secret.
+func splitQualifiedSecretPath(qualifiedPath string) (vaultTarget, string) {
+	parts := strings.SplitN(qualifiedPath, vaultTargetSeparator, 3)
+	if len(parts) != 3 {
+		return vaultTarget{}, qualifiedPath
+	}
+
+	return vaultTarget{addr: parts[0], namespace: parts[1]}, parts[2]
+}
+
+// qualifySecretKeys returns the keys referenced of each secret of the target by their qualified path.
+func qualifySecretKeys(target vaultTarget, secretKeys map[string][]string) map[string][]string {
+	if target == (vaultTarget{}) {
+		return secretKeys
+	}
+
+	qualified := make(map[string][]string, len(secretKeys))
+	for secretPath, keys := range secretKeys {
+		qualified[qualifySecretPath(target, secretPath)] = keys
+	}
+
+	return qualified
+}
+
+// getTargetVaultClient returns a Vault client of the target, logged in with the role,
+// (re)initializing the default client first if needed.
+func (c *Controller) getTargetVaultClient(ctx context.Context, target vaultTarget, role string) (*vaultapi.Client, error) {
+	c.vaultClientMu.Lock()
+	defer c.vaultClientMu.Unlock()
+
+	err := c.initVaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" || c.vaultConfig.AuthMethod == AppRoleAuthMethod {
+		role = c.vaultConfig.Role
+	}
+	key := vaultTargetClient{target: target, role: role}
+	if vaultClient, ok := c.targetVaultClients[key]; ok {
+		return vaultClient, nil
+	}
+
+	c.logger.Info(fmt.Sprintf("Initializing Vault client for address %q and namespace %q", target.addr, target.namespace))
+	vaultClient, err := c.newVaultClientForTarget(target, role)
+	if err != nil {
+		return nil, err
+	}
+	if c.targetVaultClients == nil {
+		c.targetVaultClients = make(map[vaultTargetClient]*vaultapi.Client)
+	}
+	c.targetVaultClients[key] = vaultClient
+
+	return vaultClient, nil
+}
@@ -0,0 +1,104 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// newVersionedVaultServer returns a Vault server answering reads of secret/data/app with the version
+func newVersionedVaultServer(t *testing.T, version int) *vaultapi.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/health":
+			_, _ = io.WriteString(w, `{"initialized": true, "sealed": false}`)
+		case "/v1/secret/data/app":
+			_, _ = io.WriteString(w, fmt.Sprintf(`{"data": {"data": {"password": "secret"}, "metadata": {"version": %d}}}`, version))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	vaultClient, err := vaultapi.NewClient(&vaultapi.Config{Address: server.URL})
+	require.NoError(t, err)
+
+	return vaultClient
+}
+
+func TestCollectWorkloadSecretsVaultTarget(t *testing.T) {
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-addr":      "https://vault-b:8200",
+		"secrets-webhook.security.bank-vaults.io/vault-namespace": "team-a",
+	})
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{
+			Name: "app",
+			Env:  []corev1.EnvVar{{Name: "PASSWORD", Value: "vault:secret/data/app#password"}},
+		},
+	}
+
+	controller := newTestController()
+	WithSubkeyAwareReload(true)(controller)
+	controller.handleObject(deployment)
+
+	assert.Equal(t, map[workload][]string{
+		testWorkload: {"https://vault-b:8200|team-a|secret/data/app"},
+	}, controller.workloadSecrets.GetWorkloadSecretsMap())
+	assert.Equal(t, map[string][]string{
+		"https://vault-b:8200|team-a|secret/data/app": {"password"},
+	}, controller.workloadSecrets.GetSecretKeys()[testWorkload])
+}
+
+func TestReadSecretVersionVaultTarget(t *testing.T) {
+	controller := newTestController()
+	controller.vaultClient = newVersionedVaultServer(t, 1)
+	target := vaultTarget{addr: "https://vault-b:8200", namespace: "team-a"}
+	controller.targetVaultClients = map[vaultTargetClient]*vaultapi.Client{
+		{target: target}: newVersionedVaultServer(t, 7),
+	}
+	ctx := context.Background()
+
+	version, _, err := controller.readSecretVersionWithRole(ctx, controller.vaultClient.Logical(), "", "secret/data/app", controller.logger)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	version, _, err = controller.readSecretVersionWithRole(ctx, controller.vaultClient.Logical(), "", qualifySecretPath(target, "secret/data/app"), controller.logger)
+	require.NoError(t, err)
+	assert.Equal(t, 7, version)
+}
+
+func TestSplitQualifiedSecretPath(t *testing.T) {
+	target := vaultTarget{addr: "https://vault-b:8200", namespace: ""}
+	gotTarget, secretPath := splitQualifiedSecretPath(qualifySecretPath(target, "secret/data/app"))
+	assert.Equal(t, target, gotTarget)
+	assert.Equal(t, "secret/data/app", secretPath)
+
+	gotTarget, secretPath = splitQualifiedSecretPath(qualifySecretPath(vaultTarget{}, "secret/data/app"))
+	assert.Equal(t, vaultTarget{}, gotTarget)
+	assert.Equal(t, "secret/data/app", secretPath)
+}
@@ -0,0 +1,126 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// reloadVerificationReverted is reported when the reload was undone, e.g. by an admission webhook or GitOps tool
+	reloadVerificationReverted = "reverted"
+	// reloadVerificationNotRolledOut is reported when the controller of the workload didn't pick up the reload
+	reloadVerificationNotRolledOut = "not_rolled_out"
+)
+
+// reloadVerification is the state a reloaded workload is expected to reach
+type reloadVerification struct {
+	generation  int64
+	reloadCount string
+}
+
+// reloadVerifications keeps the pending verification of each reloaded workload,
+// so only the check of the latest reload of a workload is done.
+type reloadVerifications struct {
+	delay time.Duration
+
+	mu      sync.Mutex
+	pending map[workload]reloadVerification
+}
+
+// WithReloadVerification enables checking, after the given delay, that reloaded workloads actually rolled out:
+// the reload wasn't reverted and the controller of the workload observed the updated generation.
+// Failed verifications are logged and counted in the reloader_reload_verification_failures_total metric.
+// Zero, the default, disables the verification.
+func WithReloadVerification(delay time.Duration) Option {
+	return func(c *Controller) {
+		if delay > 0 {
+			c.reloadVerifications = &reloadVerifications{
+				delay:   delay,
+				pending: make(map[workload]reloadVerification),
+			}
+		}
+	}
+}
+
+// scheduleReloadVerification checks the rollout of the reloaded workload after the verification delay,
+// in the background. A later reload of the workload supersedes the check.
+func (c *Controller) scheduleReloadVerification(ctx context.Context, reloaded workload, accessor WorkloadAccessor) {
+	if c.reloadVerifications == nil {
+		return
+	}
+
+	expected := reloadVerification{
+		generation:  accessor.GetGeneration(),
+		reloadCount: accessor.GetPodTemplate().Annotations[c.reloadCountAnnotationForKind(accessor.Kind())],
+	}
+
+	c.reloadVerifications.mu.Lock()
+	c.reloadVerifications.pending[reloaded] = expected
+	c.reloadVerifications.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(c.reloadVerifications.delay):
+		}
+
+		c.reloadVerifications.mu.Lock()
+		latest := c.reloadVerifications.pending[reloaded] == expected
+		if latest {
+			delete(c.reloadVerifications.pending, reloaded)
+		}
+		c.reloadVerifications.mu.Unlock()
+		if !latest {
+			return
+		}
+
+		c.verifyReload(ctx, reloaded, expected)
+	}()
+}
+
+// verifyReload checks that the workload still has the reload count set by the reload,
+// and that its controller observed the generation of the reload.
+func (c *Controller) verifyReload(ctx context.Context, reloaded workload, expected reloadVerification) {
+	accessor, err := getWorkloadAccessor(ctx, c.kubeClient, reloaded)
+	if apierrors.IsNotFound(err) {
+		return
+	}
+	if err != nil {
+		c.logger.Error(fmt.Errorf("failed to verify reload of workload %s: %w", reloaded, err).Error())
+		return
+	}
+
+	switch reloadCount := accessor.GetPodTemplate().Annotations[c.reloadCountAnnotationForKind(accessor.Kind())]; {
+	case reloadCount != expected.reloadCount:
+		c.logger.Warn(fmt.Sprintf("Reload of workload %s was reverted, its reload count is %q instead of %q",
+			reloaded, reloadCount, expected.reloadCount))
+		c.metrics.reloadVerificationFailures.WithLabelValues(reloaded.namespace, reloaded.kind, reloadVerificationReverted).Inc()
+
+	case accessor.GetObservedGeneration() < expected.generation:
+		c.logger.Warn(fmt.Sprintf("Reload of workload %s didn't roll out, observed generation %d is behind the reloaded generation %d",
+			reloaded, accessor.GetObservedGeneration(), expected.generation))
+		c.metrics.reloadVerificationFailures.WithLabelValues(reloaded.namespace, reloaded.kind, reloadVerificationNotRolledOut).Inc()
+
+	default:
+		c.logger.Debug(fmt.Sprintf("Verified reload of workload %s", reloaded))
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// bumpGeneration makes the fake client increment the generation of updated deployments, like the API server does
+func bumpGeneration(action k8stesting.Action) (bool, runtime.Object, error) {
+	deployment := action.(k8stesting.UpdateAction).GetObject().(*appsv1.Deployment)
+	deployment.Generation++
+	return false, nil, nil
+}
+
+func TestReloadVerification(t *testing.T) {
+	delay := time.Minute
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+
+	newVerifiedController := func() (*Controller, *testingclock.FakeClock) {
+		deployment := newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"})
+		deployment.Generation = 1
+		deployment.Status.ObservedGeneration = 1
+		controller := newTestController(deployment)
+		fakeClock := testingclock.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+		controller.clock = fakeClock
+		WithReloadVerification(delay)(controller)
+		return controller, fakeClock
+	}
+	verificationFailures := func(controller *Controller, reason string) float64 {
+		return testutil.ToFloat64(controller.metrics.reloadVerificationFailures.WithLabelValues("default", DeploymentKind, reason))
+	}
+
+	t.Run("silently reverted", func(t *testing.T) {
+		controller, fakeClock := newVerifiedController()
+		// The update is accepted, but never persisted, e.g. undone right away by another controller
+		controller.kubeClient.(*fake.Clientset).PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, action.(k8stesting.UpdateAction).GetObject(), nil
+		})
+
+		require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, nil))
+		require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+		assert.Zero(t, verificationFailures(controller, reloadVerificationReverted))
+
+		fakeClock.Step(delay)
+		assert.Eventually(t, func() bool {
+			return verificationFailures(controller, reloadVerificationReverted) == 1
+		}, time.Second, time.Millisecond)
+		assert.Zero(t, verificationFailures(controller, reloadVerificationNotRolledOut))
+	})
+
+	t.Run("not rolled out", func(t *testing.T) {
+		controller, fakeClock := newVerifiedController()
+		controller.kubeClient.(*fake.Clientset).PrependReactor("update", "deployments", bumpGeneration)
+
+		require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, nil))
+		require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+
+		fakeClock.Step(delay)
+		assert.Eventually(t, func() bool {
+			return verificationFailures(controller, reloadVerificationNotRolledOut) == 1
+		}, time.Second, time.Millisecond)
+		assert.Zero(t, verificationFailures(controller, reloadVerificationReverted))
+	})
+
+	t.Run("rolled out", func(t *testing.T) {
+		controller, _ := newVerifiedController()
+		controller.kubeClient.(*fake.Clientset).PrependReactor("update", "deployments", bumpGeneration)
+
+		require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, nil))
+		accessor, err := getWorkloadAccessor(context.Background(), controller.kubeClient, testWorkload)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), accessor.GetGeneration())
+
+		// The deployment controller observed the reloaded generation
+		deployment := accessor.(*deploymentAccessor).Deployment
+		deployment.Status.ObservedGeneration = 2
+		_, err = controller.kubeClient.AppsV1().Deployments("default").UpdateStatus(context.Background(), deployment, metav1.UpdateOptions{})
+		require.NoError(t, err)
+
+		controller.verifyReload(context.Background(), testWorkload, reloadVerification{generation: 2, reloadCount: "1"})
+		assert.Zero(t, verificationFailures(controller, reloadVerificationReverted))
+		assert.Zero(t, verificationFailures(controller, reloadVerificationNotRolledOut))
+	})
+
+	t.Run("superseded by a later reload", func(t *testing.T) {
+		controller, fakeClock := newVerifiedController()
+		controller.kubeClient.(*fake.Clientset).PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, action.(k8stesting.UpdateAction).GetObject(), nil
+		})
+
+		require.NoError(t, controller.reloadWorkload(context.Background(), testWorkload, nil))
+		controller.reloadVerifications.mu.Lock()
+		controller.reloadVerifications.pending[testWorkload] = reloadVerification{generation: 3, reloadCount: "2"}
+		controller.reloadVerifications.mu.Unlock()
+		require.Eventually(t, fakeClock.HasWaiters, time.Second, time.Millisecond)
+
+		fakeClock.Step(delay)
+		require.Eventually(t, func() bool { return !fakeClock.HasWaiters() }, time.Second, time.Millisecond)
+		// Only the check of the latest reload is done, once its own delay passed
+		controller.reloadVerifications.mu.Lock()
+		assert.Contains(t, controller.reloadVerifications.pending, testWorkload)
+		controller.reloadVerifications.mu.Unlock()
+		assert.Zero(t, verificationFailures(controller, reloadVerificationReverted))
+	})
+}
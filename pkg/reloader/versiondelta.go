@@ -0,0 +1,104 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+)
+
+// MinVersionDeltaAnnotationName is the pod template annotation overriding the minimum version delta of a workload
+const MinVersionDeltaAnnotationName = "secrets-reloader.security.bank-vaults.io/min-version-delta"
+
+// WithMinVersionDelta sets how much the version of a secret needs to increase since the last reload
+// of a workload to reload it again, e.g. to ignore a secret that is rotated on a schedule the workload
+// already handles, defaults to 1, reloading on every new version.
+func WithMinVersionDelta(delta int) (Option, error) {
+	if delta < 1 {
+		return nil, fmt.Errorf("invalid minimum version delta %d, must be a positive number", delta)
+	}
+
+	return func(c *Controller) {
+		c.minVersionDelta = delta
+	}, nil
+}
+
+// getMinVersionDelta returns the minimum version delta override set on the workload, or zero if not set or invalid.
+func getMinVersionDelta(annotations map[string]string, logger *slog.Logger) int {
+	value := annotations[MinVersionDeltaAnnotationName]
+	if value == "" {
+		return 0
+	}
+
+	delta, err := strconv.Atoi(value)
+	if err != nil || delta < 1 {
+		logger.Warn(fmt.Sprintf("Invalid minimum version delta %q, using the default delta", value))
+		return 0
+	}
+
+	return delta
+}
+
+// versionDeltaReached reports whether the version of the changed secret increased by at least the minimum
+// version delta of the workload since the secret last reloaded it. Otherwise the version the delta is counted
+// from is kept for the workload until it's reached. The returned change is counted from that version.
+// Decreasing versions, e.g. of a recreated secret, always reach the delta.
+func (c *Controller) versionDeltaReached(changed workload, change secretChange, minDelta int) (secretChange, bool) {
+	if minDelta <= 1 {
+		return change, true
+	}
+
+	c.secretVersionsMu.Lock()
+	defer c.secretVersionsMu.Unlock()
+
+	baseline, ok := c.versionBaselines[changed][change.path]
+	if !ok {
+		baseline = change.oldVersion
+	}
+	if delta := change.newVersion - baseline; delta >= 0 && delta < minDelta {
+		if c.versionBaselines[changed] == nil {
+			c.versionBaselines[changed] = make(map[string]int)
+		}
+		c.versionBaselines[changed][change.path] = baseline
+		return change, false
+	}
+
+	delete(c.versionBaselines[changed], change.path)
+	if len(c.versionBaselines[changed]) == 0 {
+		delete(c.versionBaselines, changed)
+	}
+	change.oldVersion = baseline
+
+	return change, true
+}
+
+// pruneVersionBaselines removes the versions the delta is counted from of secrets not used by their workload anymore,
+// must be called with secretVersionsMu held.
+func (c *Controller) pruneVersionBaselines(workloadSecrets map[workload][]string) {
+	for storedWorkload, baselines := range c.versionBaselines {
+		secretPaths, ok := workloadSecrets[storedWorkload]
+		if !ok {
+			delete(c.versionBaselines, storedWorkload)
+			continue
+		}
+		for secretPath := range baselines {
+			if !slices.Contains(secretPaths, secretPath) {
+				delete(baselines, secretPath)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReloadChangedWorkloadsMinVersionDelta(t *testing.T) {
+	ctx := context.Background()
+	fromPath := "secrets-webhook.security.bank-vaults.io/vault-from-path"
+	deployment := newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true", fromPath: "secret/data/foo"})
+	overridden := newTestDeployment("overridden", map[string]string{
+		SecretReloadAnnotationName:    "true",
+		fromPath:                      "secret/data/foo",
+		MinVersionDeltaAnnotationName: "1",
+	})
+	controller := newTestController(deployment, overridden)
+	option, err := WithMinVersionDelta(3)
+	require.NoError(t, err)
+	option(controller)
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	overriddenWorkload := workload{name: "overridden", namespace: "default", kind: DeploymentKind}
+	controller.handleObject(deployment)
+	controller.handleObject(overridden)
+	require.Equal(t, map[workload]workloadConfig{overriddenWorkload: {minVersionDelta: 1}}, controller.workloadSecrets.GetConfigs())
+
+	controller.secretVersions["secret/data/foo"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 1}}
+	reloadChangedWorkloads := func(version int) map[workload][]secretChange {
+		vaultClient.setVersion("secret/data/foo", version)
+		workloadsToReload, errs := controller.checkSecretVersions(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+		require.Empty(t, errs)
+		return workloadsToReload
+	}
+
+	// Increases below the delta only reload the workload overriding it
+	assert.Equal(t, map[workload][]secretChange{
+		overriddenWorkload: {{path: "secret/data/foo", oldVersion: 1, newVersion: 2}},
+	}, reloadChangedWorkloads(2))
+	assert.Equal(t, map[workload][]secretChange{
+		overriddenWorkload: {{path: "secret/data/foo", oldVersion: 2, newVersion: 3}},
+	}, reloadChangedWorkloads(3))
+
+	// The delta is counted from the version of the last reload
+	assert.Equal(t, map[workload][]secretChange{
+		testWorkload:       {{path: "secret/data/foo", oldVersion: 1, newVersion: 4}},
+		overriddenWorkload: {{path: "secret/data/foo", oldVersion: 3, newVersion: 4}},
+	}, reloadChangedWorkloads(4))
+	assert.Empty(t, controller.versionBaselines)
+	assert.Equal(t, map[workload][]secretChange{
+		overriddenWorkload: {{path: "secret/data/foo", oldVersion: 4, newVersion: 5}},
+	}, reloadChangedWorkloads(5))
+
+	// Decreasing versions always reload
+	assert.Equal(t, map[workload][]secretChange{
+		testWorkload:       {{path: "secret/data/foo", oldVersion: 4, newVersion: 1}},
+		overriddenWorkload: {{path: "secret/data/foo", oldVersion: 5, newVersion: 1}},
+	}, reloadChangedWorkloads(1))
+
+	// The versions the delta is counted from are forgotten with the workload
+	reloadChangedWorkloads(2)
+	assert.Equal(t, map[workload]map[string]int{testWorkload: {"secret/data/foo": 1}}, controller.versionBaselines)
+	controller.workloadSecrets.Delete(testWorkload)
+	controller.pruneSecretVersions(controller.workloadSecrets.GetSecretWorkloadsMap(), controller.workloadSecrets.GetWorkloadSecretsMap())
+	assert.Empty(t, controller.versionBaselines)
+
+	// The reload decision is applied to the workloads
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/foo"})
+	vaultClient.setVersion("secret/data/foo", 5)
+	summary := controller.reloadChangedWorkloads(ctx, vaultClient, controller.workloadSecrets.GetSecretWorkloadsMap(), controller.logger)
+	assert.Equal(t, 2, summary.WorkloadsReloaded)
+	deployment, err = controller.kubeClient.AppsV1().Deployments("default").Get(ctx, "test", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", deployment.Spec.Template.Annotations[ReloadCountAnnotationName])
+}
+
+func TestMinVersionDeltaValidation(t *testing.T) {
+	_, err := WithMinVersionDelta(0)
+	assert.Error(t, err)
+
+	controller := newTestController()
+	assert.Zero(t, getMinVersionDelta(map[string]string{MinVersionDeltaAnnotationName: "-2"}, controller.logger))
+	assert.Zero(t, getMinVersionDelta(map[string]string{MinVersionDeltaAnnotationName: "many"}, controller.logger))
+	assert.Equal(t, 5, getMinVersionDelta(map[string]string{MinVersionDeltaAnnotationName: "5"}, controller.logger))
+}
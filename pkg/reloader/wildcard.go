@@ -0,0 +1,183 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+const (
+	// childrenWildcard as the last segment of a secret path matches the secrets right under its parent
+	childrenWildcard = "+"
+	// descendantsWildcard as the last segment of a secret path also matches the secrets of the sub-folders of its parent
+	descendantsWildcard = "*"
+)
+
+type vaultSecretLister interface {
+	ListWithContext(ctx context.Context, path string) (*vaultapi.Secret, error)
+}
+
+// prefixedSecretLister lists secrets with the prefix prepended to their paths, like prefixedSecretReader.
+type prefixedSecretLister struct {
+	lister vaultSecretLister
+	prefix string
+}
+
+func (l *prefixedSecretLister) ListWithContext(ctx context.Context, path string) (*vaultapi.Secret, error) {
+	return l.lister.ListWithContext(ctx, applyVaultPathPrefix(l.prefix, path))
+}
+
+// isWildcardSecretPath reports whether the last segment of the secret path is a wildcard.
+func isWildcardSecretPath(secretPath string) bool {
+	return strings.HasSuffix(secretPath, "/"+childrenWildcard) || strings.HasSuffix(secretPath, "/"+descendantsWildcard)
+}
+
+// expandWildcardSecretPaths replaces the secret paths ending with a wildcard (e.g. "secret/data/app/*") with the secrets
+// listed from the Vault target of the workload, with a client logged in with its role. As secrets are collected by the
+// informer event handlers, listing is limited by VAULT_READ_TIMEOUT, or VAULT_CLIENT_TIMEOUT if not set.
+// Wildcard paths that can't be listed are skipped.
+func (c *Controller) expandWildcardSecretPaths(workload workload, target vaultTarget, role string, secretPaths []string, logger *slog.Logger) []string {
+	if !slices.ContainsFunc(secretPaths, isWildcardSecretPath) {
+		return secretPaths
+	}
+
+	timeout := c.vaultConfig.ReadTimeout
+	if timeout <= 0 {
+		timeout = c.vaultConfig.ClientTimeout
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	expandedPaths := make([]string, 0, len(secretPaths))
+	for _, secretPath := range secretPaths {
+		if !isWildcardSecretPath(secretPath) {
+			expandedPaths = append(expandedPaths, secretPath)
+			continue
+		}
+		expanded, err := c.expandWildcardSecretPath(ctx, target, role, secretPath)
+		if err != nil {
+			logger.Warn(fmt.Errorf("failed to expand wildcard secret path %s of %s %s/%s: %w", secretPath, workload.kind, workload.namespace, workload.name, err).Error())
+			continue
+		}
+		expandedPaths = append(expandedPaths, expanded...)
+	}
+
+	return expandedPaths
+}
+
+// expandWildcardSecretPath returns the secret paths matching the wildcard secret path, listed from the Vault target
+// with a client logged in with the role, like the secrets are read (see readSecretVersionWithRole).
+func (c *Controller) expandWildcardSecretPath(ctx context.Context, target vaultTarget, role, secretPath string) ([]string, error) {
+	var vaultClient *vaultapi.Client
+	var err error
+	switch {
+	case target != (vaultTarget{}):
+		vaultClient, err = c.getTargetVaultClient(ctx, target, role)
+	case role != "":
+		vaultClient, err = c.getRoleVaultClient(ctx, role)
+	default:
+		vaultClient, err = c.getVaultClient(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Vault client: %w", err)
+	}
+
+	var lister vaultSecretLister = vaultClient.Logical()
+	if c.vaultConfig.PathPrefix != "" {
+		lister = &prefixedSecretLister{lister: lister, prefix: c.vaultConfig.PathPrefix}
+	}
+
+	return listWildcardSecretPath(ctx, lister, secretPath)
+}
+
+// matchesWildcardSecretPath reports whether the secret path is matched by the wildcard secret path,
+// without listing the secrets from Vault.
+func matchesWildcardSecretPath(wildcardPath, secretPath string) bool {
+	index := strings.LastIndex(wildcardPath, "/")
+	parent, wildcard := wildcardPath[:index], wildcardPath[index+1:]
+	name, ok := strings.CutPrefix(secretPath, parent+"/")
+	if !ok || name == "" {
+		return false
+	}
+
+	return wildcard == descendantsWildcard || !strings.Contains(name, "/")
+}
+
+// listWildcardSecretPath returns the sorted secret paths matching the wildcard secret path, e.g. "secret/data/app/+"
+// matches "secret/data/app/db" and "secret/data/app/api", while "secret/data/app/*" also matches "secret/data/app/db/admin".
+// The secrets of KV v2 paths, with a "data" segment, are listed from the matching "metadata" path.
+func listWildcardSecretPath(ctx context.Context, lister vaultSecretLister, secretPath string) ([]string, error) {
+	index := strings.LastIndex(secretPath, "/")
+	parent, wildcard := secretPath[:index], secretPath[index+1:]
+	listPath := parent
+	if before, after, ok := strings.Cut(parent, "/data/"); ok {
+		listPath = before + "/metadata/" + after
+	} else if before, ok := strings.CutSuffix(parent, "/data"); ok {
+		listPath = before + "/metadata"
+	}
+
+	secretPaths, err := listSecretPaths(ctx, lister, listPath, parent, wildcard == descendantsWildcard)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(secretPaths)
+
+	return secretPaths, nil
+}
+
+// listSecretPaths lists the secrets under the list path of Vault, returning them under the parent path,
+// descending into sub-folders if recursive.
+func listSecretPaths(ctx context.Context, lister vaultSecretLister, listPath, parent string, recursive bool) ([]string, error) {
+	secret, err := lister.ListWithContext(ctx, listPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets of %s: %w", listPath, err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	keys, _ := secret.Data["keys"].([]interface{})
+	var secretPaths []string
+	for _, key := range keys {
+		name, _ := key.(string)
+		if name == "" {
+			continue
+		}
+		if folder, ok := strings.CutSuffix(name, "/"); ok {
+			if !recursive {
+				continue
+			}
+			children, err := listSecretPaths(ctx, lister, listPath+"/"+folder, parent+"/"+folder, recursive)
+			if err != nil {
+				return nil, err
+			}
+			secretPaths = append(secretPaths, children...)
+			continue
+		}
+		secretPaths = append(secretPaths, parent+"/"+name)
+	}
+
+	return secretPaths, nil
+}
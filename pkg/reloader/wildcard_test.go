@@ -0,0 +1,172 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newListingVaultServer returns a Vault server answering LIST requests of the given paths with their keys
+func newListingVaultServer(t *testing.T, keys map[string][]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/health" {
+			_, _ = io.WriteString(w, `{"initialized": true, "sealed": false}`)
+			return
+		}
+		listed, ok := keys[r.URL.Path]
+		if r.URL.Query().Get("list") != "true" || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		response := `{"data": {"keys": [`
+		for i, key := range listed {
+			if i > 0 {
+				response += ","
+			}
+			response += fmt.Sprintf("%q", key)
+		}
+		_, _ = io.WriteString(w, response+`]}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCollectSecretsWildcardAnnotation(t *testing.T) {
+	vaultServer := newListingVaultServer(t, map[string][]string{
+		"/v1/secret/metadata/app":           {"api", "db", "legacy/"},
+		"/v1/secret/metadata/shared":        {"tls", "team-a/"},
+		"/v1/secret/metadata/shared/team-a": {"token"},
+		"/v1/kv/app":                        {"config"},
+	})
+	controller := newTestController()
+	vaultClient, err := vaultapi.NewClient(&vaultapi.Config{Address: vaultServer.URL})
+	require.NoError(t, err)
+	controller.vaultClient = vaultClient
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName: "true",
+		// Sub-folders are only matched by "*"
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/app/+,secret/data/shared/*,kv/app/+,secret/data/missing/+,secret/data/other",
+	})
+
+	controller.handleObject(deployment)
+	assert.Equal(t, map[workload][]string{
+		testWorkload: {
+			"kv/app/config",
+			"secret/data/app/api",
+			"secret/data/app/db",
+			"secret/data/other",
+			"secret/data/shared/team-a/token",
+			"secret/data/shared/tls",
+		},
+	}, controller.workloadSecrets.GetWorkloadSecretsMap())
+
+	t.Run("path prefix", func(t *testing.T) {
+		vaultServer := newListingVaultServer(t, map[string][]string{
+			"/v1/gateway/vault/secret/metadata/app": {"api"},
+		})
+		controller := newTestController()
+		controller.vaultConfig.PathPrefix = "gateway/vault"
+		vaultClient, err := vaultapi.NewClient(&vaultapi.Config{Address: vaultServer.URL})
+		require.NoError(t, err)
+		controller.vaultClient = vaultClient
+
+		controller.handleObject(newTestDeployment("test", map[string]string{
+			SecretReloadAnnotationName:                                "true",
+			"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/app/*",
+		}))
+		assert.Equal(t, map[workload][]string{
+			testWorkload: {"secret/data/app/api"},
+		}, controller.workloadSecrets.GetWorkloadSecretsMap())
+	})
+}
+
+func TestCollectSecretsWildcardAnnotationVaultTarget(t *testing.T) {
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	deployment := newTestDeployment("test", map[string]string{
+		SecretReloadAnnotationName:                                "true",
+		"secrets-webhook.security.bank-vaults.io/vault-from-path": "secret/data/app/+",
+		"secrets-webhook.security.bank-vaults.io/vault-addr":      "https://vault-b:8200",
+		"secrets-webhook.security.bank-vaults.io/vault-namespace": "team-a",
+	})
+
+	// The wildcard path is listed from the Vault of the workload, not the default one
+	controller := newTestController()
+	defaultVaultClient, err := vaultapi.NewClient(&vaultapi.Config{Address: newListingVaultServer(t, map[string][]string{
+		"/v1/secret/metadata/app": {"default"},
+	}).URL})
+	require.NoError(t, err)
+	controller.vaultClient = defaultVaultClient
+	targetVaultClient, err := vaultapi.NewClient(&vaultapi.Config{Address: newListingVaultServer(t, map[string][]string{
+		"/v1/secret/metadata/app": {"api"},
+	}).URL})
+	require.NoError(t, err)
+	target := vaultTarget{addr: "https://vault-b:8200", namespace: "team-a"}
+	controller.targetVaultClients = map[vaultTargetClient]*vaultapi.Client{{target: target}: targetVaultClient}
+
+	controller.handleObject(deployment)
+	assert.Equal(t, map[workload][]string{
+		testWorkload: {"https://vault-b:8200|team-a|secret/data/app/api"},
+	}, controller.workloadSecrets.GetWorkloadSecretsMap())
+}
+
+func TestExpandWildcardSecretPathsTimeout(t *testing.T) {
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/health" {
+			_, _ = io.WriteString(w, `{"initialized": true, "sealed": false}`)
+			return
+		}
+		// Vault doesn't answer the list request in time
+		<-r.Context().Done()
+	}))
+	t.Cleanup(vaultServer.Close)
+	controller := newTestController()
+	controller.vaultConfig.ReadTimeout = 10 * time.Millisecond
+	vaultClient, err := vaultapi.NewClient(&vaultapi.Config{Address: vaultServer.URL})
+	require.NoError(t, err)
+	controller.vaultClient = vaultClient
+
+	// The wildcard path is skipped once listing times out
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	expanded := controller.expandWildcardSecretPaths(testWorkload, vaultTarget{}, "", []string{"secret/data/app/+", "secret/data/other"}, controller.logger)
+	assert.Equal(t, []string{"secret/data/other"}, expanded)
+}
+
+func TestMatchesWildcardSecretPath(t *testing.T) {
+	assert.True(t, matchesWildcardSecretPath("secret/data/app/+", "secret/data/app/db"))
+	assert.False(t, matchesWildcardSecretPath("secret/data/app/+", "secret/data/app/db/admin"))
+	assert.True(t, matchesWildcardSecretPath("secret/data/app/*", "secret/data/app/db/admin"))
+	assert.False(t, matchesWildcardSecretPath("secret/data/app/*", "secret/data/app"))
+	assert.False(t, matchesWildcardSecretPath("secret/data/app/*", "secret/data/apps/db"))
+}
+
+func TestIsWildcardSecretPath(t *testing.T) {
+	assert.True(t, isWildcardSecretPath("secret/data/app/+"))
+	assert.True(t, isWildcardSecretPath("secret/data/app/*"))
+	assert.False(t, isWildcardSecretPath("secret/data/app"))
+	assert.False(t, isWildcardSecretPath("secret/data/app*"))
+	assert.False(t, isWildcardSecretPath("*"))
+}
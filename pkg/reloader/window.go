@@ -0,0 +1,192 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// timeOfDayRange is a range of the day, from start (inclusive) to end (exclusive),
+// spanning midnight if end is before start.
+type timeOfDayRange struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// reloadWindow is the set of time ranges of the day when workloads can be reloaded
+type reloadWindow []timeOfDayRange
+
+// parseReloadWindow parses comma separated time ranges in the format of "HH:MM-HH:MM", e.g. "22:00-06:00,12:00-13:00".
+func parseReloadWindow(value string) (reloadWindow, error) {
+	var window reloadWindow
+	for _, r := range strings.Split(value, ",") {
+		start, end, ok := strings.Cut(strings.TrimSpace(r), "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid reload window %q, must be in HH:MM-HH:MM format", r)
+		}
+
+		startTime, err := parseTimeOfDay(start)
+		if err != nil {
+			return nil, err
+		}
+		endTime, err := parseTimeOfDay(end)
+		if err != nil {
+			return nil, err
+		}
+		if startTime == endTime {
+			return nil, fmt.Errorf("invalid reload window %q, start and end must differ", r)
+		}
+
+		window = append(window, timeOfDayRange{start: startTime, end: endTime})
+	}
+
+	return window, nil
+}
+
+// parseTimeOfDay parses a time of the day in HH:MM format to the duration since midnight.
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, must be in HH:MM format", value)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// sinceMidnight returns the time elapsed since the start of the day of t.
+func sinceMidnight(t time.Time) time.Duration {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return t.Sub(midnight)
+}
+
+// contains reports whether t is within any of the time ranges of the window.
+func (w reloadWindow) contains(t time.Time) bool {
+	now := sinceMidnight(t)
+	for _, r := range w {
+		if r.start < r.end && now >= r.start && now < r.end {
+			return true
+		}
+		if r.start > r.end && (now >= r.start || now < r.end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextOpening returns the next time after t when one of the time ranges of the window starts.
+func (w reloadWindow) nextOpening(t time.Time) time.Time {
+	var next time.Time
+	for _, r := range w {
+		opening := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Add(r.start)
+		if !opening.After(t) {
+			opening = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()).Add(r.start)
+		}
+		if next.IsZero() || opening.Before(next) {
+			next = opening
+		}
+	}
+
+	return next
+}
+
+// deferOutsideReloadWindow queues the workloads to reload while outside the reload window,
+// removing them from workloadsToReload, and adds the queued ones back once inside the window.
+// Reloads kept pending for other reasons (e.g. Deployments with the Recreate strategy that didn't
+// opt in yet) are added back the same way.
+func (c *Controller) deferOutsideReloadWindow(workloadsToReload map[workload][]secretChange, logger *slog.Logger) {
+	c.pendingReloadsMu.Lock()
+	defer c.pendingReloadsMu.Unlock()
+
+	if c.reloadWindow != nil && !c.reloadWindow.contains(c.clock.Now()) {
+		for workload, changes := range workloadsToReload {
+			c.pendingReloads[workload] = mergeSecretChanges(c.pendingReloads[workload], changes)
+			delete(workloadsToReload, workload)
+		}
+		if len(c.pendingReloads) > 0 {
+			logger.Info(fmt.Sprintf("Outside of the reload window, %d workloads are pending reload", len(c.pendingReloads)))
+		}
+		return
+	}
+
+	// Workloads deleted since their reload was deferred are not reloaded
+	workloadSecrets := c.workloadSecrets.GetWorkloadSecretsMap()
+	for workload, changes := range c.pendingReloads {
+		if _, ok := workloadSecrets[workload]; ok {
+			workloadsToReload[workload] = mergeSecretChanges(changes, workloadsToReload[workload])
+		}
+		delete(c.pendingReloads, workload)
+	}
+}
+
+// mergeSecretChanges merges the later changes of secrets into the earlier ones,
+// keeping the oldest version of secrets that changed multiple times.
+func mergeSecretChanges(earlier, later []secretChange) []secretChange {
+	merged := append([]secretChange{}, earlier...)
+	for _, change := range later {
+		found := false
+		for i := range merged {
+			if merged[i].path == change.path {
+				merged[i].newVersion = change.newVersion
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, change)
+		}
+	}
+
+	return merged
+}
+
+// runReloadWindow reloads the workloads pending reload every time the reload window opens,
+// until the context is cancelled.
+func (c *Controller) runReloadWindow(ctx context.Context) {
+	for {
+		now := c.clock.Now()
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(c.reloadWindow.nextOpening(now).Sub(now)):
+		}
+
+		c.reloadPendingWorkloads(ctx)
+	}
+}
+
+// reloadPendingWorkloads reloads the workloads whose reload was deferred until the reload window.
+func (c *Controller) reloadPendingWorkloads(ctx context.Context) {
+	ctx = WithCorrelationID(ctx, string(uuid.NewUUID()))
+	logger := c.logger.With(
+		slog.String("worker", "reloader"),
+		slog.String("correlation_id", correlationIDFromContext(ctx)),
+	)
+
+	workloadsToReload := make(map[workload][]secretChange)
+	c.deferOutsideReloadWindow(workloadsToReload, logger)
+	if len(workloadsToReload) == 0 {
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Reload window opened, reloading %d pending workloads", len(workloadsToReload)))
+	c.reloadWorkloads(ctx, workloadsToReload, logger)
+}
@@ -0,0 +1,125 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestParseReloadWindow(t *testing.T) {
+	window, err := parseReloadWindow("22:00-06:00, 12:30-13:00")
+	require.NoError(t, err)
+	assert.Equal(t, reloadWindow{
+		{start: 22 * time.Hour, end: 6 * time.Hour},
+		{start: 12*time.Hour + 30*time.Minute, end: 13 * time.Hour},
+	}, window)
+
+	for _, value := range []string{"", "22:00", "22:00-", "25:00-06:00", "10:00-10:00", "22:00-06:00,"} {
+		_, err := parseReloadWindow(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestReloadWindowContains(t *testing.T) {
+	window, err := parseReloadWindow("22:00-06:00,12:00-13:00")
+	require.NoError(t, err)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 3, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	assert.True(t, window.contains(at(22, 0)))
+	assert.True(t, window.contains(at(23, 59)))
+	assert.True(t, window.contains(at(0, 0)))
+	assert.True(t, window.contains(at(5, 59)))
+	assert.False(t, window.contains(at(6, 0)))
+	assert.True(t, window.contains(at(12, 30)))
+	assert.False(t, window.contains(at(13, 0)))
+	assert.False(t, window.contains(at(21, 59)))
+
+	assert.Equal(t, at(12, 0), window.nextOpening(at(8, 0)))
+	assert.Equal(t, at(22, 0), window.nextOpening(at(12, 0)))
+	assert.Equal(t, at(22, 0), window.nextOpening(at(13, 30)))
+	assert.Equal(t, at(12, 0).AddDate(0, 0, 1), window.nextOpening(at(22, 0)))
+}
+
+func TestReloadChangedWorkloadsReloadWindow(t *testing.T) {
+	controller := newTestController(newTestDeployment("test", map[string]string{SecretReloadAnnotationName: "true"}))
+	fakeClock := testingclock.NewFakeClock(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	controller.clock = fakeClock
+	window, err := parseReloadWindow("22:00-06:00")
+	require.NoError(t, err)
+	controller.reloadWindow = window
+
+	testWorkload := workload{name: "test", namespace: "default", kind: DeploymentKind}
+	controller.workloadSecrets.Store(testWorkload, []string{"secret/data/foo"})
+	controller.secretVersions["secret/data/foo"] = 1
+	vaultClient := &versionedVaultClientMock{versions: map[string]int{"secret/data/foo": 2}}
+	reloadCount := func() string {
+		deployment, err := controller.kubeClient.AppsV1().Deployments("default").Get(context.Background(), "test", metav1.GetOptions{})
+		require.NoError(t, err)
+		return deployment.Spec.Template.Annotations[ReloadCountAnnotationName]
+	}
+	reloadChangedWorkloads := func() CycleSummary {
+		return controller.reloadChangedWorkloads(
+			context.Background(),
+			vaultClient,
+			controller.workloadSecrets.GetSecretWorkloadsMap(),
+			controller.logger,
+		)
+	}
+
+	t.Run("out of window", func(t *testing.T) {
+		summary := reloadChangedWorkloads()
+		assert.Equal(t, 1, summary.SecretsChanged)
+		assert.Equal(t, 0, summary.WorkloadsReloaded)
+		assert.Empty(t, reloadCount())
+
+		// Changes keep being detected while the reload is pending
+		vaultClient.setVersion("secret/data/foo", 3)
+		reloadChangedWorkloads()
+		assert.Empty(t, reloadCount())
+		assert.Equal(t, map[workload][]secretChange{
+			testWorkload: {{path: "secret/data/foo", oldVersion: 1, newVersion: 3}},
+		}, controller.pendingReloads)
+	})
+
+	t.Run("window opens", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go controller.runReloadWindow(ctx)
+
+		require.Eventually(t, fakeClock.HasWaiters, time.Second, 10*time.Millisecond)
+		fakeClock.SetTime(time.Date(2025, 3, 10, 22, 0, 0, 0, time.UTC))
+
+		assert.Eventually(t, func() bool { return reloadCount() == "1" }, time.Second, 10*time.Millisecond)
+		controller.pendingReloadsMu.Lock()
+		assert.Empty(t, controller.pendingReloads)
+		controller.pendingReloadsMu.Unlock()
+	})
+
+	t.Run("in window", func(t *testing.T) {
+		vaultClient.setVersion("secret/data/foo", 4)
+		summary := reloadChangedWorkloads()
+		assert.Equal(t, 1, summary.WorkloadsReloaded)
+		assert.Equal(t, "2", reloadCount())
+	})
+}
@@ -0,0 +1,375 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WorkloadAccessor provides access to the kind specific parts of a workload object,
+// so that the controller can handle every supported kind the same way.
+type WorkloadAccessor interface {
+	metav1.Object
+
+	// Kind returns the kind of the workload
+	Kind() string
+	// GetPodTemplate returns the pod template of the workload
+	GetPodTemplate() *corev1.PodTemplateSpec
+	// GetSelector returns the label selector of the workload's pods
+	GetSelector() *metav1.LabelSelector
+	// SetPodTemplateAnnotation sets an annotation on the pod template of the workload
+	SetPodTemplateAnnotation(key, value string)
+	// SetPodTemplateLabel sets a label on the pod template of the workload
+	SetPodTemplateLabel(key, value string)
+	// Update writes the workload back to the cluster, updating the accessor with the result
+	Update(ctx context.Context, kubeClient kubernetes.Interface, opts metav1.UpdateOptions) error
+	// Apply applies the server-side apply patch to the workload, updating the accessor with the result
+	Apply(ctx context.Context, kubeClient kubernetes.Interface, patch []byte, opts metav1.PatchOptions) error
+	// GetObservedGeneration returns the most recent generation observed by the controller of the workload
+	GetObservedGeneration() int64
+}
+
+// newWorkloadAccessor returns an accessor for the object if it is a supported workload.
+func newWorkloadAccessor(obj interface{}) (WorkloadAccessor, bool) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &deploymentAccessor{o}, o != nil
+	case *appsv1.DaemonSet:
+		return &daemonSetAccessor{o}, o != nil
+	case *appsv1.StatefulSet:
+		return &statefulSetAccessor{o}, o != nil
+	case *batchv1.CronJob:
+		return &cronJobAccessor{o}, o != nil
+	case *batchv1.Job:
+		return &jobAccessor{o}, o != nil
+	default:
+		return nil, false
+	}
+}
+
+// getWorkloadAccessor fetches the workload from the cluster and returns an accessor for it.
+func getWorkloadAccessor(ctx context.Context, kubeClient kubernetes.Interface, workload workload) (WorkloadAccessor, error) {
+	switch workload.kind {
+	case DeploymentKind:
+		deployment, err := kubeClient.AppsV1().Deployments(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &deploymentAccessor{deployment}, nil
+
+	case DaemonSetKind:
+		daemonSet, err := kubeClient.AppsV1().DaemonSets(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &daemonSetAccessor{daemonSet}, nil
+
+	case StatefulSetKind:
+		statefulSet, err := kubeClient.AppsV1().StatefulSets(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &statefulSetAccessor{statefulSet}, nil
+
+	case CronJobKind:
+		cronJob, err := kubeClient.BatchV1().CronJobs(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &cronJobAccessor{cronJob}, nil
+
+	case JobKind:
+		job, err := kubeClient.BatchV1().Jobs(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &jobAccessor{job}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown object type: %s", workload.kind)
+	}
+}
+
+// isBatchKind reports whether the workloads of the kind run their pods to completion,
+// so their running pods are not replaced when they are reloaded, only their next runs are.
+func isBatchKind(kind string) bool {
+	return kind == CronJobKind || kind == JobKind
+}
+
+// workloadFromAccessor returns the key the workload is stored with.
+func workloadFromAccessor(accessor WorkloadAccessor) workload {
+	return workload{name: accessor.GetName(), namespace: accessor.GetNamespace(), kind: accessor.Kind()}
+}
+
+func setPodTemplateAnnotation(podTemplate *corev1.PodTemplateSpec, key, value string) {
+	if podTemplate.Annotations == nil {
+		podTemplate.Annotations = make(map[string]string)
+	}
+	podTemplate.Annotations[key] = value
+}
+
+func setPodTemplateLabel(podTemplate *corev1.PodTemplateSpec, key, value string) {
+	if podTemplate.Labels == nil {
+		podTemplate.Labels = make(map[string]string)
+	}
+	podTemplate.Labels[key] = value
+}
+
+type deploymentAccessor struct {
+	*appsv1.Deployment
+}
+
+func (*deploymentAccessor) Kind() string {
+	return DeploymentKind
+}
+
+func (a *deploymentAccessor) GetPodTemplate() *corev1.PodTemplateSpec {
+	return &a.Spec.Template
+}
+
+func (a *deploymentAccessor) GetSelector() *metav1.LabelSelector {
+	return a.Spec.Selector
+}
+
+func (a *deploymentAccessor) SetPodTemplateAnnotation(key, value string) {
+	setPodTemplateAnnotation(&a.Spec.Template, key, value)
+}
+
+func (a *deploymentAccessor) SetPodTemplateLabel(key, value string) {
+	setPodTemplateLabel(&a.Spec.Template, key, value)
+}
+
+func (a *deploymentAccessor) Update(ctx context.Context, kubeClient kubernetes.Interface, opts metav1.UpdateOptions) error {
+	updated, err := kubeClient.AppsV1().Deployments(a.Namespace).Update(ctx, a.Deployment, opts)
+	if err != nil {
+		return err
+	}
+	a.Deployment = updated
+	return nil
+}
+
+func (a *deploymentAccessor) Apply(ctx context.Context, kubeClient kubernetes.Interface, patch []byte, opts metav1.PatchOptions) error {
+	applied, err := kubeClient.AppsV1().Deployments(a.Namespace).Patch(ctx, a.Name, types.ApplyPatchType, patch, opts)
+	if err != nil {
+		return err
+	}
+	a.Deployment = applied
+	return nil
+}
+
+func (a *deploymentAccessor) GetObservedGeneration() int64 {
+	return a.Status.ObservedGeneration
+}
+
+type daemonSetAccessor struct {
+	*appsv1.DaemonSet
+}
+
+func (*daemonSetAccessor) Kind() string {
+	return DaemonSetKind
+}
+
+func (a *daemonSetAccessor) GetPodTemplate() *corev1.PodTemplateSpec {
+	return &a.Spec.Template
+}
+
+func (a *daemonSetAccessor) GetSelector() *metav1.LabelSelector {
+	return a.Spec.Selector
+}
+
+func (a *daemonSetAccessor) SetPodTemplateAnnotation(key, value string) {
+	setPodTemplateAnnotation(&a.Spec.Template, key, value)
+}
+
+func (a *daemonSetAccessor) SetPodTemplateLabel(key, value string) {
+	setPodTemplateLabel(&a.Spec.Template, key, value)
+}
+
+func (a *daemonSetAccessor) Update(ctx context.Context, kubeClient kubernetes.Interface, opts metav1.UpdateOptions) error {
+	updated, err := kubeClient.AppsV1().DaemonSets(a.Namespace).Update(ctx, a.DaemonSet, opts)
+	if err != nil {
+		return err
+	}
+	a.DaemonSet = updated
+	return nil
+}
+
+func (a *daemonSetAccessor) Apply(ctx context.Context, kubeClient kubernetes.Interface, patch []byte, opts metav1.PatchOptions) error {
+	applied, err := kubeClient.AppsV1().DaemonSets(a.Namespace).Patch(ctx, a.Name, types.ApplyPatchType, patch, opts)
+	if err != nil {
+		return err
+	}
+	a.DaemonSet = applied
+	return nil
+}
+
+func (a *daemonSetAccessor) GetObservedGeneration() int64 {
+	return a.Status.ObservedGeneration
+}
+
+type statefulSetAccessor struct {
+	*appsv1.StatefulSet
+}
+
+func (*statefulSetAccessor) Kind() string {
+	return StatefulSetKind
+}
+
+func (a *statefulSetAccessor) GetPodTemplate() *corev1.PodTemplateSpec {
+	return &a.Spec.Template
+}
+
+func (a *statefulSetAccessor) GetSelector() *metav1.LabelSelector {
+	return a.Spec.Selector
+}
+
+func (a *statefulSetAccessor) SetPodTemplateAnnotation(key, value string) {
+	setPodTemplateAnnotation(&a.Spec.Template, key, value)
+}
+
+func (a *statefulSetAccessor) SetPodTemplateLabel(key, value string) {
+	setPodTemplateLabel(&a.Spec.Template, key, value)
+}
+
+func (a *statefulSetAccessor) Update(ctx context.Context, kubeClient kubernetes.Interface, opts metav1.UpdateOptions) error {
+	updated, err := kubeClient.AppsV1().StatefulSets(a.Namespace).Update(ctx, a.StatefulSet, opts)
+	if err != nil {
+		return err
+	}
+	a.StatefulSet = updated
+	return nil
+}
+
+func (a *statefulSetAccessor) Apply(ctx context.Context, kubeClient kubernetes.Interface, patch []byte, opts metav1.PatchOptions) error {
+	applied, err := kubeClient.AppsV1().StatefulSets(a.Namespace).Patch(ctx, a.Name, types.ApplyPatchType, patch, opts)
+	if err != nil {
+		return err
+	}
+	a.StatefulSet = applied
+	return nil
+}
+
+func (a *statefulSetAccessor) GetObservedGeneration() int64 {
+	return a.Status.ObservedGeneration
+}
+
+type cronJobAccessor struct {
+	*batchv1.CronJob
+}
+
+func (*cronJobAccessor) Kind() string {
+	return CronJobKind
+}
+
+func (a *cronJobAccessor) GetPodTemplate() *corev1.PodTemplateSpec {
+	return &a.Spec.JobTemplate.Spec.Template
+}
+
+func (a *cronJobAccessor) GetSelector() *metav1.LabelSelector {
+	return a.Spec.JobTemplate.Spec.Selector
+}
+
+func (a *cronJobAccessor) SetPodTemplateAnnotation(key, value string) {
+	setPodTemplateAnnotation(&a.Spec.JobTemplate.Spec.Template, key, value)
+}
+
+func (a *cronJobAccessor) SetPodTemplateLabel(key, value string) {
+	setPodTemplateLabel(&a.Spec.JobTemplate.Spec.Template, key, value)
+}
+
+func (a *cronJobAccessor) Update(ctx context.Context, kubeClient kubernetes.Interface, opts metav1.UpdateOptions) error {
+	updated, err := kubeClient.BatchV1().CronJobs(a.Namespace).Update(ctx, a.CronJob, opts)
+	if err != nil {
+		return err
+	}
+	a.CronJob = updated
+	return nil
+}
+
+func (a *cronJobAccessor) Apply(ctx context.Context, kubeClient kubernetes.Interface, patch []byte, opts metav1.PatchOptions) error {
+	applied, err := kubeClient.BatchV1().CronJobs(a.Namespace).Patch(ctx, a.Name, types.ApplyPatchType, patch, opts)
+	if err != nil {
+		return err
+	}
+	a.CronJob = applied
+	return nil
+}
+
+// GetObservedGeneration returns the generation of the CronJob, as its status has no observed generation,
+// its Jobs are created from the latest job template anyway
+func (a *cronJobAccessor) GetObservedGeneration() int64 {
+	return a.Generation
+}
+
+type jobAccessor struct {
+	*batchv1.Job
+}
+
+func (*jobAccessor) Kind() string {
+	return JobKind
+}
+
+func (a *jobAccessor) GetPodTemplate() *corev1.PodTemplateSpec {
+	return &a.Spec.Template
+}
+
+func (a *jobAccessor) GetSelector() *metav1.LabelSelector {
+	return a.Spec.Selector
+}
+
+func (a *jobAccessor) SetPodTemplateAnnotation(key, value string) {
+	setPodTemplateAnnotation(&a.Spec.Template, key, value)
+}
+
+func (a *jobAccessor) SetPodTemplateLabel(key, value string) {
+	setPodTemplateLabel(&a.Spec.Template, key, value)
+}
+
+func (a *jobAccessor) Update(ctx context.Context, kubeClient kubernetes.Interface, opts metav1.UpdateOptions) error {
+	updated, err := kubeClient.BatchV1().Jobs(a.Namespace).Update(ctx, a.Job, opts)
+	if err != nil {
+		return err
+	}
+	a.Job = updated
+	return nil
+}
+
+func (a *jobAccessor) Apply(ctx context.Context, kubeClient kubernetes.Interface, patch []byte, opts metav1.PatchOptions) error {
+	applied, err := kubeClient.BatchV1().Jobs(a.Namespace).Patch(ctx, a.Name, types.ApplyPatchType, patch, opts)
+	if err != nil {
+		return err
+	}
+	a.Job = applied
+	return nil
+}
+
+// GetObservedGeneration returns the generation of the Job, as its status has no observed generation
+func (a *jobAccessor) GetObservedGeneration() int64 {
+	return a.Generation
+}
+
+// podTemplateMutable reports whether the annotations of the pod template of the Job can be changed,
+// which is only allowed while it is suspended and hasn't started yet.
+func (a *jobAccessor) podTemplateMutable() bool {
+	return a.Spec.Suspend != nil && *a.Spec.Suspend && a.Status.StartTime == nil
+}
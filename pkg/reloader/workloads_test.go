@@ -0,0 +1,104 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWorkloadAccessors(t *testing.T) {
+	objectMeta := metav1.ObjectMeta{Name: "test", Namespace: "default"}
+	tests := []struct {
+		kind   string
+		object runtime.Object
+	}{
+		{
+			kind:   DeploymentKind,
+			object: &appsv1.Deployment{ObjectMeta: objectMeta},
+		},
+		{
+			kind:   DaemonSetKind,
+			object: &appsv1.DaemonSet{ObjectMeta: objectMeta},
+		},
+		{
+			kind:   StatefulSetKind,
+			object: &appsv1.StatefulSet{ObjectMeta: objectMeta},
+		},
+		{
+			kind:   CronJobKind,
+			object: &batchv1.CronJob{ObjectMeta: objectMeta},
+		},
+		{
+			kind:   JobKind,
+			object: &batchv1.Job{ObjectMeta: objectMeta},
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.kind, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(ttp.object)
+			workloadData := workload{name: "test", namespace: "default", kind: ttp.kind}
+
+			accessor, ok := newWorkloadAccessor(ttp.object)
+			require.True(t, ok)
+			assert.Equal(t, workloadData, workloadFromAccessor(accessor))
+
+			// get the workload from the cluster, update its pod template and write it back
+			accessor, err := getWorkloadAccessor(context.Background(), kubeClient, workloadData)
+			require.NoError(t, err)
+			assert.Equal(t, ttp.kind, accessor.Kind())
+
+			accessor.SetPodTemplateAnnotation("foo", "bar")
+			assert.Equal(t, "bar", accessor.GetPodTemplate().Annotations["foo"])
+			accessor.SetPodTemplateLabel("baz", "qux")
+			assert.Equal(t, "qux", accessor.GetPodTemplate().Labels["baz"])
+			require.NoError(t, accessor.Update(context.Background(), kubeClient, metav1.UpdateOptions{}))
+
+			accessor, err = getWorkloadAccessor(context.Background(), kubeClient, workloadData)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"foo": "bar"}, accessor.GetPodTemplate().Annotations)
+			assert.Equal(t, map[string]string{"baz": "qux"}, accessor.GetPodTemplate().Labels)
+		})
+	}
+
+	t.Run("CronJob job template", func(t *testing.T) {
+		cronJob := &batchv1.CronJob{ObjectMeta: objectMeta}
+		accessor, ok := newWorkloadAccessor(cronJob)
+		require.True(t, ok)
+		accessor.SetPodTemplateAnnotation("foo", "bar")
+		assert.Equal(t, map[string]string{"foo": "bar"}, cronJob.Spec.JobTemplate.Spec.Template.Annotations)
+	})
+
+	t.Run("unsupported object", func(t *testing.T) {
+		_, ok := newWorkloadAccessor(&corev1.Pod{})
+		assert.False(t, ok)
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		_, err := getWorkloadAccessor(context.Background(), fake.NewSimpleClientset(), workload{name: "test", namespace: "default", kind: "Unknown"})
+		assert.EqualError(t, err, "unknown object type: Unknown")
+	})
+}
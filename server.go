@@ -0,0 +1,98 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const (
+	serverShutdownTimeout = 10 * time.Second
+	metricsPushJob        = "vault-secrets-reloader"
+)
+
+// newHTTPServers returns the server for health checks and debugging, and a separate server for metrics
+// if metricsAddr is set, otherwise metrics are served by the health server.
+func newHTTPServers(healthAddr, metricsAddr string, gatherer prometheus.Gatherer, debugStateHandler http.Handler) []*http.Server {
+	metricsHandler := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+
+	healthMux := http.NewServeMux()
+	healthMux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	healthMux.Handle("/debug/state", debugStateHandler)
+
+	if metricsAddr == "" {
+		healthMux.Handle("/metrics", metricsHandler)
+
+		return []*http.Server{
+			{Addr: healthAddr, Handler: healthMux, ReadHeaderTimeout: 10 * time.Second},
+		}
+	}
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metricsHandler)
+
+	return []*http.Server{
+		{Addr: healthAddr, Handler: healthMux, ReadHeaderTimeout: 10 * time.Second},
+		{Addr: metricsAddr, Handler: metricsMux, ReadHeaderTimeout: 10 * time.Second},
+	}
+}
+
+// startHTTPServers starts the servers in the background.
+func startHTTPServers(logger *slog.Logger, servers []*http.Server) {
+	for _, server := range servers {
+		go func(server *http.Server) {
+			err := server.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error(fmt.Errorf("error serving http on %s: %w", server.Addr, err).Error())
+			}
+		}(server)
+	}
+}
+
+// shutdownHTTPServers gracefully shuts down the servers, waiting for active connections to finish.
+func shutdownHTTPServers(logger *slog.Logger, servers []*http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				logger.Error(fmt.Errorf("error shutting down http server on %s: %w", server.Addr, err).Error())
+			}
+		}(server)
+	}
+	wg.Wait()
+}
+
+// newMetricsPusher returns a function that pushes the metrics of the gatherer to the Prometheus push gateway at url,
+// replacing the previously pushed metrics of the reloader.
+func newMetricsPusher(url string, gatherer prometheus.Gatherer) func(ctx context.Context) error {
+	return push.New(url, metricsPushJob).Gatherer(gatherer).PushContext
+}
@@ -0,0 +1,150 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test counter"})
+	registry.MustRegister(counter)
+	counter.Inc()
+
+	return registry
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(url) //nolint:noctx
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp.StatusCode, string(body)
+}
+
+func newTestDebugStateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"cycles":[]}`))
+	})
+}
+
+func TestNewHTTPServers(t *testing.T) {
+	t.Run("separate metrics server", func(t *testing.T) {
+		servers := newHTTPServers(":8080", ":8081", newTestRegistry(t), newTestDebugStateHandler())
+		require.Len(t, servers, 2)
+		assert.Equal(t, ":8080", servers[0].Addr)
+		assert.Equal(t, ":8081", servers[1].Addr)
+
+		health := httptest.NewServer(servers[0].Handler)
+		defer health.Close()
+		metrics := httptest.NewServer(servers[1].Handler)
+		defer metrics.Close()
+
+		status, body := get(t, health.URL+"/")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "ok", body)
+		_, body = get(t, health.URL+"/metrics")
+		assert.NotContains(t, body, "test_total")
+		status, body = get(t, health.URL+"/debug/state")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `{"cycles":[]}`, body)
+
+		status, body = get(t, metrics.URL+"/metrics")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "test_total 1")
+		status, _ = get(t, metrics.URL+"/")
+		assert.Equal(t, http.StatusNotFound, status)
+		status, _ = get(t, metrics.URL+"/debug/state")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("shared server", func(t *testing.T) {
+		servers := newHTTPServers(":8080", "", newTestRegistry(t), newTestDebugStateHandler())
+		require.Len(t, servers, 1)
+
+		health := httptest.NewServer(servers[0].Handler)
+		defer health.Close()
+
+		status, body := get(t, health.URL+"/")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "ok", body)
+		status, body = get(t, health.URL+"/metrics")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "test_total 1")
+	})
+}
+
+func TestShutdownHTTPServers(t *testing.T) {
+	servers := newHTTPServers("127.0.0.1:0", "127.0.0.1:0", newTestRegistry(t), newTestDebugStateHandler())
+
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		listener, err := net.Listen("tcp", server.Addr)
+		require.NoError(t, err)
+		go func(server *http.Server) {
+			errs <- server.Serve(listener)
+		}(server)
+	}
+
+	shutdownHTTPServers(slog.New(slog.NewTextHandler(io.Discard, nil)), servers)
+
+	for range servers {
+		assert.True(t, errors.Is(<-errs, http.ErrServerClosed))
+	}
+}
+
+func TestNewMetricsPusher(t *testing.T) {
+	var method, path, body string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	require.NoError(t, newMetricsPusher(gateway.URL, newTestRegistry(t))(context.Background()))
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/vault-secrets-reloader", path)
+	assert.NotEmpty(t, body)
+
+	t.Run("gateway error", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer failing.Close()
+
+		assert.Error(t, newMetricsPusher(failing.URL, newTestRegistry(t))(context.Background()))
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Provisioned by ldflags, e.g. -X main.version=v1.2.3, taken from the build info otherwise
+var (
+	version    string
+	commitHash string
+)
+
+// formatVersion returns the version, commit and Go version of the build, preferring the ones set with ldflags.
+func formatVersion(info *debug.BuildInfo) string {
+	buildVersion, buildCommit, goVersion := version, commitHash, runtime.Version()
+	if info != nil {
+		if buildVersion == "" && info.Main.Version != "(devel)" {
+			buildVersion = info.Main.Version
+		}
+		if buildCommit == "" {
+			buildCommit = vcsCommit(info.Settings)
+		}
+		if info.GoVersion != "" {
+			goVersion = info.GoVersion
+		}
+	}
+	if buildVersion == "" {
+		buildVersion = "dev"
+	}
+	if buildCommit == "" {
+		buildCommit = "unknown"
+	}
+
+	return fmt.Sprintf("vault-secrets-reloader version %s, commit %s, built with %s", buildVersion, buildCommit, goVersion)
+}
+
+// vcsCommit returns the revision the binary was built from, marked if it had uncommitted changes.
+func vcsCommit(settings []debug.BuildSetting) string {
+	var revision, modified string
+	for _, setting := range settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+
+	return revision
+}
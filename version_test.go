@@ -0,0 +1,46 @@
+// Copyright © 2025 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatVersion(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.23.5",
+		Main:      debug.Module{Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123abcd"},
+			{Key: "vcs.modified", Value: "false"},
+		},
+	}
+	assert.Equal(t, "vault-secrets-reloader version v1.2.3, commit 0123abcd, built with go1.23.5", formatVersion(info))
+
+	// Local builds
+	info.Main.Version = "(devel)"
+	info.Settings[1].Value = "true"
+	assert.Equal(t, "vault-secrets-reloader version dev, commit 0123abcd-dirty, built with go1.23.5", formatVersion(info))
+	assert.Equal(t, "vault-secrets-reloader version dev, commit unknown, built with "+runtime.Version(), formatVersion(nil))
+
+	// ldflags take precedence over the build info
+	version, commitHash = "v2.0.0", "fedc3210"
+	t.Cleanup(func() { version, commitHash = "", "" })
+	assert.Equal(t, "vault-secrets-reloader version v2.0.0, commit fedc3210, built with go1.23.5", formatVersion(info))
+}